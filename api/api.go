@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"learningbay24.de/backend/errs"
 	"learningbay24.de/backend/exam"
 	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/storage"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
@@ -36,10 +39,10 @@ type _file struct {
 
 // Handle an error by setting the correct HTTP status code and filling the body of the response with the error message if necessary.
 func handleApiError(c *gin.Context, err error) {
-	NOT_AUTHORIZED := []error{errs.ErrNotAdmin, errs.ErrNotModerator, errs.ErrNotUser, errs.ErrNotCourseAdmin, errs.ErrNotCourseModerator, errs.ErrNotCourseUser}
-	NOT_FOUNDS := []error{sql.ErrNoRows, errs.ErrNoUploads}
-	BAD_REQUESTS := []error{errs.ErrFileExtensionNotAllowed, errs.ErrNoFileExtension, errs.ErrParameterConversion, errs.ErrNoFileInRequest, errs.ErrBodyConversion, errs.ErrNoQuery, errs.ErrRawData, errs.ErrUploadLimitReached, errs.ErrEmptyName, errs.ErrVisibleTimePast, errs.ErrDeadlineTimePast, errs.ErrVisibleFromAfterDeadline, errs.ErrSubmissionTimeAfterDeadline, errs.ErrEmptyFileName, bcrypt.ErrMismatchedHashAndPassword}
-	CONFLICTS := []error{errs.ErrSelfRegisterExam, errs.ErrRegisterDeadlinePassed, errs.ErrUnregisterDeadlinePassed, errs.ErrExamEnded, errs.ErrExamHasntStarted, errs.ErrCourseNotEmpty, errs.ErrWrongEnrollkey, errs.ErrExamHasntEnded, errs.ErrRegisterDeadlinePast, errs.ErrDeregisterDeadlinePast, errs.ErrRegisterDeadlineAfterDateTime, errs.ErrDeregisterDeadlineAfterDateTime, errs.ErrRegisterDeadlineAfterDerigsterDeadline}
+	NOT_AUTHORIZED := []error{errs.ErrNotAdmin, errs.ErrNotModerator, errs.ErrNotUser, errs.ErrNotCourseAdmin, errs.ErrNotCourseModerator, errs.ErrNotCourseUser, errs.ErrNotEntryAuthor, errs.ErrInvalidSignature, errs.ErrDownloadLinkExpired}
+	NOT_FOUNDS := []error{sql.ErrNoRows, errs.ErrNoUploads, storage.ErrNotExist}
+	BAD_REQUESTS := []error{errs.ErrFileExtensionNotAllowed, errs.ErrNoFileExtension, errs.ErrFileContentMismatch, errs.ErrUnsafeArchive, errs.ErrUncheckableArchive, errs.ErrFileTooLarge, errs.ErrFileInfected, errs.ErrFileTooLargeToScan, errs.ErrParameterConversion, errs.ErrNoFileInRequest, errs.ErrBodyConversion, errs.ErrNoQuery, errs.ErrRawData, errs.ErrUploadLimitReached, errs.ErrUploadIncomplete, errs.ErrEmptyName, errs.ErrVisibleTimePast, errs.ErrDeadlineTimePast, errs.ErrVisibleFromAfterDeadline, errs.ErrSubmissionTimeAfterDeadline, errs.ErrEmptyFileName, errs.ErrEmptySubject, errs.ErrSubjectTooLong, errs.ErrEmptyContent, errs.ErrPrerequisiteCycle, errs.ErrDirectoryCycle, errs.ErrInvalidSemester, errs.ErrInvalidRepeatDistance, errs.ErrInvalidAppointment, errs.ErrSeriesEndMissing, errs.ErrSeriesEndBeforeStart, errs.ErrTooManyOccurrences, errs.ErrInvalidICal, errs.ErrUnsupportedRecurrence, errs.ErrInvalidTimeRange, errs.ErrInvalidPagination, errs.ErrSearchTermTooShort, errs.ErrInvalidEnrollMode, errs.ErrInvalidCapacity, errs.ErrInvalidCourseRole, errs.ErrInvalidInviteUses, errs.ErrInviteExpiryPast, errs.ErrInvalidCSV, errs.ErrInvalidArchive, bcrypt.ErrMismatchedHashAndPassword}
+	CONFLICTS := []error{errs.ErrSelfRegisterExam, errs.ErrRegisterDeadlinePassed, errs.ErrUnregisterDeadlinePassed, errs.ErrExamEnded, errs.ErrExamHasntStarted, errs.ErrCourseNotEmpty, errs.ErrWrongEnrollkey, errs.ErrCourseAdminRole, errs.ErrEnrollmentClosed, errs.ErrCourseFull, errs.ErrCourseArchived, errs.ErrInviteExpired, errs.ErrInviteUsedUp, errs.ErrMissingPrerequisites, errs.ErrPrerequisiteExists, errs.ErrSemesterNotSet, errs.ErrExamHasntEnded, errs.ErrRegisterDeadlinePast, errs.ErrDeregisterDeadlinePast, errs.ErrRegisterDeadlineAfterDateTime, errs.ErrDeregisterDeadlineAfterDateTime, errs.ErrRegisterDeadlineAfterDerigsterDeadline, errs.ErrUploadOffsetMismatch}
 
 	log.Error(err)
 
@@ -311,6 +314,54 @@ func (f *PublicController) CreateCourse(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, newCourse)
 }
 
+// CopyCourse creates a new course, so only users that may create courses and administrate the copied one can copy it
+func (f *PublicController) CopyCourse(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeModerator(role_id) {
+		handleApiError(c, errs.ErrNotModerator)
+		return
+	}
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	var options struct {
+		Name       string `json:"name"`
+		OffsetDays int    `json:"offset_days"`
+	}
+	if err := c.BindJSON(&options); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	id, err := course.CopyCourse(f.Database, course_id, user_id, options.Name, options.OffsetDays)
+	if err != nil {
+		log.Errorf("Unable to copy course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, id)
+}
+
 func (f *PublicController) EnrollUser(c *gin.Context) {
 	user_id := c.MustGet("CookieUserId").(int)
 	role_id := c.MustGet("CookieRoleId").(int)
@@ -336,13 +387,18 @@ func (f *PublicController) EnrollUser(c *gin.Context) {
 		}
 	}
 
-	user, err := course.EnrollUser(f.Database, user_id, id, newCourse.EnrollKey)
+	user, status, err := course.EnrollUser(f.Database, user_id, id, newCourse.EnrollKey)
 	if err != nil {
 		log.Errorf("Unable to enroll user in course: %s", err.Error())
 		handleApiError(c, err)
 		return
 	}
 
+	// the user still has to wait for an approval or a free seat
+	if status != course.StatusEnrolled {
+		c.IndentedJSON(http.StatusAccepted, status)
+		return
+	}
 	c.IndentedJSON(http.StatusOK, user.ID)
 }
 
@@ -537,6 +593,18 @@ func (f *PublicController) UploadMaterial(c *gin.Context) {
 		return
 	}
 
+	// materials are uploaded to the top level of the course, unless a directory is given
+	var directory_id null.Int
+	if c.Param("directory_id") != "" {
+		id, err := strconv.Atoi(c.Param("directory_id"))
+		if err != nil {
+			log.Errorf("Unable to convert parameter `directory_id` to int: %s", err.Error())
+			handleApiError(c, errs.ErrParameterConversion)
+			return
+		}
+		directory_id = null.IntFrom(id)
+	}
+
 	if c.ContentType() == "text/plain" {
 		var file _file
 		if err := c.BindJSON(&file); err != nil {
@@ -545,31 +613,27 @@ func (f *PublicController) UploadMaterial(c *gin.Context) {
 			return
 		}
 
-		if err := coursematerial.CreateMaterial(f.Database, file.Name, file.Uri, user_id, course_id, false, nil, 0); err != nil {
+		if err := coursematerial.CreateMaterial(f.Database, file.Name, file.Uri, user_id, course_id, false, nil, 0, directory_id); err != nil {
 			log.Errorf("Unable to create CourseMaterial: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
 	} else {
-		file, err := c.FormFile("file")
-		if err != nil {
-			log.Error(err)
-			handleApiError(c, errs.ErrNoFileInRequest)
-			return
-		}
-
-		fi, err := file.Open()
+		file, err := f.formFile(c, user_id)
 		if err != nil {
+			log.Errorf("Unable to get file from request: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
+		defer file.Close()
 
-		err = coursematerial.CreateMaterial(f.Database, file.Filename, "", user_id, course_id, true, fi, int(file.Size))
+		err = coursematerial.CreateMaterial(f.Database, file.Name, "", user_id, course_id, true, file, file.Size, directory_id)
 		if err != nil {
 			log.Errorf("Unable to create CourseMaterial: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
+		file.Saved()
 	}
 
 	c.Status(http.StatusCreated)
@@ -597,33 +661,19 @@ func (f *PublicController) GetMaterialsFromCourse(c *gin.Context) {
 		return
 	}
 
-	type _file struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
-		URI  string `json:"uri"`
-	}
-
-	files, err := coursematerial.GetAllMaterialsFromCourse(f.Database, course_id)
+	// course moderators see directories before they become visible to course users
+	tree, err := coursematerial.GetMaterialTree(f.Database, course_id, AuthorizeCourseModerator(course_role, role_id))
 	if err != nil {
 		log.Errorf("Unable to get all materials from course: %s", err.Error())
 		handleApiError(c, err)
 		return
 	}
 
-	var _files []_file
-	for _, file := range files {
-		uri := ""
-		if file.Local == 0 {
-			uri = file.URI
-		}
-
-		_files = append(_files, _file{file.ID, file.Name, uri})
-	}
-
-	c.IndentedJSON(http.StatusOK, _files)
+	c.IndentedJSON(http.StatusOK, tree)
 }
 
-func (f *PublicController) GetMaterialFromCourse(c *gin.Context) {
+// authorizedMaterial returns the material requested by the route parameters if the user may see it, otherwise it handles the error and returns nil
+func (f *PublicController) authorizedMaterial(c *gin.Context) *models.File {
 	user_id := c.MustGet("CookieUserId").(int)
 	role_id := c.MustGet("CookieRoleId").(int)
 
@@ -631,36 +681,55 @@ func (f *PublicController) GetMaterialFromCourse(c *gin.Context) {
 	if err != nil {
 		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
 		handleApiError(c, errs.ErrParameterConversion)
-		return
+		return nil
 	}
 
 	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
 	if err != nil {
 		log.Errorf("Unable to get course role: %s", err.Error())
 		handleApiError(c, err)
-		return
+		return nil
 	}
 	if !AuthorizeCourseUser(course_role, role_id) {
 		handleApiError(c, errs.ErrNotCourseUser)
-		return
+		return nil
 	}
 
 	file_id, err := strconv.Atoi(c.Param("file_id"))
 	if err != nil {
 		log.Errorf("Unable to convert parameter `file_id` to int: %s", err.Error())
 		handleApiError(c, errs.ErrParameterConversion)
-		return
+		return nil
+	}
+
+	if !AuthorizeCourseModerator(course_role, role_id) {
+		visible, err := coursematerial.IsMaterialVisible(f.Database, course_id, file_id)
+		if err != nil {
+			log.Errorf("Unable to check visibility of material with id %d: %s", file_id, err.Error())
+			handleApiError(c, err)
+			return nil
+		}
+		// hidden materials are treated as if they didn't exist yet
+		if !visible {
+			handleApiError(c, sql.ErrNoRows)
+			return nil
+		}
 	}
 
 	file, err := coursematerial.GetMaterialFromCourse(f.Database, course_id, file_id)
 	if err != nil {
 		log.Errorf("Unable to get material with id %d from course: %s", file_id, err.Error())
 		handleApiError(c, err)
-		return
+		return nil
 	}
 
-	c.File(file.URI)
-	c.Status(http.StatusOK)
+	return file
+}
+
+func (f *PublicController) GetMaterialFromCourse(c *gin.Context) {
+	if file := f.authorizedMaterial(c); file != nil {
+		f.sendFile(c, file)
+	}
 }
 
 func (f *PublicController) DeleteMaterialFromCourse(c *gin.Context) {
@@ -703,6 +772,31 @@ func (f *PublicController) DeleteMaterialFromCourse(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+func (f *PublicController) SearchMaterials(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	term, ok := c.GetQuery("searchterm")
+	if !ok {
+		handleApiError(c, errs.ErrNoQuery)
+		return
+	}
+
+	hits, err := coursematerial.SearchMaterials(f.Database, user_id, term)
+	if err != nil {
+		log.Errorf("Unable to search materials: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, hits)
+}
+
 func (f *PublicController) DeleteUser(c *gin.Context) {
 	role_id := c.MustGet("CookieRoleId").(int)
 
@@ -836,6 +930,37 @@ func (f *PublicController) AddCourseToCalender(c *gin.Context) {
 		return
 	}
 
+	// repeating the appointment is optional; a series ends at `until` or after `count` appointments
+	repeat := calender.None
+	if r, ok := j["repeat"].(string); ok {
+		n, err := strconv.Atoi(r)
+		if err != nil {
+			log.Error("unable to convert string to int")
+			handleApiError(c, errs.ErrBodyConversion)
+			return
+		}
+		repeat = calender.RepeatDistance(n)
+	}
+	var until null.Time
+	if u, ok := j["until"].(string); ok && u != "" {
+		t, err := time.Parse(time.RFC3339, u)
+		if err != nil {
+			log.Error("unable to convert string to time.Time")
+			handleApiError(c, errs.ErrBodyConversion)
+			return
+		}
+		until = null.TimeFrom(t)
+	}
+	count := 0
+	if n, ok := j["count"].(string); ok && n != "" {
+		count, err = strconv.Atoi(n)
+		if err != nil {
+			log.Error("unable to convert string to int")
+			handleApiError(c, errs.ErrBodyConversion)
+			return
+		}
+	}
+
 	// authorization
 	course_role, err := course.GetCourseRole(f.Database, user_id, int(courseId))
 	if err != nil {
@@ -849,7 +974,7 @@ func (f *PublicController) AddCourseToCalender(c *gin.Context) {
 	}
 
 	pCon := &calender.PublicController{Database: f.Database}
-	_, err = pCon.AddCourseToCalender(date, int(duration), null.StringFrom(location), int8(online), int(courseId))
+	_, err = pCon.AddCourseToCalender(date, int(duration), null.StringFrom(location), int8(online), int(courseId), repeat, until, count)
 	if err != nil {
 		log.Errorf("Unable to add course to calendar: %s", err.Error())
 		handleApiError(c, err)
@@ -896,8 +1021,11 @@ func (f *PublicController) DeactivateCourseInCalender(c *gin.Context) {
 		return
 	}
 
+	// only the given appointment is cancelled, unless the whole series is requested
+	series, _ := j["series"].(bool)
+
 	pCon := &calender.PublicController{Database: f.Database}
-	err = pCon.DeactivateCourseInCalender(appointment_id)
+	err = pCon.DeactivateCourseInCalender(appointment_id, series)
 	if err != nil {
 		log.Errorf("Unable to deactivate course in calendar: %s", err.Error())
 		handleApiError(c, err)
@@ -907,7 +1035,61 @@ func (f *PublicController) DeactivateCourseInCalender(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+func (f *PublicController) EditAppointment(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+	user_id := c.MustGet("CookieUserId").(int)
+
+	appointment_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	var j struct {
+		Date     time.Time   `json:"date"`
+		Duration int         `json:"duration"`
+		Location null.String `json:"location"`
+		Online   int8        `json:"online"`
+		Series   bool        `json:"series"`
+	}
+	if err := c.BindJSON(&j); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	// authorization
+	appointment, err := models.FindAppointment(context.Background(), f.Database, appointment_id)
+	if err != nil {
+		log.Errorf("Unable to get appointment from id: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	course_role, err := course.GetCourseRole(f.Database, user_id, appointment.CourseID)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseModerator(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseModerator)
+		return
+	}
+
+	pCon := &calender.PublicController{Database: f.Database}
+	err = pCon.EditAppointment(appointment_id, j.Date, j.Duration, j.Location, j.Online, j.Series)
+	if err != nil {
+		log.Errorf("Unable to edit appointment: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
 func (f *PublicController) SearchCourse(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
 	role_id := c.MustGet("CookieRoleId").(int)
 
 	if !AuthorizeUser(role_id) {
@@ -916,20 +1098,106 @@ func (f *PublicController) SearchCourse(c *gin.Context) {
 		return
 	}
 
-	searchterm, ok := c.GetQuery("searchterm")
-	if !ok {
-		handleApiError(c, errs.ErrNoQuery)
-		return
+	query := course.SearchQuery{Term: c.Query("searchterm")}
+
+	var err error
+	var archived, open_seats null.Bool
+	var page, page_size null.Int
+	for name, v := range map[string]*null.Int{"field_of_study": &query.FieldOfStudyID, "semester": &query.Semester, "page": &page, "page_size": &page_size} {
+		if *v, err = queryNullInt(c, name); err != nil {
+			log.Errorf("Unable to convert query `%s` to int: %s", name, err.Error())
+			handleApiError(c, errs.ErrParameterConversion)
+			return
+		}
 	}
+	for name, v := range map[string]*null.Bool{"enrolled": &query.Enrolled, "archived": &archived, "open_seats": &open_seats} {
+		if *v, err = queryNullBool(c, name); err != nil {
+			log.Errorf("Unable to convert query `%s` to bool: %s", name, err.Error())
+			handleApiError(c, errs.ErrParameterConversion)
+			return
+		}
+	}
+	query.IncludeArchived = archived.Bool
+	query.OpenSeats = open_seats.Bool
+	query.Page = page.Int
+	query.PageSize = page_size.Int
 
-	courses, err := course.SearchCourse(f.Database, searchterm)
+	result, err := course.SearchCourse(f.Database, user_id, query)
 	if err != nil {
 		log.Errorf("Unable to search course: %s\n", err.Error())
 		handleApiError(c, err)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, courses)
+	c.IndentedJSON(http.StatusOK, result)
+}
+
+// contentDisposition returns the Content-Disposition header showing a file inline under its display name
+func contentDisposition(name string) string {
+	if d := mime.FormatMediaType("inline", map[string]string{"filename": name}); d != "" {
+		return d
+	}
+	return "inline"
+}
+
+// sendFile serves the content of a local file from its storage backend to the client.
+// Range requests and conditional requests are answered, so that downloads can be resumed and unchanged files aren't downloaded again.
+func (f *PublicController) sendFile(c *gin.Context, file *models.File) {
+	content, err := dbi.OpenFile(f.Database, file)
+	if err != nil {
+		log.Errorf("Unable to open file with id %d: %s", file.ID, err.Error())
+		handleApiError(c, err)
+		return
+	}
+	defer content.Close()
+
+	// files saved before their type was detected fall back to the type of their extension
+	contentType := file.MimeType.String
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(file.Name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", contentDisposition(file.Name))
+	c.Header("X-Content-Type-Options", "nosniff")
+	// files are only served to authorized users, so shared caches must not keep them, and clients have to revalidate them
+	c.Header("Cache-Control", "private, no-cache")
+	if content.Sha256 != "" {
+		// the content of a file never changes, so its hash is a strong validator
+		c.Header("ETag", `"`+content.Sha256+`"`)
+	}
+
+	http.ServeContent(c.Writer, c.Request, file.Name, file.CreatedAt, content)
+}
+
+// queryNullInt returns the query parameter with the given name as an int, or null if it isn't set
+func queryNullInt(c *gin.Context, name string) (null.Int, error) {
+	v := c.Query(name)
+	if v == "" {
+		return null.Int{}, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return null.Int{}, err
+	}
+	return null.IntFrom(i), nil
+}
+
+// queryNullBool returns the query parameter with the given name as a bool, or null if it isn't set
+func queryNullBool(c *gin.Context, name string) (null.Bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return null.Bool{}, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return null.Bool{}, err
+	}
+	return null.BoolFrom(b), nil
 }
 
 func (f *PublicController) CreateExam(c *gin.Context) {
@@ -1301,27 +1569,21 @@ func (f *PublicController) UploadExamFile(c *gin.Context) {
 			return
 		}
 	} else {
-		file, err := c.FormFile("file")
+		file, err := f.formFile(c, user_id)
 		if err != nil {
-			log.Errorf("No file found in request: %s", err.Error())
-			log.Error(err)
-			handleApiError(c, errs.ErrNoFileInRequest)
-			return
-		}
-
-		fi, err := file.Open()
-		if err != nil {
-			log.Errorf("Unable to open file: %s", err.Error())
-			log.Error(err)
+			log.Errorf("Unable to get file from request: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
-		err = pCtrl.UploadExamFile(file.Filename, "", user_id, id, true, fi, int(file.Size))
+		defer file.Close()
+
+		err = pCtrl.UploadExamFile(file.Name, "", user_id, id, true, file, file.Size)
 		if err != nil {
 			log.Errorf("Unable to create ExamFile: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
+		file.Saved()
 	}
 
 	c.Status(http.StatusCreated)
@@ -1556,8 +1818,7 @@ func (f *PublicController) GetFileFromExam(c *gin.Context) {
 		return
 	}
 
-	c.File(file[0].URI)
-	c.Status(http.StatusOK)
+	f.sendFile(c, file[0])
 }
 
 func (f *PublicController) SubmitAnswerToExam(c *gin.Context) {
@@ -1592,26 +1853,21 @@ func (f *PublicController) SubmitAnswerToExam(c *gin.Context) {
 			return
 		}
 	} else {
-		file, err := c.FormFile("file")
-		if err != nil {
-			log.Errorf("No file found in request: %s", err.Error())
-			log.Error(err)
-			handleApiError(c, errs.ErrNoFileInRequest)
-			return
-		}
-
-		fi, err := file.Open()
+		file, err := f.formFile(c, userId)
 		if err != nil {
-			log.Errorf("Unable to open file: %s", err.Error())
+			log.Errorf("Unable to get file from request: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
-		err = pCtrl.SubmitAnswer(file.Filename, "", examId, userId, true, fi, int(file.Size))
+		defer file.Close()
+
+		err = pCtrl.SubmitAnswer(file.Name, "", examId, userId, true, file, file.Size)
 		if err != nil {
 			log.Errorf("Unable to submit answer: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
+		file.Saved()
 	}
 
 	err = pCtrl.AttendExam(examId, userId)
@@ -1749,8 +2005,7 @@ func (f *PublicController) GetFileFromAttendee(c *gin.Context) {
 		handleApiError(c, err)
 		return
 	}
-	c.File(file.URI)
-	c.Status(http.StatusOK)
+	f.sendFile(c, file)
 }
 
 func (f *PublicController) GradeAnswer(c *gin.Context) {
@@ -2032,8 +2287,10 @@ func (f *PublicController) CreateSubmission(c *gin.Context) {
 		handleApiError(c, errs.ErrBodyConversion)
 		return
 	}
+	// optional, comma separated extensions of the files that may be submitted
+	allowed_file_types, _ := j["allowed_file_types"].(string)
 
-	id, err := course.CreateSubmission(f.Database, name, deadline, course_id, max_filesize, visible_from)
+	id, err := course.CreateSubmission(f.Database, name, deadline, course_id, max_filesize, visible_from, allowed_file_types)
 	if err != nil {
 		log.Errorf("Unable to create submission: %s", err.Error())
 		handleApiError(c, err)
@@ -2147,8 +2404,10 @@ func (f *PublicController) EditSubmissionById(c *gin.Context) {
 		handleApiError(c, errs.ErrBodyConversion)
 		return
 	}
+	// optional, comma separated extensions of the files that may be submitted
+	allowed_file_types, _ := j["allowed_file_types"].(string)
 
-	_, err = course.EditSubmission(f.Database, submission_id, name, deadline, max_filesize, visible_from)
+	_, err = course.EditSubmission(f.Database, submission_id, name, deadline, max_filesize, visible_from, allowed_file_types)
 	if err != nil {
 		log.Errorf("Unable to update submission: %s", err.Error())
 		handleApiError(c, err)
@@ -2215,27 +2474,21 @@ func (f *PublicController) CreateSubmissionHasFiles(c *gin.Context) {
 			return
 		}
 	} else {
-		file, err := c.FormFile("file")
+		file, err := f.formFile(c, user_id)
 		if err != nil {
-			log.Errorf("No file found in request: %s", err.Error())
-			log.Error(err)
-			handleApiError(c, errs.ErrNoFileInRequest)
-			return
-		}
-
-		fi, err := file.Open()
-		if err != nil {
-			log.Errorf("Unable to open file: %s", err.Error())
+			log.Errorf("Unable to get file from request: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
+		defer file.Close()
 
-		err = course.CreateSubmissionHasFiles(f.Database, submission_id, file.Filename, "", user_id, true, fi, int(file.Size))
+		err = course.CreateSubmissionHasFiles(f.Database, submission_id, file.Name, "", user_id, true, file, file.Size)
 		if err != nil {
 			log.Errorf("Unable to add file to submission: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
+		file.Saved()
 	}
 
 	c.Status(http.StatusCreated)
@@ -2478,27 +2731,21 @@ func (f *PublicController) CreateUserSubmissionHasFiles(c *gin.Context) {
 			return
 		}
 	} else {
-		file, err := c.FormFile("file")
-		if err != nil {
-			log.Errorf("No file found in request: %s", err.Error())
-			log.Error(err)
-			handleApiError(c, errs.ErrNoFileInRequest)
-			return
-		}
-
-		fi, err := file.Open()
+		file, err := f.formFile(c, user_id)
 		if err != nil {
-			log.Errorf("Unable to open file: %s", err.Error())
+			log.Errorf("Unable to get file from request: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
+		defer file.Close()
 
-		err = course.CreateUserSubmissionHasFiles(f.Database, user_submission_id, file.Filename, "", user_id, true, fi, int(file.Size))
+		err = course.CreateUserSubmissionHasFiles(f.Database, user_submission_id, file.Name, "", user_id, true, file, file.Size)
 		if err != nil {
 			log.Errorf("Unable to add file to user submission: %s", err.Error())
 			handleApiError(c, err)
 			return
 		}
+		file.Saved()
 	}
 
 	c.Status(http.StatusCreated)
@@ -2766,6 +3013,119 @@ func (f *PublicController) GetFileFromUserSubmission(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, users)
 }
 
+// authorizedSubmissionFile returns the file of a submission requested by the route parameters if the user is a course user, otherwise it handles the error and returns nil
+func (f *PublicController) authorizedSubmissionFile(c *gin.Context) *models.File {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	submission_id, err := strconv.Atoi(c.Param("submission_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `submission_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return nil
+	}
+	file_id, err := strconv.Atoi(c.Param("file_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `file_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return nil
+	}
+
+	course_id, err := course.GetCourseIdBySubmission(f.Database, submission_id)
+	if err != nil {
+		log.Errorf("Unable to get `course_id` by submission: %s", err.Error())
+		handleApiError(c, err)
+		return nil
+	}
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return nil
+	}
+	if !AuthorizeCourseUser(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseUser)
+		return nil
+	}
+
+	file, err := course.GetSubmissionFile(f.Database, submission_id, file_id)
+	if err != nil {
+		log.Errorf("Unable to get file with id %d from submission: %s", file_id, err.Error())
+		handleApiError(c, err)
+		return nil
+	}
+
+	return file
+}
+
+// DownloadFileFromSubmission sends the content of a file of a submission to course users
+func (f *PublicController) DownloadFileFromSubmission(c *gin.Context) {
+	if file := f.authorizedSubmissionFile(c); file != nil {
+		f.sendFile(c, file)
+	}
+}
+
+// authorizedUserSubmissionFile returns the file of a user submission requested by the route parameters if the user is its submitter or a course moderator,
+// otherwise it handles the error and returns nil
+func (f *PublicController) authorizedUserSubmissionFile(c *gin.Context) *models.File {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	user_submission_id, err := strconv.Atoi(c.Param("usersubmission_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `usersubmission_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return nil
+	}
+	file_id, err := strconv.Atoi(c.Param("file_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `file_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return nil
+	}
+
+	user_submission, err := course.GetUserSubmission(f.Database, user_submission_id)
+	if err != nil {
+		log.Errorf("Unable to get user submission: %s", err.Error())
+		handleApiError(c, err)
+		return nil
+	}
+	if user_submission.SubmitterID != user_id {
+		course_id, err := course.GetCourseIdBySubmission(f.Database, user_submission.SubmissionID)
+		if err != nil {
+			log.Errorf("Unable to get `course_id` by submission: %s", err.Error())
+			handleApiError(c, err)
+			return nil
+		}
+		course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+		if err != nil {
+			log.Errorf("Unable to get course role: %s", err.Error())
+			handleApiError(c, err)
+			return nil
+		}
+		if !AuthorizeCourseModerator(course_role, role_id) {
+			handleApiError(c, errs.ErrNotCourseModerator)
+			return nil
+		}
+	}
+
+	file, err := course.GetUserSubmissionFile(f.Database, user_submission_id, file_id)
+	if err != nil {
+		log.Errorf("Unable to get file with id %d from user submission: %s", file_id, err.Error())
+		handleApiError(c, err)
+		return nil
+	}
+
+	return file
+}
+
+// DownloadFileFromUserSubmission sends the content of a file of a user submission to its submitter and the course moderators
+func (f *PublicController) DownloadFileFromUserSubmission(c *gin.Context) {
+	if file := f.authorizedUserSubmissionFile(c); file != nil {
+		f.sendFile(c, file)
+	}
+}
+
 func (f *PublicController) GetUserCourseRole(c *gin.Context) {
 	user_id := c.MustGet("CookieUserId").(int)
 
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/course"
+	"learningbay24.de/backend/errs"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+func (f *PublicController) ArchiveCourse(c *gin.Context) {
+	f.setCourseArchived(c, true)
+}
+
+func (f *PublicController) UnarchiveCourse(c *gin.Context) {
+	f.setCourseArchived(c, false)
+}
+
+func (f *PublicController) setCourseArchived(c *gin.Context, archived bool) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	if archived {
+		err = course.ArchiveCourse(f.Database, course_id)
+	} else {
+		err = course.UnarchiveCourse(f.Database, course_id)
+	}
+	if err != nil {
+		log.Errorf("Unable to change archived state of course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) GetArchivedCoursesFromUser(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	user_id := c.MustGet("CookieUserId").(int)
+
+	courses, err := course.GetArchivedCoursesFromUser(f.Database, user_id)
+	if err != nil {
+		log.Errorf("Unable to get archived courses from user: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, courses)
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"learningbay24.de/backend/calender"
+	"learningbay24.de/backend/course"
+	"learningbay24.de/backend/errs"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+func (f *PublicController) GetCalendarFeedToken(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	pCon := &calender.PublicController{Database: f.Database}
+	token, err := pCon.GetFeedToken(user_id)
+	if err != nil {
+		log.Errorf("Unable to get calendar feed token: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, token)
+}
+
+func (f *PublicController) RegenerateCalendarFeedToken(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	pCon := &calender.PublicController{Database: f.Database}
+	token, err := pCon.RegenerateFeedToken(user_id)
+	if err != nil {
+		log.Errorf("Unable to regenerate calendar feed token: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, token)
+}
+
+// GetCalendarFeed is reachable without being logged in, since calendar applications subscribing to it can't send the session cookie.
+// Knowing the randomly generated token is what proves the access to it.
+func (f *PublicController) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	pCon := &calender.PublicController{Database: f.Database}
+	user_id, err := pCon.GetUserIdFromFeedToken(token)
+	if err != nil {
+		log.Errorf("Unable to get user from calendar feed token: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := pCon.ExportCalendar(user_id, &buf); err != nil {
+		log.Errorf("Unable to export calendar of user %d: %s", user_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "inline; filename=\"calendar.ics\"")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+func (f *PublicController) ImportAppointments(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseModerator(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseModerator)
+		return
+	}
+
+	// a dry run only previews the appointments that would be created
+	dry_run := false
+	if d := c.Query("dry_run"); d != "" {
+		dry_run, err = strconv.ParseBool(d)
+		if err != nil {
+			log.Errorf("Unable to convert query `dry_run` to bool: %s", err.Error())
+			handleApiError(c, errs.ErrParameterConversion)
+			return
+		}
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("Unable to get file from request: %s", err.Error())
+		handleApiError(c, errs.ErrNoFileInRequest)
+		return
+	}
+
+	fileContent, err := file.Open()
+	if err != nil {
+		log.Errorf("Unable to open file: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	defer fileContent.Close()
+
+	pCon := &calender.PublicController{Database: f.Database}
+	result, err := pCon.ImportAppointments(course_id, fileContent, dry_run)
+	if err != nil {
+		log.Errorf("Unable to import appointments: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	if dry_run {
+		c.IndentedJSON(http.StatusOK, result)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, result)
+}
+
+func (f *PublicController) GetTimeline(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	// without a range, the timeline shows the next four weeks
+	from := time.Now()
+	if q := c.Query("from"); q != "" {
+		t, err := time.Parse(time.RFC3339, q)
+		if err != nil {
+			log.Errorf("Unable to convert query `from` to time: %s", err.Error())
+			handleApiError(c, errs.ErrParameterConversion)
+			return
+		}
+		from = t
+	}
+	to := from.AddDate(0, 0, 28)
+	if q := c.Query("to"); q != "" {
+		t, err := time.Parse(time.RFC3339, q)
+		if err != nil {
+			log.Errorf("Unable to convert query `to` to time: %s", err.Error())
+			handleApiError(c, errs.ErrParameterConversion)
+			return
+		}
+		to = t
+	}
+
+	pCon := &calender.PublicController{Database: f.Database}
+	timeline, err := pCon.GetTimeline(user_id, from, to)
+	if err != nil {
+		log.Errorf("Unable to get timeline: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, timeline)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"learningbay24.de/backend/certificate"
+	"learningbay24.de/backend/errs"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+func (f *PublicController) GetCertificatesFromUser(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	user_id := c.MustGet("CookieUserId").(int)
+
+	certs, err := certificate.GetCertificatesFromUser(f.Database, user_id)
+	if err != nil {
+		log.Errorf("Unable to get certificates from user: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, certs)
+}
+
+// VerifyCertificate is reachable without being logged in, so that anyone a certificate is handed to can check it.
+// Knowing the randomly generated ID is what proves the access to it.
+func (f *PublicController) VerifyCertificate(c *gin.Context) {
+	id := c.Param("id")
+
+	cert, err := certificate.GetCertificate(f.Database, id)
+	if err != nil {
+		log.Errorf("Unable to get certificate with id %s: %s", id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, cert)
+}
+
+func (f *PublicController) GetCertificatePDF(c *gin.Context) {
+	id := c.Param("id")
+
+	cert, err := certificate.GetCertificate(f.Database, id)
+	if err != nil {
+		log.Errorf("Unable to get certificate with id %s: %s", id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := certificate.WritePDF(&buf, cert); err != nil {
+		log.Errorf("Unable to render certificate with id %s: %s", id, err.Error())
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"certificate-%s.pdf\"", cert.ID))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/course"
+	coursematerial "learningbay24.de/backend/courseMaterial"
+	"learningbay24.de/backend/errs"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+)
+
+type _directory struct {
+	Name        string    `json:"name"`
+	ParentID    null.Int  `json:"parent_id"`
+	VisibleFrom null.Time `json:"visible_from"`
+}
+
+// authorizeDirectoryRequest parses the parameters shared by all directory routes and checks that the cookie user moderates the course.
+// If false is returned, the response has already been written.
+func (f *PublicController) authorizeDirectoryRequest(c *gin.Context, param string) (int, int, bool) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return 0, 0, false
+	}
+
+	id := 0
+	if param != "" {
+		id, err = strconv.Atoi(c.Param(param))
+		if err != nil {
+			log.Errorf("Unable to convert parameter `%s` to int: %s", param, err.Error())
+			handleApiError(c, errs.ErrParameterConversion)
+			return 0, 0, false
+		}
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return 0, 0, false
+	}
+	if !AuthorizeCourseModerator(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseModerator)
+		return 0, 0, false
+	}
+
+	return course_id, id, true
+}
+
+func (f *PublicController) CreateDirectory(c *gin.Context) {
+	course_id, _, ok := f.authorizeDirectoryRequest(c, "")
+	if !ok {
+		return
+	}
+
+	var dir _directory
+	if err := c.BindJSON(&dir); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	id, err := coursematerial.CreateDirectory(f.Database, course_id, dir.ParentID, dir.Name, dir.VisibleFrom)
+	if err != nil {
+		log.Errorf("Unable to create directory: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, id)
+}
+
+func (f *PublicController) EditDirectory(c *gin.Context) {
+	course_id, directory_id, ok := f.authorizeDirectoryRequest(c, "directory_id")
+	if !ok {
+		return
+	}
+
+	var dir _directory
+	if err := c.BindJSON(&dir); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	err := coursematerial.EditDirectory(f.Database, course_id, directory_id, dir.Name, dir.VisibleFrom)
+	if err != nil {
+		log.Errorf("Unable to edit directory with id %d: %s", directory_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) MoveDirectory(c *gin.Context) {
+	course_id, directory_id, ok := f.authorizeDirectoryRequest(c, "directory_id")
+	if !ok {
+		return
+	}
+
+	var dir _directory
+	if err := c.BindJSON(&dir); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	err := coursematerial.MoveDirectory(f.Database, course_id, directory_id, dir.ParentID)
+	if err != nil {
+		log.Errorf("Unable to move directory with id %d: %s", directory_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) DeleteDirectory(c *gin.Context) {
+	course_id, directory_id, ok := f.authorizeDirectoryRequest(c, "directory_id")
+	if !ok {
+		return
+	}
+
+	err := coursematerial.DeleteDirectory(f.Database, course_id, directory_id)
+	if err != nil {
+		log.Errorf("Unable to delete directory with id %d: %s", directory_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (f *PublicController) MoveMaterial(c *gin.Context) {
+	course_id, file_id, ok := f.authorizeDirectoryRequest(c, "file_id")
+	if !ok {
+		return
+	}
+
+	var target struct {
+		DirectoryID null.Int `json:"directory_id"`
+	}
+	if err := c.BindJSON(&target); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	err := coursematerial.MoveMaterial(f.Database, course_id, file_id, target.DirectoryID)
+	if err != nil {
+		log.Errorf("Unable to move file with id %d: %s", file_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
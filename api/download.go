@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+	signedurl "learningbay24.de/backend/signedUrl"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+type _downloadLink struct {
+	// Path and query of the URL, relative to the API
+	Url       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// sendFileLink responds with a signed URL that downloads the file without being logged in, until it expires
+func sendFileLink(c *gin.Context, file *models.File) {
+	url, expires := signedurl.URL(file.ID, time.Now())
+	c.IndentedJSON(http.StatusOK, _downloadLink{Url: url, ExpiresAt: expires})
+}
+
+func (f *PublicController) GetMaterialLink(c *gin.Context) {
+	if file := f.authorizedMaterial(c); file != nil {
+		sendFileLink(c, file)
+	}
+}
+
+func (f *PublicController) GetSubmissionFileLink(c *gin.Context) {
+	if file := f.authorizedSubmissionFile(c); file != nil {
+		sendFileLink(c, file)
+	}
+}
+
+func (f *PublicController) GetUserSubmissionFileLink(c *gin.Context) {
+	if file := f.authorizedUserSubmissionFile(c); file != nil {
+		sendFileLink(c, file)
+	}
+}
+
+// DownloadSignedFile is reachable without being logged in, so that files can be opened in external viewers and downloaders.
+// The signature proves that a user allowed to download the file requested the URL shortly before.
+func (f *PublicController) DownloadSignedFile(c *gin.Context) {
+	file_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	if err := signedurl.Verify(file_id, c.Query("expires"), c.Query("signature"), time.Now()); err != nil {
+		log.Infof("Refused download of file with id %d: %s", file_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	file, err := models.FindFile(context.Background(), f.Database, file_id)
+	if err != nil {
+		log.Errorf("Unable to get file with id %d: %s", file_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	f.sendFile(c, file)
+}
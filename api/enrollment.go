@@ -0,0 +1,165 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/course"
+	"learningbay24.de/backend/errs"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+)
+
+func (f *PublicController) SetEnrollmentOptions(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	var options struct {
+		EnrollMode int8     `json:"enroll_mode"`
+		Capacity   null.Int `json:"capacity"`
+	}
+	if err := c.BindJSON(&options); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	err = course.SetEnrollmentOptions(f.Database, course_id, course.EnrollMode(options.EnrollMode), options.Capacity)
+	if err != nil {
+		log.Errorf("Unable to set enrollment options of course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) GetEnrollmentRequests(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	requests, err := course.GetEnrollmentRequests(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get enrollment requests from course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, requests)
+}
+
+func (f *PublicController) ApproveEnrollmentRequest(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	requester_id, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `user_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	status, err := course.ApproveEnrollmentRequest(f.Database, requester_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to approve enrollment request: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, status)
+}
+
+func (f *PublicController) RejectEnrollmentRequest(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	requester_id, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `user_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	err = course.RejectEnrollmentRequest(f.Database, requester_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to reject enrollment request: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/course"
+	courseexport "learningbay24.de/backend/courseExport"
+	"learningbay24.de/backend/errs"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+func (f *PublicController) ExportCourse(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	manifest, err := courseexport.GetManifest(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get manifest of course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"course-%d.zip\"", course_id))
+	c.Status(http.StatusOK)
+	// NOTE: the archive is streamed, so the status can't change anymore if writing it fails
+	if err := courseexport.WriteArchive(f.Database, c.Writer, manifest); err != nil {
+		log.Errorf("Unable to write course archive: %s", err.Error())
+	}
+}
+
+// ImportCourse creates a new course, so only users that may create courses can import one
+func (f *PublicController) ImportCourse(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeModerator(role_id) {
+		handleApiError(c, errs.ErrNotModerator)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("Unable to get file from request: %s", err.Error())
+		handleApiError(c, errs.ErrNoFileInRequest)
+		return
+	}
+
+	fileContent, err := file.Open()
+	if err != nil {
+		log.Errorf("Unable to open file: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	defer fileContent.Close()
+
+	id, err := courseexport.ImportCourse(f.Database, user_id, fileContent, file.Size, c.PostForm("name"))
+	if err != nil {
+		log.Errorf("Unable to import course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, id)
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/errs"
+	fieldofstudy "learningbay24.de/backend/fieldOfStudy"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+func (f *PublicController) GetFieldsOfStudy(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	fos, err := fieldofstudy.GetFieldsOfStudy(f.Database)
+	if err != nil {
+		log.Errorf("Unable to get fields of study: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, fos)
+}
+
+func (f *PublicController) CreateFieldOfStudy(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeAdmin(role_id) {
+		handleApiError(c, errs.ErrNotAdmin)
+		return
+	}
+
+	var fos struct {
+		Name      string `json:"name"`
+		Semesters int    `json:"semesters"`
+	}
+	if err := c.BindJSON(&fos); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	id, err := fieldofstudy.CreateFieldOfStudy(f.Database, fos.Name, fos.Semesters)
+	if err != nil {
+		log.Errorf("Unable to create field of study: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, id)
+}
+
+func (f *PublicController) GetCoursesFromFieldOfStudy(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	courses, err := fieldofstudy.GetCoursesFromFieldOfStudy(f.Database, id)
+	if err != nil {
+		log.Errorf("Unable to get courses from field of study: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, courses)
+}
+
+func (f *PublicController) SetCourseSemesterInFieldOfStudy(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeAdmin(role_id) {
+		handleApiError(c, errs.ErrNotAdmin)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_id, err := strconv.Atoi(c.Param("course_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `course_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	var body struct {
+		Semester int `json:"semester"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	err = fieldofstudy.SetCourseSemester(f.Database, id, course_id, body.Semester)
+	if err != nil {
+		log.Errorf("Unable to add course to field of study: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) RemoveCourseFromFieldOfStudy(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeAdmin(role_id) {
+		handleApiError(c, errs.ErrNotAdmin)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_id, err := strconv.Atoi(c.Param("course_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `course_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	err = fieldofstudy.RemoveCourseFromFieldOfStudy(f.Database, id, course_id)
+	if err != nil {
+		log.Errorf("Unable to remove course from field of study: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (f *PublicController) GetFieldsOfStudyFromUser(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	fos, err := fieldofstudy.GetFieldsOfStudyFromUser(f.Database, user_id)
+	if err != nil {
+		log.Errorf("Unable to get fields of study from user: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, fos)
+}
+
+func (f *PublicController) JoinFieldOfStudy(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	err = fieldofstudy.JoinFieldOfStudy(f.Database, user_id, id)
+	if err != nil {
+		log.Errorf("Unable to join field of study: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) LeaveFieldOfStudy(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	err = fieldofstudy.LeaveFieldOfStudy(f.Database, user_id, id)
+	if err != nil {
+		log.Errorf("Unable to leave field of study: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (f *PublicController) GetCurriculum(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	curriculum, err := fieldofstudy.GetCurriculum(f.Database, user_id)
+	if err != nil {
+		log.Errorf("Unable to get curriculum from user: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, curriculum)
+}
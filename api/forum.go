@@ -0,0 +1,314 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/course"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/forum"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+type _forumEntry struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+}
+
+func (f *PublicController) GetForumThreads(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseUser(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseUser)
+		return
+	}
+
+	forum_id, err := forum.GetForumIdFromCourse(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get forum from course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	threads, err := forum.GetThreads(f.Database, forum_id)
+	if err != nil {
+		log.Errorf("Unable to get threads from forum: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, threads)
+}
+
+func (f *PublicController) GetForumThread(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	entry_id, err := strconv.Atoi(c.Param("entry_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `entry_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseUser(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseUser)
+		return
+	}
+
+	forum_id, err := forum.GetForumIdFromCourse(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get forum from course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	thread, err := forum.GetThread(f.Database, forum_id, entry_id)
+	if err != nil {
+		log.Errorf("Unable to get thread with id %d: %s", entry_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, thread)
+}
+
+func (f *PublicController) CreateForumThread(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseUser(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseUser)
+		return
+	}
+
+	var entry _forumEntry
+	if err := c.BindJSON(&entry); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	forum_id, err := forum.GetForumIdFromCourse(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get forum from course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	id, err := forum.CreateThread(f.Database, forum_id, user_id, entry.Subject, entry.Content)
+	if err != nil {
+		log.Errorf("Unable to create thread: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, id)
+}
+
+func (f *PublicController) ReplyToForumEntry(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	entry_id, err := strconv.Atoi(c.Param("entry_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `entry_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseUser(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseUser)
+		return
+	}
+
+	var entry _forumEntry
+	if err := c.BindJSON(&entry); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	forum_id, err := forum.GetForumIdFromCourse(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get forum from course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	id, err := forum.ReplyToEntry(f.Database, forum_id, entry_id, user_id, entry.Subject, entry.Content)
+	if err != nil {
+		log.Errorf("Unable to reply to entry with id %d: %s", entry_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, id)
+}
+
+func (f *PublicController) EditForumEntry(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	entry_id, err := strconv.Atoi(c.Param("entry_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `entry_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseUser(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseUser)
+		return
+	}
+
+	var entry _forumEntry
+	if err := c.BindJSON(&entry); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	forum_id, err := forum.GetForumIdFromCourse(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get forum from course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	err = forum.EditEntry(f.Database, forum_id, entry_id, user_id, entry.Subject, entry.Content)
+	if err != nil {
+		log.Errorf("Unable to edit entry with id %d: %s", entry_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) DeleteForumEntry(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	entry_id, err := strconv.Atoi(c.Param("entry_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `entry_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseUser(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseUser)
+		return
+	}
+
+	forum_id, err := forum.GetForumIdFromCourse(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get forum from course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	entry, err := forum.GetEntry(f.Database, forum_id, entry_id)
+	if err != nil {
+		log.Errorf("Unable to get entry with id %d: %s", entry_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	// course moderators may delete any entry, everybody else only their own ones
+	if entry.AuthorID != user_id && !AuthorizeCourseModerator(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseModerator)
+		return
+	}
+
+	err = forum.DeleteEntry(f.Database, forum_id, entry_id)
+	if err != nil {
+		log.Errorf("Unable to delete entry with id %d: %s", entry_id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
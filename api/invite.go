@@ -0,0 +1,198 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/course"
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+)
+
+func (f *PublicController) GetInvitesFromCourse(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	invites, err := course.GetInvites(f.Database, course_id)
+	if err != nil {
+		log.Errorf("Unable to get invites from course: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, invites)
+}
+
+func (f *PublicController) CreateInvite(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	var invite struct {
+		RoleID    int       `json:"role_id"`
+		MaxUses   null.Int  `json:"max_uses"`
+		ExpiresAt null.Time `json:"expires_at"`
+	}
+	if err := c.BindJSON(&invite); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+	// invites enroll regular users, unless told otherwise
+	if invite.RoleID == 0 {
+		invite.RoleID = dbi.CourseUserRoleId
+	}
+
+	created, err := course.CreateInvite(f.Database, course_id, user_id, invite.RoleID, invite.MaxUses, invite.ExpiresAt)
+	if err != nil {
+		log.Errorf("Unable to create invite: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, created)
+}
+
+func (f *PublicController) RevokeInvite(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	invite_id, err := strconv.Atoi(c.Param("invite_id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `invite_id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	err = course.RevokeInvite(f.Database, course_id, invite_id)
+	if err != nil {
+		log.Errorf("Unable to revoke invite: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (f *PublicController) RedeemInvite(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	course_id, err := course.RedeemInvite(f.Database, user_id, c.Param("token"))
+	if err != nil {
+		log.Errorf("Unable to redeem invite: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, course_id)
+}
+
+func (f *PublicController) EnrollUsersFromCSV(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	course_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	course_role, err := course.GetCourseRole(f.Database, user_id, course_id)
+	if err != nil {
+		log.Errorf("Unable to get course role: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	if !AuthorizeCourseAdmin(course_role, role_id) {
+		handleApiError(c, errs.ErrNotCourseAdmin)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("Unable to get file from request: %s", err.Error())
+		handleApiError(c, errs.ErrNoFileInRequest)
+		return
+	}
+
+	fileContent, err := file.Open()
+	if err != nil {
+		log.Errorf("Unable to open file: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+	defer fileContent.Close()
+
+	results, err := course.EnrollUsersFromCSV(f.Database, course_id, fileContent)
+	if err != nil {
+		log.Errorf("Unable to enroll users from csv: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, results)
+}
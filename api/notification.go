@@ -0,0 +1,146 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/notification"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+func (f *PublicController) GetNotificationsFromUser(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	user_id := c.MustGet("CookieUserId").(int)
+
+	notifications, err := notification.GetNotificationsFromUser(f.Database, user_id, false)
+	if err != nil {
+		log.Errorf("Unable to get notifications from user: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, notifications)
+}
+
+func (f *PublicController) GetUnreadNotificationsFromUser(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	user_id := c.MustGet("CookieUserId").(int)
+
+	notifications, err := notification.GetNotificationsFromUser(f.Database, user_id, true)
+	if err != nil {
+		log.Errorf("Unable to get unread notifications from user: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, notifications)
+}
+
+func (f *PublicController) MarkNotificationAsRead(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	user_id := c.MustGet("CookieUserId").(int)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	err = notification.MarkAsRead(f.Database, user_id, id)
+	if err != nil {
+		log.Errorf("Unable to mark notification with id %d as read: %s", id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) MarkAllNotificationsAsRead(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	user_id := c.MustGet("CookieUserId").(int)
+
+	err := notification.MarkAllAsRead(f.Database, user_id)
+	if err != nil {
+		log.Errorf("Unable to mark notifications as read: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (f *PublicController) DeleteNotification(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	user_id := c.MustGet("CookieUserId").(int)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	err = notification.DeleteNotification(f.Database, user_id, id)
+	if err != nil {
+		log.Errorf("Unable to delete notification with id %d: %s", id, err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (f *PublicController) DeleteAllNotifications(c *gin.Context) {
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	if !AuthorizeUser(role_id) {
+		handleApiError(c, errs.ErrNotUser)
+		return
+	}
+
+	user_id := c.MustGet("CookieUserId").(int)
+
+	err := notification.DeleteAllNotifications(f.Database, user_id)
+	if err != nil {
+		log.Errorf("Unable to delete notifications: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
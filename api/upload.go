@@ -0,0 +1,201 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"learningbay24.de/backend/course"
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+)
+
+type _upload struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Submission the file is uploaded for, so that its restrictions are checked before the upload starts
+	SubmissionID null.Int `json:"submission_id"`
+}
+
+// uploadedFile is a file sent with a request, either directly or as a finished chunked upload
+type uploadedFile struct {
+	io.ReadCloser
+	Name string
+	Size int
+	// deletes the chunked upload once the file was saved
+	saved func()
+}
+
+// Saved has to be called once the file was saved, so that a chunked upload isn't kept any longer
+func (u *uploadedFile) Saved() {
+	u.saved()
+}
+
+// formFile returns the file sent in the multipart form field `file`, or the finished chunked upload with the ID in the form field `upload_id`
+func (f *PublicController) formFile(c *gin.Context, user_id int) (*uploadedFile, error) {
+	if id := c.PostForm("upload_id"); id != "" {
+		upload_id, err := strconv.Atoi(id)
+		if err != nil {
+			log.Errorf("Unable to convert parameter `upload_id` to int: %s", err.Error())
+			return nil, errs.ErrParameterConversion
+		}
+
+		upload, content, err := dbi.OpenUpload(f.Database, user_id, upload_id)
+		if err != nil {
+			return nil, err
+		}
+		return &uploadedFile{ReadCloser: content, Name: upload.Name, Size: int(upload.Size), saved: func() {
+			if err := dbi.DeleteUpload(f.Database, user_id, upload_id); err != nil {
+				log.Errorf("Unable to delete saved upload: %s", err.Error())
+			}
+		}}, nil
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("No file found in request: %s", err.Error())
+		return nil, errs.ErrNoFileInRequest
+	}
+	content, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &uploadedFile{ReadCloser: content, Name: file.Filename, Size: int(file.Size), saved: func() {}}, nil
+}
+
+func setUploadHeaders(c *gin.Context, upload *dbi.Upload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Received, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Header("Cache-Control", "no-store")
+}
+
+// CreateUpload starts a chunked upload. Its chunks are sent with PatchUpload and the finished upload
+// can be used instead of a file in any request that takes one, by sending its ID in the form field `upload_id`.
+func (f *PublicController) CreateUpload(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+	role_id := c.MustGet("CookieRoleId").(int)
+
+	var u _upload
+	if err := c.BindJSON(&u); err != nil {
+		log.Errorf("Unable to bind json: %s", err.Error())
+		// NOTE: `BindJSON` sets the return status arleady
+		return
+	}
+
+	var policy dbi.FilePolicy
+	if u.SubmissionID.Valid {
+		submission, err := course.GetSubmission(f.Database, u.SubmissionID.Int)
+		if err != nil {
+			log.Errorf("Unable to get submission: %s", err.Error())
+			handleApiError(c, err)
+			return
+		}
+
+		course_role, err := course.GetCourseRole(f.Database, user_id, submission.CourseID)
+		if err != nil {
+			log.Errorf("Unable to get course role: %s", err.Error())
+			handleApiError(c, err)
+			return
+		}
+		if !AuthorizeCourseUser(course_role, role_id) {
+			handleApiError(c, errs.ErrNotCourseUser)
+			return
+		}
+
+		policy = course.SubmissionFilePolicy(submission)
+	}
+
+	upload, err := dbi.CreateUpload(f.Database, user_id, u.Name, u.Size, policy)
+	if err != nil {
+		log.Errorf("Unable to create upload: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.Header("Location", fmt.Sprintf("/uploads/%d", upload.ID))
+	c.IndentedJSON(http.StatusCreated, upload)
+}
+
+// GetUpload returns how many bytes of an upload were received, so that it can be resumed from there.
+// It answers HEAD requests too, which only get the headers.
+func (f *PublicController) GetUpload(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	upload, err := dbi.GetUpload(f.Database, user_id, id)
+	if err != nil {
+		log.Errorf("Unable to get upload: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.IndentedJSON(http.StatusOK, upload)
+}
+
+// PatchUpload receives the next chunk of an upload as the body of the request.
+// The header `Upload-Offset` has to contain the number of bytes received so far, the response contains it after the chunk.
+func (f *PublicController) PatchUpload(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		log.Errorf("Unable to convert header `Upload-Offset` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.Status(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	received, err := dbi.WriteUploadChunk(f.Database, user_id, id, offset, c.Request.Body)
+	if received > 0 || err == nil {
+		c.Header("Upload-Offset", strconv.FormatInt(received, 10))
+	}
+	if err != nil {
+		log.Errorf("Unable to write chunk of upload: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload cancels an upload
+func (f *PublicController) DeleteUpload(c *gin.Context) {
+	user_id := c.MustGet("CookieUserId").(int)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Errorf("Unable to convert parameter `id` to int: %s", err.Error())
+		handleApiError(c, errs.ErrParameterConversion)
+		return
+	}
+
+	if err := dbi.DeleteUpload(f.Database, user_id, id); err != nil {
+		log.Errorf("Unable to delete upload: %s", err.Error())
+		handleApiError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -4,10 +4,6 @@ package calender
 // FA470: Appointment erst ab einem gegebenen Datum anezeigen lassen -> submission.visibleFrom
 //	"Checkbox, ob der Kursteilnehmer die Abgabe erst ab einem bestimmten Zeitpunkt sieht"
 //	"Wenn vorherige Checkbox checked ist: Zeitfeld und Uhrzeitfeld für Sichtbarkeitsdatum für Kursteilnehmer"
-// FA330: Gelangen zu Abgabe über den Kalender:
-//	"Endnutzer sieht auf seinem Dashboard und über den Navigationspunkt „Stundenplan“ seinen Kalender"
-//	"Durch Drücken auf einer der Deadlines für seine Abgaben,
-//		öffnet sich für den Endnutzer ein Formular zu dieser Abgabe mit dazugehörigen Informationen (siehe andere F.A.)"
 //
 // json.Unmarshal() in api.go -> switch to BindJSON() (see AddCourseToCalender)
 
@@ -17,10 +13,13 @@ import (
 	"fmt"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"learningbay24.de/backend/course"
+	"learningbay24.de/backend/dbi"
 	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/notification"
 )
 
 type Calender interface {
@@ -71,37 +70,77 @@ func (p *PublicController) GetAllAppointments(userId int) ([]AppointmentWithCour
 }
 
 // adds appointment/s to the course; appointments may repeat
-func (p *PublicController) AddCourseToCalender(date time.Time, duration int, location null.String, online int8, courseId int) (int, error) {
-	tx, err := p.Database.BeginTx(context.Background(), nil)
+// A repeating appointment is expanded into a series that ends at `until` or after `count` occurrences, whichever comes first.
+// Archived courses can't get new appointments.
+// Returns the ID of the first appointment.
+func (p *PublicController) AddCourseToCalender(date time.Time, duration int, location null.String, online int8, courseId int, repeat RepeatDistance, until null.Time, count int) (int, error) {
+	dates, err := occurrences(date, repeat, until, count)
 	if err != nil {
 		return 0, err
 	}
 
-	newAppoint := &models.Appointment{Date: date, Location: location, Online: online, CourseID: courseId, Duration: duration}
-
-	err = newAppoint.Insert(context.Background(), tx, boil.Infer())
+	tx, err := p.Database.BeginTx(context.Background(), nil)
 	if err != nil {
-		if e := tx.Rollback(); e != nil {
-			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
-		}
 		return 0, err
 	}
-	course, err := models.FindCourse(context.Background(), tx, courseId)
+
+	err = dbi.CheckCourseWritable(tx, courseId)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
 			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
 		return 0, err
 	}
-	course.AddAppointments(context.Background(), tx, true, newAppoint)
+
+	var seriesId null.Int
+	if repeat != None {
+		res, err := tx.Exec("INSERT INTO appointment_series (course_id, repeat_distance) VALUES (?, ?)", courseId, int(repeat))
+		if err == nil {
+			var id int64
+			id, err = res.LastInsertId()
+			seriesId = null.IntFrom(int(id))
+		}
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+			return 0, err
+		}
+	}
+
+	var firstId int
+	for i, d := range dates {
+		newAppoint := &models.Appointment{Date: d, Location: location, Online: online, CourseID: courseId, Duration: duration, SeriesID: seriesId}
+
+		err = newAppoint.Insert(context.Background(), tx, boil.Infer())
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+			return 0, err
+		}
+
+		if i == 0 {
+			firstId = newAppoint.ID
+		}
+	}
 
 	if e := tx.Commit(); e != nil {
 		return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 	}
-	return newAppoint.ID, nil
+
+	// a series is announced by its first appointment only;
+	// failing to notify the course's users shouldn't undo the creation
+	if err := notification.NotifyAppointmentCreated(p.Database, firstId); err != nil {
+		log.Errorf("Unable to notify users about new appointment: %s", err.Error())
+	}
+
+	return firstId, nil
 }
 
-func (p *PublicController) DeactivateCourseInCalender(appointmentId int) error {
+// DeactivateCourseInCalender takes the ID of an appointment and cancels it.
+// If wholeSeries is set, all appointments of the series the appointment is part of are cancelled.
+func (p *PublicController) DeactivateCourseInCalender(appointmentId int, wholeSeries bool) error {
 	tx, err := p.Database.BeginTx(context.Background(), nil)
 	if err != nil {
 		return err
@@ -114,7 +153,18 @@ func (p *PublicController) DeactivateCourseInCalender(appointmentId int) error {
 		}
 		return err
 	}
-	appointment.Delete(context.Background(), p.Database, false)
+
+	if wholeSeries && appointment.SeriesID.Valid {
+		err = deleteSeries(tx, appointment.SeriesID.Int)
+	} else {
+		_, err = appointment.Delete(context.Background(), tx, false)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
 
 	if e := tx.Commit(); e != nil {
 		return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
@@ -6,6 +6,8 @@ import (
 	"testing"
 	"time"
 
+	"learningbay24.de/backend/errs"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/volatiletech/null/v8"
@@ -23,7 +25,8 @@ func (a AnyTime) Match(v driver.Value) bool {
 
 // Match satisfies sqlmock.Argument interface
 func (a AnyString) Match(v driver.Value) bool {
-	_, ok := v.(null.String)
+	// `null.String` is converted to its underlying value before it reaches the driver
+	_, ok := v.(string)
 	return ok
 }
 
@@ -41,23 +44,72 @@ func TestAddCourseToCalender(t *testing.T) {
 	boil.SetDB(db)
 
 	ctrl := &PublicController{db}
+	date := time.Date(2022, 10, 17, 10, 0, 0, 0, time.UTC)
 
-	// start
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `appointment` (`location`,`online`,`course_id`,`created_at`,`updated_at`,`deleted_at`) VALUES (?,?,?,?,?,?)")).WithArgs(1, AnyTime{}, AnyString{}, 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `course` WHERE `id`=? and `deleted_at` is null")).WithArgs(1).WillReturnRows().RowsWillBeClosed()
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `appointment` (`location`,`online`,`course_id`,`created_at`,`updated_at`,`deleted_at`) VALUES (?,?,?,?,?,?)")).WithArgs(1, AnyTime{}, AnyString{}, 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("select * from `course` where `id`=? and `deleted_at` is null")).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO appointment_series (course_id, repeat_distance) VALUES (?, ?)")).WithArgs(1, int(Week)).WillReturnResult(sqlmock.NewResult(7, 1))
+	for i := 0; i < 3; i++ {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `appointment`")).WithArgs(AnyTime{}, AnyString{}, 1, 1, AnyTime{}, sqlmock.AnyArg(), sqlmock.AnyArg(), 3600, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(int64(10+i), 1))
+	}
 	mock.ExpectCommit()
 
-	/*
-		ID        string
-		Date      string
-		Location  string
-		Online    string
-		CourseID  string
-	*/
+	id, err := ctrl.AddCourseToCalender(date, 3600, null.String{String: "Home", Valid: true}, 1, 1, Week, null.Time{}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, id)
+}
+
+func TestOccurrences(t *testing.T) {
+	start := time.Date(2022, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	dates, err := occurrences(start, None, null.Time{}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{start}, dates)
+
+	dates, err = occurrences(start, Week, null.TimeFrom(start.AddDate(0, 0, 14)), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{start, start.AddDate(0, 0, 7), start.AddDate(0, 0, 14)}, dates)
 
-	id, err := ctrl.AddCourseToCalender(time.Time{}, 3600, null.String{String: "Home", Valid: true}, 1, 1)
+	// February, April and June don't have a 31st
+	dates, err = occurrences(start, Month, null.Time{}, 4)
 	assert.NoError(t, err)
-	assert.NotEqual(t, id, 0)
+	assert.Equal(t, []time.Time{
+		start,
+		time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC),
+		time.Date(2022, 5, 31, 10, 0, 0, 0, time.UTC),
+		time.Date(2022, 7, 31, 10, 0, 0, 0, time.UTC),
+	}, dates)
+
+	// whichever end comes first wins
+	dates, err = occurrences(start, Year, null.TimeFrom(start.AddDate(1, 0, 0)), 5)
+	assert.NoError(t, err)
+	assert.Len(t, dates, 2)
+
+	_, err = occurrences(start, Week, null.Time{}, 0)
+	assert.ErrorIs(t, err, errs.ErrSeriesEndMissing)
+
+	_, err = occurrences(start, Week, null.TimeFrom(start.AddDate(0, 0, -1)), 0)
+	assert.ErrorIs(t, err, errs.ErrSeriesEndBeforeStart)
+
+	_, err = occurrences(start, Week, null.TimeFrom(start.AddDate(20, 0, 0)), 0)
+	assert.ErrorIs(t, err, errs.ErrTooManyOccurrences)
+
+	_, err = occurrences(start, RepeatDistance(42), null.Time{}, 3)
+	assert.ErrorIs(t, err, errs.ErrInvalidRepeatDistance)
+}
+
+func TestEditAppointmentValidation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected", err)
+	}
+	defer db.Close()
+
+	p := &PublicController{Database: db}
+	start := time.Date(2022, 4, 4, 10, 0, 0, 0, time.UTC)
+
+	assert.ErrorIs(t, p.EditAppointment(1, time.Time{}, 5400, null.String{}, 0, true), errs.ErrInvalidAppointment)
+	assert.ErrorIs(t, p.EditAppointment(1, start, 0, null.String{}, 0, false), errs.ErrInvalidAppointment)
+	// nothing is changed before the values are checked
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,121 @@
+package calender
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"learningbay24.de/backend/models"
+)
+
+// newFeedToken generates a random token that is hard enough to guess to protect a calendar feed
+func newFeedToken() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b[:]), nil
+}
+
+// GetFeedToken takes the ID of a user and returns the token of their calendar feed, creating one if they don't have it yet
+func (p *PublicController) GetFeedToken(userId int) (string, error) {
+	var token string
+	err := p.Database.QueryRow("SELECT token FROM calendar_feed WHERE user_id = ?", userId).Scan(&token)
+	if err == nil {
+		return token, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	return p.RegenerateFeedToken(userId)
+}
+
+// RegenerateFeedToken takes the ID of a user and replaces the token of their calendar feed, so that the old feed URL stops working
+func (p *PublicController) RegenerateFeedToken(userId int) (string, error) {
+	token, err := newFeedToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = p.Database.Exec(`INSERT INTO calendar_feed (user_id, token) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE token = VALUES(token), created_at = current_timestamp()`, userId, token)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// GetUserIdFromFeedToken takes the token of a calendar feed and returns the ID of the user it belongs to
+func (p *PublicController) GetUserIdFromFeedToken(token string) (int, error) {
+	var userId int
+	err := p.Database.QueryRow("SELECT user_id FROM calendar_feed WHERE token = ?", token).Scan(&userId)
+	if err != nil {
+		return 0, err
+	}
+
+	// deleted users don't have a calendar anymore
+	if _, err := models.FindUser(context.Background(), p.Database, userId); err != nil {
+		return 0, err
+	}
+
+	return userId, nil
+}
+
+// ExportCalendar takes the ID of a user and writes their schedule as an iCalendar file to w.
+// It contains the appointments, exams and exam registration deadlines of their courses and the deadlines of visible submissions.
+func (p *PublicController) ExportCalendar(userId int, w io.Writer) error {
+	events, err := p.getCalendarEvents(userId, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return writeICal(w, "LearningBay24", events, time.Now())
+}
+
+// getCalendarEvents turns the whole timeline of the user into events of a calendar
+func (p *PublicController) getCalendarEvents(userId int, now time.Time) ([]icalEvent, error) {
+	entries, err := p.getTimeline(userId, now, timeRange{})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]icalEvent, 0, len(entries))
+	for _, t := range entries {
+		e := icalEvent{
+			UID:         fmt.Sprintf("%s-%d@%s", t.Type, t.ID, icalDomain),
+			Description: t.CourseName,
+			Location:    t.Location.String,
+			Start:       t.Start,
+		}
+		if t.End.Valid {
+			e.End = t.End.Time
+		}
+		if t.Online {
+			e.Description += "\nOnline"
+		}
+
+		switch t.Type {
+		case TimelineAppointment:
+			e.Summary = t.CourseName
+		case TimelineExam:
+			e.Summary = fmt.Sprintf("Exam: %s", t.Title)
+		case TimelineExamRegisterDeadline:
+			e.Summary = fmt.Sprintf("Registration deadline: %s", t.Title)
+		case TimelineExamDeregisterDeadline:
+			e.Summary = fmt.Sprintf("Deregistration deadline: %s", t.Title)
+		case TimelineSubmissionDeadline:
+			e.Summary = fmt.Sprintf("Submission deadline: %s", t.Title)
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,97 @@
+package calender
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Domain used to make the UIDs of exported events globally unique
+const icalDomain = "learningbay24.de"
+
+// Format of UTC date-times as defined in RFC 5545, section 3.3.5
+const icalTimeFormat = "20060102T150405Z"
+
+// Maximum length of a content line in octets, excluding the line break
+const icalLineLength = 75
+
+// icalEvent is a single VEVENT of an iCalendar file.
+// If End is zero, the event has no duration, e.g. for deadlines.
+type icalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+}
+
+// writeICal writes the given events as an iCalendar (RFC 5545) file with the given name to w
+func writeICal(w io.Writer, name string, events []icalEvent, now time.Time) error {
+	bw := bufio.NewWriter(w)
+
+	writeICalLine(bw, "BEGIN:VCALENDAR")
+	writeICalLine(bw, "VERSION:2.0")
+	writeICalLine(bw, "PRODID:-//LearningBay24//Calendar//EN")
+	writeICalLine(bw, "CALSCALE:GREGORIAN")
+	writeICalLine(bw, "METHOD:PUBLISH")
+	writeICalLine(bw, "X-WR-CALNAME:"+escapeICalText(name))
+
+	stamp := now.UTC().Format(icalTimeFormat)
+	for _, e := range events {
+		writeICalLine(bw, "BEGIN:VEVENT")
+		writeICalLine(bw, "UID:"+e.UID)
+		writeICalLine(bw, "DTSTAMP:"+stamp)
+		writeICalLine(bw, "DTSTART:"+e.Start.UTC().Format(icalTimeFormat))
+		if !e.End.IsZero() {
+			writeICalLine(bw, "DTEND:"+e.End.UTC().Format(icalTimeFormat))
+		}
+		writeICalLine(bw, "SUMMARY:"+escapeICalText(e.Summary))
+		if e.Location != "" {
+			writeICalLine(bw, "LOCATION:"+escapeICalText(e.Location))
+		}
+		if e.Description != "" {
+			writeICalLine(bw, "DESCRIPTION:"+escapeICalText(e.Description))
+		}
+		writeICalLine(bw, "END:VEVENT")
+	}
+
+	writeICalLine(bw, "END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// writeICalLine writes a content line, folding it into several lines if it is too long.
+// Lines are only split between characters, so that multi-byte characters stay intact.
+func writeICalLine(w *bufio.Writer, line string) {
+	limit := icalLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts towards its length
+		limit = icalLineLength - 1
+	}
+
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+// escapeICalText escapes the characters that have a special meaning in TEXT values
+func escapeICalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
@@ -0,0 +1,137 @@
+package calender
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"learningbay24.de/backend/errs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteICal(t *testing.T) {
+	start := time.Date(2022, 7, 4, 10, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	events := []icalEvent{
+		{UID: "appointment-1@learningbay24.de", Summary: "Math; Analysis, Part 1", Location: "Room 1", Start: start, End: start.Add(90 * time.Minute)},
+		{UID: "submission-2@learningbay24.de", Summary: "Deadline", Description: "first\nsecond", Start: start},
+	}
+
+	var buf bytes.Buffer
+	err := writeICal(&buf, "Test", events, start)
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Contains(t, out, "DTSTART:20220704T080000Z\r\nDTEND:20220704T093000Z\r\n")
+	assert.Contains(t, out, `SUMMARY:Math\; Analysis\, Part 1`)
+	assert.Contains(t, out, `DESCRIPTION:first\nsecond`)
+	// deadlines don't have an end
+	assert.Equal(t, 1, strings.Count(out, "DTEND"))
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VEVENT"))
+}
+
+func TestWriteICalLineFolding(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeICal(&buf, strings.Repeat("ä", 100), nil, time.Now())
+	assert.NoError(t, err)
+
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	assert.Contains(t, unfolded, "X-WR-CALNAME:"+strings.Repeat("ä", 100)+"\r\n")
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), icalLineLength)
+		assert.True(t, strings.ToValidUTF8(line, "") == line)
+	}
+}
+
+func TestParseICal(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data is not available")
+	}
+
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:lecture",
+		"SUMMARY:Analysis\\, Part 1",
+		"LOCATION:Room 1",
+		"DTSTART;TZID=Europe/Berlin:20221017T100000",
+		"DTEND;TZID=Europe/Berlin:20221017T113000",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,TH;COUNT=5",
+		"EXDATE;TZID=Europe/Berlin:20221020T100000",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"DESCRIPTION:Reminder",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:lecture",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20221024T100000",
+		"DTSTART;TZID=Europe/Berlin:20221024T140000",
+		"DURATION:PT1H30M",
+		"LOCATION:Room",
+		"  2",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:cancelled",
+		"STATUS:CANCELLED",
+		"DTSTART:20221101T090000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := parseICal(strings.NewReader(ics))
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	lecture := events[0]
+	assert.Equal(t, "Analysis, Part 1", lecture.Summary)
+	assert.Equal(t, 90*time.Minute, lecture.Duration)
+	assert.Len(t, lecture.ExDates, 2)
+
+	dates, err := expandRRule(lecture.Start, lecture.RRule, lecture.ExDates)
+	assert.NoError(t, err)
+	// the 20th is excluded and the 24th is moved by the second event
+	assert.Equal(t, []time.Time{
+		time.Date(2022, 10, 17, 10, 0, 0, 0, berlin),
+		time.Date(2022, 10, 27, 10, 0, 0, 0, berlin),
+		time.Date(2022, 10, 31, 10, 0, 0, 0, berlin),
+	}, dates)
+
+	moved := events[1]
+	assert.Equal(t, "Room 2", moved.Location)
+	assert.Equal(t, time.Date(2022, 10, 24, 14, 0, 0, 0, berlin), moved.Start)
+	assert.Equal(t, 90*time.Minute, moved.Duration)
+
+	_, err = parseICal(strings.NewReader("BEGIN:VEVENT\r\nEND:VEVENT"))
+	assert.ErrorIs(t, err, errs.ErrInvalidICal)
+}
+
+func TestExpandRRule(t *testing.T) {
+	start := time.Date(2022, 1, 31, 8, 0, 0, 0, time.UTC)
+
+	dates, err := expandRRule(start, "FREQ=MONTHLY;UNTIL=20220531", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{start, time.Date(2022, 3, 31, 8, 0, 0, 0, time.UTC), time.Date(2022, 5, 31, 8, 0, 0, 0, time.UTC)}, dates)
+
+	dates, err = expandRRule(start, "FREQ=DAILY;INTERVAL=2;COUNT=3", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{start, start.AddDate(0, 0, 2), start.AddDate(0, 0, 4)}, dates)
+
+	_, err = expandRRule(start, "FREQ=MONTHLY;BYDAY=MO", nil)
+	assert.ErrorIs(t, err, errs.ErrUnsupportedRecurrence)
+	_, err = expandRRule(start, "FREQ=HOURLY;COUNT=2", nil)
+	assert.ErrorIs(t, err, errs.ErrUnsupportedRecurrence)
+	// rules without an end are expanded for about a semester
+	dates, err = expandRRule(start, "FREQ=WEEKLY", nil)
+	assert.NoError(t, err)
+	assert.Len(t, dates, 27)
+	assert.Equal(t, start.AddDate(0, 0, 7*26), dates[len(dates)-1])
+	_, err = expandRRule(start, "FREQ=DAILY;COUNT=1000", nil)
+	assert.ErrorIs(t, err, errs.ErrTooManyOccurrences)
+}
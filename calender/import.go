@@ -0,0 +1,648 @@
+package calender
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// Format of dates without a time as defined in RFC 5545, section 3.3.4
+const icalDateFormat = "20060102"
+
+// How far rules without COUNT or UNTIL are expanded, about a semester, as courses are held for one
+const openRRuleHorizon = 183 * 24 * time.Hour
+
+// ImportedAppointment is an appointment read from an iCalendar file
+type ImportedAppointment struct {
+	Summary  string      `json:"summary"`
+	Date     time.Time   `json:"date"`
+	Duration int         `json:"duration"`
+	Location null.String `json:"location"`
+}
+
+// ImportResult lists the appointments an import created, or would create on a dry run,
+// and the ones that were skipped since the course already has an appointment at the same date and location
+type ImportResult struct {
+	DryRun     bool                   `json:"dry_run"`
+	Created    []*ImportedAppointment `json:"created"`
+	Duplicates []*ImportedAppointment `json:"duplicates"`
+}
+
+// icalProperty is a single unfolded content line, e.g. `DTSTART;TZID=Europe/Berlin:20220704T100000`
+type icalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// importedEvent is a VEVENT of an imported iCalendar file, before its recurrences are expanded
+type importedEvent struct {
+	UID          string
+	Summary      string
+	Location     string
+	Start        time.Time
+	Duration     time.Duration
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time
+}
+
+// ImportAppointments takes the ID of a course and an iCalendar file and adds its events as appointments to the course.
+// Repeating events are expanded according to their RRULE, events at the same date and location as an existing appointment are skipped.
+// If dryRun is set, nothing is saved and the result only shows what the import would do.
+// Archived courses can't be imported into.
+func (p *PublicController) ImportAppointments(courseId int, r io.Reader, dryRun bool) (*ImportResult, error) {
+	if err := dbi.CheckCourseWritable(p.Database, courseId); err != nil {
+		return nil, err
+	}
+
+	events, err := parseICal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := models.Appointments(models.AppointmentWhere.CourseID.EQ(courseId)).All(context.Background(), p.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, a := range existing {
+		seen[appointmentKey(a.Date, a.Location)] = true
+	}
+
+	result := &ImportResult{DryRun: dryRun, Created: []*ImportedAppointment{}, Duplicates: []*ImportedAppointment{}}
+	// appointments that belong to the same recurring event are linked to a series
+	var groups [][]*ImportedAppointment
+	var distances []RepeatDistance
+	for _, e := range events {
+		dates, err := expandRRule(e.Start, e.RRule, e.ExDates)
+		if err != nil {
+			return nil, fmt.Errorf("event %q: %w", e.UID, err)
+		}
+
+		var group []*ImportedAppointment
+		for _, d := range dates {
+			a := &ImportedAppointment{Summary: e.Summary, Date: d, Duration: int(e.Duration.Seconds())}
+			if e.Location != "" {
+				a.Location = null.StringFrom(e.Location)
+			}
+
+			key := appointmentKey(a.Date, a.Location)
+			if seen[key] {
+				result.Duplicates = append(result.Duplicates, a)
+				continue
+			}
+			seen[key] = true
+
+			result.Created = append(result.Created, a)
+			group = append(group, a)
+		}
+
+		if len(group) > 0 {
+			groups = append(groups, group)
+			distances = append(distances, seriesDistance(e.RRule))
+		}
+	}
+
+	if dryRun || len(result.Created) == 0 {
+		return result, nil
+	}
+
+	tx, err := p.Database.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, group := range groups {
+		var seriesId null.Int
+		if distances[i] != None && len(group) > 1 {
+			res, err := tx.Exec("INSERT INTO appointment_series (course_id, repeat_distance) VALUES (?, ?)", courseId, int(distances[i]))
+			if err == nil {
+				var id int64
+				id, err = res.LastInsertId()
+				seriesId = null.IntFrom(int(id))
+			}
+			if err != nil {
+				if e := tx.Rollback(); e != nil {
+					return nil, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+				}
+				return nil, err
+			}
+		}
+
+		for _, a := range group {
+			appointment := &models.Appointment{Date: a.Date, Duration: a.Duration, Location: a.Location, CourseID: courseId, SeriesID: seriesId}
+			err = appointment.Insert(context.Background(), tx, boil.Infer())
+			if err != nil {
+				if e := tx.Rollback(); e != nil {
+					return nil, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+				}
+				return nil, err
+			}
+		}
+	}
+
+	if e := tx.Commit(); e != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", e)
+	}
+
+	log.Infof("Imported %d appointments into course %d", len(result.Created), courseId)
+	return result, nil
+}
+
+// appointmentKey identifies an appointment by its date and location, to find duplicates
+func appointmentKey(date time.Time, location null.String) string {
+	return fmt.Sprintf("%d|%s", date.Unix(), strings.TrimSpace(location.String))
+}
+
+// seriesDistance returns how far apart the appointments created from a recurrence rule are,
+// or None if the rule doesn't match a distance a series can have
+func seriesDistance(rrule string) RepeatDistance {
+	rule, err := parseRRule(rrule)
+	if err != nil || rule.Interval != 1 || len(rule.ByDay) > 1 {
+		return None
+	}
+
+	switch rule.Freq {
+	case "WEEKLY":
+		return Week
+	case "MONTHLY":
+		return Month
+	case "YEARLY":
+		return Year
+	}
+	return None
+}
+
+// parseICal reads the events of an iCalendar file.
+// Cancelled events are left out and events that override a single occurrence of a recurring event replace that occurrence.
+func parseICal(r io.Reader) ([]*importedEvent, error) {
+	props, err := unfoldICal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*importedEvent
+	var current *importedEvent
+	var end time.Time
+	var cancelled, allDay bool
+	// nested components like VALARM may repeat properties of the event
+	depth := 0
+	for _, prop := range props {
+		switch {
+		case prop.Name == "BEGIN" && prop.Value == "VEVENT":
+			current = &importedEvent{}
+			end = time.Time{}
+			cancelled = false
+			allDay = false
+			depth = 0
+			continue
+		case current == nil:
+			continue
+		case prop.Name == "BEGIN":
+			depth++
+			continue
+		case prop.Name == "END" && prop.Value != "VEVENT":
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch prop.Name {
+		case "UID":
+			current.UID = prop.Value
+		case "SUMMARY":
+			current.Summary = unescapeICalText(prop.Value)
+		case "LOCATION":
+			current.Location = unescapeICalText(prop.Value)
+		case "STATUS":
+			cancelled = strings.EqualFold(prop.Value, "CANCELLED")
+		case "RRULE":
+			current.RRule = prop.Value
+		case "DTSTART":
+			current.Start, err = parseICalTime(prop)
+			allDay = prop.Params["VALUE"] == "DATE" || len(strings.TrimSpace(prop.Value)) == len(icalDateFormat)
+		case "DTEND":
+			end, err = parseICalTime(prop)
+		case "DURATION":
+			current.Duration, err = parseICalDuration(prop.Value)
+		case "RECURRENCE-ID":
+			current.RecurrenceID, err = parseICalTime(prop)
+		case "EXDATE":
+			for _, v := range strings.Split(prop.Value, ",") {
+				var t time.Time
+				t, err = parseICalTime(icalProperty{Name: prop.Name, Params: prop.Params, Value: v})
+				if err != nil {
+					break
+				}
+				current.ExDates = append(current.ExDates, t)
+			}
+		case "END":
+			if cancelled {
+				// a cancelled occurrence still has to be removed from its recurring event
+				if !current.RecurrenceID.IsZero() {
+					events = append(events, &importedEvent{UID: current.UID, RecurrenceID: current.RecurrenceID})
+				}
+				current = nil
+				continue
+			}
+			if current.Start.IsZero() {
+				return nil, fmt.Errorf("%w: event %q has no start", errs.ErrInvalidICal, current.UID)
+			}
+			if !end.IsZero() {
+				current.Duration = end.Sub(current.Start)
+			} else if current.Duration == 0 && allDay {
+				// events that only have a date last the whole day
+				current.Duration = 24 * time.Hour
+			}
+			if current.Duration < 0 {
+				return nil, fmt.Errorf("%w: event %q ends before it starts", errs.ErrInvalidICal, current.UID)
+			}
+			events = append(events, current)
+			current = nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errs.ErrInvalidICal, err.Error())
+		}
+	}
+
+	return applyOverrides(events), nil
+}
+
+// applyOverrides excludes occurrences that are changed by a separate event with a RECURRENCE-ID from their recurring event
+func applyOverrides(events []*importedEvent) []*importedEvent {
+	recurring := make(map[string]*importedEvent)
+	for _, e := range events {
+		if e.RRule != "" && e.RecurrenceID.IsZero() {
+			recurring[e.UID] = e
+		}
+	}
+
+	result := make([]*importedEvent, 0, len(events))
+	for _, e := range events {
+		if !e.RecurrenceID.IsZero() {
+			if r, ok := recurring[e.UID]; ok {
+				r.ExDates = append(r.ExDates, e.RecurrenceID)
+			}
+			// only the RECURRENCE-ID is known of cancelled occurrences
+			if e.Start.IsZero() {
+				continue
+			}
+		}
+		result = append(result, e)
+	}
+
+	return result
+}
+
+// unfoldICal splits an iCalendar file into its properties, joining folded lines
+func unfoldICal(r io.Reader) ([]icalProperty, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrInvalidICal, err.Error())
+	}
+
+	if len(lines) == 0 || lines[0] != "BEGIN:VCALENDAR" {
+		return nil, fmt.Errorf("%w: missing BEGIN:VCALENDAR", errs.ErrInvalidICal)
+	}
+
+	props := make([]icalProperty, 0, len(lines))
+	for _, line := range lines {
+		prop, err := parseICalLine(line)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, prop)
+	}
+
+	return props, nil
+}
+
+// parseICalLine splits a content line into its name, parameters and value
+func parseICalLine(line string) (icalProperty, error) {
+	prop := icalProperty{Params: map[string]string{}}
+
+	// the value starts at the first colon that isn't part of a quoted parameter value
+	quoted := false
+	colon := -1
+	for i := 0; i < len(line) && colon < 0; i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return prop, fmt.Errorf("%w: invalid line %q", errs.ErrInvalidICal, line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop.Name = strings.ToUpper(parts[0])
+	prop.Value = line[colon+1:]
+	for _, param := range parts[1:] {
+		k, v, _ := strings.Cut(param, "=")
+		prop.Params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+
+	return prop, nil
+}
+
+// parseICalTime parses the value of a DATE or DATE-TIME property.
+// Times without a zone are read in the zone of their TZID parameter, or in the local zone if it is missing or unknown.
+func parseICalTime(prop icalProperty) (time.Time, error) {
+	loc := time.Local
+	if tzid, ok := prop.Params["TZID"]; ok {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		} else {
+			log.Warnf("Unknown time zone %q, using local time instead", tzid)
+		}
+	}
+
+	v := strings.TrimSpace(prop.Value)
+	switch {
+	case prop.Params["VALUE"] == "DATE" || len(v) == len(icalDateFormat):
+		return time.ParseInLocation(icalDateFormat, v, loc)
+	case strings.HasSuffix(v, "Z"):
+		return time.Parse(icalTimeFormat, v)
+	default:
+		return time.ParseInLocation(strings.TrimSuffix(icalTimeFormat, "Z"), v, loc)
+	}
+}
+
+// parseICalDuration parses a duration as defined in RFC 5545, section 3.3.6, e.g. `PT1H30M` or `P1W`
+func parseICalDuration(v string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(v, "+"), "P")
+	if s == v || s == "" {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+		num = ""
+
+		switch {
+		case c == 'W' && !inTime:
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			d += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+
+	return d, nil
+}
+
+// unescapeICalText reverts `escapeICalText`
+func unescapeICalText(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, c := range s {
+		if escaped {
+			if c == 'n' || c == 'N' {
+				b.WriteRune('\n')
+			} else {
+				b.WriteRune(c)
+			}
+			escaped = false
+			continue
+		}
+		if c == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// rrule is the part of a recurrence rule (RFC 5545, section 3.3.10) that can be imported
+type rrule struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRRule parses the value of a RRULE property.
+// Only the parts timetables commonly use are supported; BYDAY is only supported for weekly rules.
+func parseRRule(v string) (*rrule, error) {
+	rule := &rrule{Interval: 1}
+	for _, part := range strings.Split(v, ";") {
+		k, val, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: invalid INTERVAL %q", errs.ErrUnsupportedRecurrence, val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: invalid COUNT %q", errs.ErrUnsupportedRecurrence, val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			t, err := parseICalTime(icalProperty{Value: val})
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid UNTIL %q", errs.ErrUnsupportedRecurrence, val)
+			}
+			// an UNTIL date includes the whole day
+			if len(val) == len(icalDateFormat) {
+				t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			rule.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				wd, ok := icalWeekdays[strings.ToUpper(d)]
+				if !ok {
+					return nil, fmt.Errorf("%w: BYDAY %q", errs.ErrUnsupportedRecurrence, d)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "WKST":
+			// only matters for weekly rules with an interval and several days, which are computed from monday on
+		default:
+			return nil, fmt.Errorf("%w: %s", errs.ErrUnsupportedRecurrence, k)
+		}
+	}
+
+	switch rule.Freq {
+	case "DAILY", "MONTHLY", "YEARLY":
+		if len(rule.ByDay) > 0 {
+			return nil, fmt.Errorf("%w: BYDAY in %s rule", errs.ErrUnsupportedRecurrence, rule.Freq)
+		}
+	case "WEEKLY":
+	default:
+		return nil, fmt.Errorf("%w: FREQ %q", errs.ErrUnsupportedRecurrence, rule.Freq)
+	}
+
+	return rule, nil
+}
+
+// expandRRule returns the dates of all occurrences of an event starting at the given date,
+// leaving out the excluded dates. An empty rule means the event only happens once.
+func expandRRule(start time.Time, v string, exDates []time.Time) ([]time.Time, error) {
+	var dates []time.Time
+	if v == "" {
+		dates = []time.Time{start}
+	} else {
+		rule, err := parseRRule(v)
+		if err != nil {
+			return nil, err
+		}
+		dates, err = rule.occurrences(start)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]time.Time, 0, len(dates))
+	for _, d := range dates {
+		excluded := false
+		for _, ex := range exDates {
+			if d.Equal(ex) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			result = append(result, d)
+		}
+	}
+
+	return result, nil
+}
+
+// occurrences expands the rule into its dates, beginning with start.
+// Like `occurrences` of series, days that don't exist in a month are skipped. Rules without an end are expanded up to openRRuleHorizon after start.
+func (r *rrule) occurrences(start time.Time) ([]time.Time, error) {
+	until := r.Until
+	if r.Count == 0 && until.IsZero() {
+		until = start.Add(openRRuleHorizon)
+	}
+
+	days := r.ByDay
+	if r.Freq == "WEEKLY" && len(days) > 0 {
+		// sort the days from monday on, so that the dates of a week are ascending
+		sort.Slice(days, func(i, j int) bool { return (days[i]+6)%7 < (days[j]+6)%7 })
+	}
+	// monday of the week of the first occurrence
+	weekStart := start.AddDate(0, 0, -int((start.Weekday()+6)%7))
+
+	dates := []time.Time{start}
+	for i := 0; ; i++ {
+		var candidates []time.Time
+		switch r.Freq {
+		case "DAILY":
+			candidates = []time.Time{start.AddDate(0, 0, i*r.Interval)}
+		case "WEEKLY":
+			if len(days) == 0 {
+				candidates = []time.Time{start.AddDate(0, 0, 7*i*r.Interval)}
+				break
+			}
+			week := weekStart.AddDate(0, 0, 7*i*r.Interval)
+			for _, wd := range days {
+				candidates = append(candidates, week.AddDate(0, 0, int((wd+6)%7)))
+			}
+		case "MONTHLY":
+			d := start.AddDate(0, i*r.Interval, 0)
+			if d.Day() == start.Day() {
+				candidates = []time.Time{d}
+			}
+		case "YEARLY":
+			d := start.AddDate(i*r.Interval, 0, 0)
+			if d.Day() == start.Day() {
+				candidates = []time.Time{d}
+			}
+		}
+
+		for _, d := range candidates {
+			if !d.After(start) {
+				continue
+			}
+			if !until.IsZero() && d.After(until) {
+				return dates, nil
+			}
+			if r.Count > 0 && len(dates) >= r.Count {
+				return dates, nil
+			}
+
+			dates = append(dates, d)
+			if len(dates) > maxOccurrences {
+				return nil, errs.ErrTooManyOccurrences
+			}
+		}
+
+		// rules that only skip days still have to end at some point
+		if i > maxOccurrences*12 {
+			return dates, nil
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package calender
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// Maximum amount of appointments a single series may consist of
+const maxOccurrences = 500
+
+// occurrences expands a repeating appointment starting at the given date into the dates of all of its appointments.
+// Monthly and yearly appointments skip the months that don't have the day of the first appointment, e.g. February 30th.
+func occurrences(start time.Time, repeat RepeatDistance, until null.Time, count int) ([]time.Time, error) {
+	if repeat == None {
+		return []time.Time{start}, nil
+	}
+	if repeat != Week && repeat != Month && repeat != Year {
+		return nil, errs.ErrInvalidRepeatDistance
+	}
+	if !until.Valid && count <= 0 {
+		return nil, errs.ErrSeriesEndMissing
+	}
+	if until.Valid && until.Time.Before(start) {
+		return nil, errs.ErrSeriesEndBeforeStart
+	}
+	if count > maxOccurrences {
+		return nil, errs.ErrTooManyOccurrences
+	}
+
+	var dates []time.Time
+	for i := 0; count <= 0 || len(dates) < count; i++ {
+		var d time.Time
+		switch repeat {
+		case Week:
+			d = start.AddDate(0, 0, 7*i)
+		case Month:
+			d = start.AddDate(0, i, 0)
+		case Year:
+			d = start.AddDate(i, 0, 0)
+		}
+
+		if until.Valid && d.After(until.Time) {
+			break
+		}
+		// `AddDate` normalizes days that don't exist in the target month into the following one
+		if repeat != Week && d.Day() != start.Day() {
+			continue
+		}
+
+		dates = append(dates, d)
+		if len(dates) > maxOccurrences {
+			return nil, errs.ErrTooManyOccurrences
+		}
+	}
+
+	return dates, nil
+}
+
+// deleteSeries soft-deletes the series with the given ID together with all of its appointments
+func deleteSeries(exec boil.ContextExecutor, seriesId int) error {
+	_, err := models.Appointments(models.AppointmentWhere.SeriesID.EQ(null.IntFrom(seriesId))).DeleteAll(context.Background(), exec, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.Exec("UPDATE appointment_series SET deleted_at = ? WHERE id = ?", time.Now(), seriesId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EditAppointment takes the ID of an appointment and its new date, duration, location and whether it is held online and overwrites it.
+// If wholeSeries is set, every appointment of the series the appointment is part of is changed as well,
+// moving each of them by the same amount of time the given appointment is moved.
+func (p *PublicController) EditAppointment(appointmentId int, date time.Time, duration int, location null.String, online int8, wholeSeries bool) error {
+	// a missing date would move the appointments to the year 1
+	if date.IsZero() || duration <= 0 {
+		return errs.ErrInvalidAppointment
+	}
+
+	tx, err := p.Database.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	appointment, err := models.FindAppointment(context.Background(), tx, appointmentId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	appointments := models.AppointmentSlice{appointment}
+	if wholeSeries && appointment.SeriesID.Valid {
+		appointments, err = models.Appointments(models.AppointmentWhere.SeriesID.EQ(appointment.SeriesID)).All(context.Background(), tx)
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+			return err
+		}
+	}
+
+	shift := date.Sub(appointment.Date)
+	for _, a := range appointments {
+		a.Date = a.Date.Add(shift)
+		a.Duration = duration
+		a.Location = location
+		a.Online = online
+
+		_, err = a.Update(context.Background(), tx, boil.Infer())
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+			return err
+		}
+	}
+
+	if e := tx.Commit(); e != nil {
+		return fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return nil
+}
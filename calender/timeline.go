@@ -0,0 +1,217 @@
+package calender
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"learningbay24.de/backend/course"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// TimelineEntryType tells which kind of date an entry of the timeline is
+type TimelineEntryType string
+
+const (
+	TimelineAppointment            TimelineEntryType = "appointment"
+	TimelineExam                   TimelineEntryType = "exam"
+	TimelineExamRegisterDeadline   TimelineEntryType = "exam_register_deadline"
+	TimelineExamDeregisterDeadline TimelineEntryType = "exam_deregister_deadline"
+	TimelineSubmissionDeadline     TimelineEntryType = "submission_deadline"
+)
+
+// TimelineEntry is a single date of the timeline of a user.
+// ID is the ID of the appointment, exam or submission the entry belongs to and Link the API path to get it from.
+type TimelineEntry struct {
+	Type       TimelineEntryType `json:"type"`
+	ID         int               `json:"id"`
+	Title      string            `json:"title"`
+	CourseID   int               `json:"course_id"`
+	CourseName string            `json:"course_name"`
+	Start      time.Time         `json:"start"`
+	End        null.Time         `json:"end"`
+	Location   null.String       `json:"location"`
+	Online     bool              `json:"online"`
+	Link       string            `json:"link"`
+}
+
+// GetTimeline takes the ID of a user and a time range and returns everything that takes place in the courses the user is enrolled in within that range, sorted by date.
+// It consists of appointments, exams, exam registration deadlines and deadlines of submissions that are visible already.
+func (p *PublicController) GetTimeline(userId int, from time.Time, to time.Time) ([]*TimelineEntry, error) {
+	if from.After(to) {
+		return nil, errs.ErrInvalidTimeRange
+	}
+
+	entries, err := p.getTimeline(userId, time.Now(), timeRange{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	// an exam is loaded if any of its dates is within the range, so its other dates have to be left out here
+	timeline := []*TimelineEntry{}
+	for _, e := range entries {
+		end := e.Start
+		if e.End.Valid {
+			end = e.End.Time
+		}
+		// entries that are still going on at the start of the range are part of it as well
+		if e.Start.Before(to) && !end.Before(from) {
+			timeline = append(timeline, e)
+		}
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].Start.Before(timeline[j].Start) })
+
+	return timeline, nil
+}
+
+// timeRange limits which entries of the timeline are loaded, the zero value loads all of them
+type timeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (r timeRange) all() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// overlaps returns the condition for dates of the given table starting before the end of the range and ending after its start
+func (r timeRange) overlaps(table string) qm.QueryMod {
+	return qm.Expr(
+		qm.Where(fmt.Sprintf("`%s`.`date` < ?", table), r.To),
+		qm.Where(fmt.Sprintf("DATE_ADD(`%s`.`date`, INTERVAL `%s`.`duration` SECOND) >= ?", table, table), r.From),
+	)
+}
+
+// getTimeline collects the entries of the timeline of a user that may be within the given range. Exams are returned with all of their dates
+// if any of them is within the range.
+func (p *PublicController) getTimeline(userId int, now time.Time, r timeRange) ([]*TimelineEntry, error) {
+	courses, err := course.GetEnrolledCoursesFromUser(p.Database, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*TimelineEntry{}
+	if len(courses) == 0 {
+		return entries, nil
+	}
+
+	names := make(map[int]string, len(courses))
+	ids := make([]int, 0, len(courses))
+	for _, c := range courses {
+		names[c.ID] = c.Name
+		ids = append(ids, c.ID)
+	}
+
+	appointmentMods := []qm.QueryMod{models.AppointmentWhere.CourseID.IN(ids)}
+	if !r.all() {
+		appointmentMods = append(appointmentMods, r.overlaps(models.TableNames.Appointment))
+	}
+	appointments, err := models.Appointments(appointmentMods...).All(context.Background(), p.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range appointments {
+		entries = append(entries, &TimelineEntry{
+			Type:       TimelineAppointment,
+			ID:         a.ID,
+			Title:      names[a.CourseID],
+			CourseID:   a.CourseID,
+			CourseName: names[a.CourseID],
+			Start:      a.Date,
+			End:        null.TimeFrom(a.Date.Add(time.Duration(a.Duration) * time.Second)),
+			Location:   a.Location,
+			Online:     a.Online == 1,
+			Link:       fmt.Sprintf("/courses/%d", a.CourseID),
+		})
+	}
+
+	examMods := []qm.QueryMod{models.ExamWhere.CourseID.IN(ids)}
+	if !r.all() {
+		from, to := null.TimeFrom(r.From), null.TimeFrom(r.To)
+		examMods = append(examMods, qm.Expr(
+			r.overlaps(models.TableNames.Exam),
+			qm.Or2(qm.Expr(models.ExamWhere.RegisterDeadline.GTE(from), models.ExamWhere.RegisterDeadline.LT(to))),
+			qm.Or2(qm.Expr(models.ExamWhere.DeregisterDeadline.GTE(from), models.ExamWhere.DeregisterDeadline.LT(to))),
+		))
+	}
+	exams, err := models.Exams(examMods...).All(context.Background(), p.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ex := range exams {
+		link := fmt.Sprintf("/exams/%d", ex.ID)
+		entries = append(entries, &TimelineEntry{
+			Type:       TimelineExam,
+			ID:         ex.ID,
+			Title:      ex.Name,
+			CourseID:   ex.CourseID,
+			CourseName: names[ex.CourseID],
+			Start:      ex.Date,
+			End:        null.TimeFrom(ex.Date.Add(time.Duration(ex.Duration) * time.Second)),
+			Location:   ex.Location,
+			Online:     ex.Online == 1,
+			Link:       link,
+		})
+
+		if ex.RegisterDeadline.Valid {
+			entries = append(entries, &TimelineEntry{
+				Type:       TimelineExamRegisterDeadline,
+				ID:         ex.ID,
+				Title:      ex.Name,
+				CourseID:   ex.CourseID,
+				CourseName: names[ex.CourseID],
+				Start:      ex.RegisterDeadline.Time,
+				Link:       link,
+			})
+		}
+		if ex.DeregisterDeadline.Valid {
+			entries = append(entries, &TimelineEntry{
+				Type:       TimelineExamDeregisterDeadline,
+				ID:         ex.ID,
+				Title:      ex.Name,
+				CourseID:   ex.CourseID,
+				CourseName: names[ex.CourseID],
+				Start:      ex.DeregisterDeadline.Time,
+				Link:       link,
+			})
+		}
+	}
+
+	submissionMods := []qm.QueryMod{
+		models.SubmissionWhere.CourseID.IN(ids),
+		models.SubmissionWhere.VisibleFrom.LTE(now),
+		models.SubmissionWhere.Deadline.IsNotNull(),
+	}
+	if !r.all() {
+		submissionMods = append(submissionMods,
+			models.SubmissionWhere.Deadline.GTE(null.TimeFrom(r.From)),
+			models.SubmissionWhere.Deadline.LT(null.TimeFrom(r.To)),
+		)
+	}
+	submissions, err := models.Submissions(submissionMods...).All(context.Background(), p.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range submissions {
+		entries = append(entries, &TimelineEntry{
+			Type:       TimelineSubmissionDeadline,
+			ID:         s.ID,
+			Title:      s.Name,
+			CourseID:   s.CourseID,
+			CourseName: names[s.CourseID],
+			Start:      s.Deadline.Time,
+			Link:       fmt.Sprintf("/submissions/%d", s.ID),
+		})
+	}
+
+	return entries, nil
+}
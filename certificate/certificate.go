@@ -0,0 +1,139 @@
+// Package certificate implements issuing and verifying certificates users gain by passing exams
+package certificate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// Details is a certificate together with the information needed to verify it
+type Details struct {
+	ID       string    `json:"id"`
+	Holder   string    `json:"holder"`
+	Course   string    `json:"course"`
+	Exam     string    `json:"exam,omitempty"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// newUUID generates a random version 4 UUID as described in RFC 4122
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Issue takes the ID of a user, of a course and of an exam of that course and issues a certificate for passing it.
+// If the user already holds a certificate for the exam, its ID is returned instead.
+func Issue(exec boil.ContextExecutor, userId int, courseId int, examId int) (string, error) {
+	cert, err := models.Certificates(
+		models.CertificateWhere.UserID.EQ(userId),
+		models.CertificateWhere.ExamID.EQ(null.IntFrom(examId)),
+	).One(context.Background(), exec)
+	if err == nil {
+		return cert.ID, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+
+	cert = &models.Certificate{ID: id, UserID: userId, LinkedCourseID: courseId, ExamID: null.IntFrom(examId)}
+	err = cert.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return "", err
+	}
+
+	return cert.ID, nil
+}
+
+// Revoke takes the ID of a user and of an exam and soft-deletes the certificate the user got for passing it, if there is one
+func Revoke(exec boil.ContextExecutor, userId int, examId int) error {
+	_, err := models.Certificates(
+		models.CertificateWhere.UserID.EQ(userId),
+		models.CertificateWhere.ExamID.EQ(null.IntFrom(examId)),
+	).DeleteAll(context.Background(), exec, false)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getDetails collects the information about a certificate that is shown to users
+func getDetails(db *sql.DB, cert *models.Certificate) (*Details, error) {
+	user, err := models.FindUser(context.Background(), db, cert.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	course, err := models.FindCourse(context.Background(), db, cert.LinkedCourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Details{ID: cert.ID, Holder: user.Firstname + " " + user.Surname, Course: course.Name, IssuedAt: cert.CreatedAt.Time}
+	if user.Title.Valid {
+		d.Holder = user.Title.String + " " + d.Holder
+	}
+
+	if cert.ExamID.Valid {
+		exam, err := models.FindExam(context.Background(), db, cert.ExamID.Int)
+		if err != nil {
+			return nil, err
+		}
+		d.Exam = exam.Name
+	}
+
+	return d, nil
+}
+
+// GetCertificate takes the ID of a certificate and returns its details, which is used to verify it
+func GetCertificate(db *sql.DB, id string) (*Details, error) {
+	cert, err := models.FindCertificate(context.Background(), db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return getDetails(db, cert)
+}
+
+// GetCertificatesFromUser takes the ID of a user and returns the details of all certificates they hold, newest first
+func GetCertificatesFromUser(db *sql.DB, userId int) ([]*Details, error) {
+	certs, err := models.Certificates(
+		models.CertificateWhere.UserID.EQ(userId),
+		qm.OrderBy(models.CertificateColumns.CreatedAt+" DESC"),
+	).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]*Details, 0, len(certs))
+	for _, cert := range certs {
+		d, err := getDetails(db, cert)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, d)
+	}
+
+	return details, nil
+}
@@ -0,0 +1,60 @@
+package certificate
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUUID(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := newUUID()
+		assert.NoError(t, err)
+		assert.Regexp(t, format, id)
+		assert.False(t, seen[id])
+		seen[id] = true
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	d := &Details{
+		ID:       "0b7e8c1a-2f4d-4e6a-9c3b-5d7f1a2b3c4d",
+		Holder:   "Dr. Jürgen Müller",
+		Course:   "Analysis (I)",
+		Exam:     "Final",
+		IssuedAt: time.Date(2022, 6, 30, 12, 0, 0, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	err := WritePDF(&buf, d)
+	assert.NoError(t, err)
+
+	pdf := buf.String()
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	assert.Contains(t, pdf, "(Analysis \\(I\\)) Tj")
+	assert.Contains(t, pdf, "(Dr. J\xfcrgen M\xfcller) Tj")
+	assert.Contains(t, pdf, "(Issued on 30.06.2022) Tj")
+
+	// every entry of the cross-reference table has to point at the start of its object
+	start := strings.LastIndex(pdf, "startxref\n")
+	xref, err := strconv.Atoi(strings.Fields(pdf[start+len("startxref\n"):])[0])
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(pdf[xref:], "xref\n"))
+
+	entries := strings.Split(pdf[xref:], "\n")[3:8]
+	for i, e := range entries {
+		off, err := strconv.Atoi(e[:10])
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(pdf[off:], fmt.Sprintf("%d 0 obj\n", i+1)))
+	}
+}
@@ -0,0 +1,103 @@
+package certificate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A line of text on the certificate, placed at the given height from the bottom of the page
+type pdfLine struct {
+	text string
+	size int
+	y    int
+}
+
+// Width and height of an A4 page in points
+const (
+	pageWidth  = 595
+	pageHeight = 842
+)
+
+// Average width of a Helvetica glyph relative to the font size, used to roughly center the text
+const avgGlyphWidth = 0.5
+
+// WritePDF renders the certificate as a single page PDF document and writes it to w
+func WritePDF(w io.Writer, d *Details) error {
+	lines := []pdfLine{
+		{"Certificate", 36, 680},
+		{"This is to certify that", 14, 600},
+		{d.Holder, 24, 560},
+		{"has successfully completed the course", 14, 510},
+		{d.Course, 20, 475},
+	}
+	if d.Exam != "" {
+		lines = append(lines,
+			pdfLine{"by passing the exam", 14, 430},
+			pdfLine{d.Exam, 18, 400},
+		)
+	}
+	lines = append(lines,
+		pdfLine{"Issued on " + d.IssuedAt.Format("02.01.2006"), 12, 200},
+		pdfLine{"Certificate ID: " + d.ID, 10, 120},
+		pdfLine{"This certificate can be verified at /certificates/" + d.ID, 10, 105},
+	)
+
+	var content strings.Builder
+	for _, l := range lines {
+		text := encodeWinAnsi(l.text)
+		x := (pageWidth - float64(len(text))*float64(l.size)*avgGlyphWidth) / 2
+		if x < 20 {
+			x = 20
+		}
+		fmt.Fprintf(&content, "BT /F1 %d Tf %.2f %d Td (%s) Tj ET\n", l.size, x, l.y, escapePDFString(text))
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>", pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// encodeWinAnsi converts s to the single byte encoding of the standard fonts.
+// Characters outside of Latin-1 can't be displayed and are replaced by a question mark.
+func encodeWinAnsi(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff || (r < 0x20 && r != '\t') {
+			b = append(b, '?')
+			continue
+		}
+		b = append(b, byte(r))
+	}
+
+	return string(b)
+}
+
+// escapePDFString escapes the characters that have a special meaning in PDF string literals
+func escapePDFString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
@@ -22,10 +22,41 @@ type Files struct {
 	Path             string
 	AllowedFileTypes []string
 	MaxUploadPerUser int
+	// Backend new files are stored in, "local" or "s3"
+	Storage string
+	S3      S3
+	// Directory infected uploads are kept in, which must not be below Path
+	QuarantinePath string
+	Scanner        Scanner
+}
+
+type Scanner struct {
+	// Address of clamd, e.g. "tcp://127.0.0.1:3310" or "unix:///run/clamav/clamd.ctl". Uploads aren't scanned if it is empty.
+	Clamd string
+	// Seconds a scan may take, 60 if 0
+	Timeout int
+	// Minutes between checks whether the signatures were updated, after which all files are scanned again, 60 if 0
+	RescanInterval int
+	// Largest content in bytes clamd accepts, its StreamMaxLength, 25 MiB if 0
+	MaxSize int64
+	// Reject uploads larger than MaxSize instead of storing them without scanning them
+	RejectUnscannable bool
+}
+
+type S3 struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Address the bucket as part of the path instead of the host name, as MinIO does by default
+	PathStyle bool
 }
 
 type Secrets struct {
 	JWTSecret string
+	// Key download URLs are signed with, derived from JWTSecret if empty
+	DownloadSecret string
 }
 
 type Config struct {
@@ -41,10 +72,12 @@ type Config struct {
 
 var (
 	Conf Config
+	// Name of the storage backend to move all files to instead of running the server, set with -m
+	MigrateStorage string
 )
 
 func parseCLI() {
-	opts, _, err := getopt.Getopts(os.Args, "v")
+	opts, _, err := getopt.Getopts(os.Args, "vm:")
 	if err != nil {
 		log.Fatalf("Unable to parse command line arguments: %s\n", err.Error())
 	}
@@ -53,6 +86,8 @@ func parseCLI() {
 		switch opt.Option {
 		case 'v':
 			Conf.LogLevel = "debug"
+		case 'm':
+			MigrateStorage = opt.Value
 		}
 	}
 }
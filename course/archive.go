@@ -0,0 +1,91 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// ArchiveCourse takes the ID of a course and archives it. Archived courses are read-only: members can still see materials and grades,
+// but nobody can enroll, upload, submit, register for exams or post in the forum anymore.
+func ArchiveCourse(db *sql.DB, courseId int) error {
+	c, err := models.FindCourse(context.Background(), db, courseId)
+	if err != nil {
+		return err
+	}
+	if c.ArchivedAt.Valid {
+		return nil
+	}
+
+	c.ArchivedAt = null.TimeFrom(time.Now())
+	_, err = c.Update(context.Background(), db, boil.Whitelist(models.CourseColumns.ArchivedAt, models.CourseColumns.UpdatedAt))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnarchiveCourse takes the ID of an archived course and makes it writable again, admitting users from the waitlist if there are free seats
+func UnarchiveCourse(db *sql.DB, courseId int) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	err = unarchiveCourse(tx, courseId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return nil
+}
+
+func unarchiveCourse(exec boil.ContextExecutor, courseId int) error {
+	c, err := lockCourse(exec, courseId)
+	if err != nil {
+		return err
+	}
+	if !c.ArchivedAt.Valid {
+		return nil
+	}
+
+	c.ArchivedAt = null.TimeFromPtr(nil)
+	_, err = c.Update(context.Background(), exec, boil.Whitelist(models.CourseColumns.ArchivedAt, models.CourseColumns.UpdatedAt))
+	if err != nil {
+		return err
+	}
+
+	return fillFromWaitlist(exec, c)
+}
+
+// GetArchivedCoursesFromUser takes the ID of a user and returns the archived courses they are a member of, regardless of their role
+func GetArchivedCoursesFromUser(db *sql.DB, uid int) ([]*models.Course, error) {
+	courses, err := models.Courses(
+		qm.Select(models.CourseColumns.ID, models.CourseColumns.Name, models.CourseColumns.Description, models.CourseColumns.ForumID, "course.created_at", "course.updated_at", "course.archived_at"),
+		qm.From(models.TableNames.UserHasCourse),
+		qm.Where("user_has_course.user_id = ?", uid),
+		qm.And("user_has_course.course_id = course.id"),
+		qm.And("user_has_course.deleted_at IS NULL"),
+		qm.And("course.archived_at IS NOT NULL"),
+		qm.OrderBy("course.archived_at DESC"),
+	).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	return courses, nil
+}
@@ -0,0 +1,270 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// CopyCourse takes the ID of a course, of the user copying it, the name of the copy and an amount of days and creates a copy of the course owned by the user, e.g. for a new term.
+// The copy contains the materials, directories, submissions and exams of the course, with all of their dates moved by the given amount of days.
+// Users, their submissions and the forum entries are not copied. If name is empty, the copy keeps the name of the course.
+func CopyCourse(db *sql.DB, courseId int, userId int, name string, offsetDays int) (int, error) {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := copyCourse(tx, courseId, userId, name, offsetDays)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return 0, err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return id, nil
+}
+
+func copyCourse(exec boil.ContextExecutor, courseId int, userId int, name string, offsetDays int) (int, error) {
+	src, err := models.FindCourse(context.Background(), exec, courseId)
+	if err != nil {
+		return 0, err
+	}
+	if name == "" {
+		name = src.Name
+	}
+
+	// moving by days instead of a duration keeps the time of day across daylight saving time
+	shift := func(t time.Time) time.Time { return t.AddDate(0, 0, offsetDays) }
+	shiftNull := func(t null.Time) null.Time {
+		if !t.Valid {
+			return t
+		}
+		return null.TimeFrom(shift(t.Time))
+	}
+
+	f := &models.Forum{Name: name}
+	err = f.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	c := &models.Course{Name: name, Description: src.Description, EnrollKey: src.EnrollKey, ForumID: f.ID, EnrollMode: src.EnrollMode, Capacity: src.Capacity}
+	err = c.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	uhc := models.UserHasCourse{UserID: userId, CourseID: c.ID, RoleID: dbi.CourseAdminRoleId}
+	err = uhc.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = exec.Exec(`INSERT INTO course_requires_course (course_id, required_course_id)
+		SELECT ?, required_course_id FROM course_requires_course WHERE course_id = ?`, c.ID, src.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	// directories are created first and nested afterwards, as a parent may come after its children
+	directories, err := models.Directories(models.DirectoryWhere.CourseID.EQ(src.ID)).All(context.Background(), exec)
+	if err != nil {
+		return 0, err
+	}
+
+	copies := make(map[int]*models.Directory, len(directories))
+	for _, d := range directories {
+		cp := &models.Directory{Name: d.Name, CourseID: c.ID, VisibleFrom: shift(d.VisibleFrom)}
+		err = cp.Insert(context.Background(), exec, boil.Infer())
+		if err != nil {
+			return 0, err
+		}
+		copies[d.ID] = cp
+	}
+
+	for _, d := range directories {
+		if !d.ParentID.Valid {
+			continue
+		}
+		// the parent may have been deleted
+		parent, ok := copies[d.ParentID.Int]
+		if !ok {
+			continue
+		}
+
+		cp := copies[d.ID]
+		cp.ParentID = null.IntFrom(parent.ID)
+		_, err = cp.Update(context.Background(), exec, boil.Whitelist(models.DirectoryColumns.ParentID))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var placements []struct {
+		FileID      int `boil:"file_id"`
+		DirectoryID int `boil:"directory_id"`
+	}
+	err = queries.Raw(`SELECT dhf.file_id, dhf.directory_id FROM directory_has_files dhf
+		JOIN directory d ON d.id = dhf.directory_id
+		WHERE d.course_id = ? AND d.deleted_at IS NULL`, src.ID).Bind(context.Background(), exec, &placements)
+	if err != nil {
+		return 0, err
+	}
+
+	fileDirectories := make(map[int]int, len(placements))
+	for _, p := range placements {
+		fileDirectories[p.FileID] = p.DirectoryID
+	}
+
+	files, err := getLinkedFiles(exec, "course_has_files", "course_id", src.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, file := range files {
+		fileId, err := copyFile(exec, file)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = exec.Exec("INSERT INTO course_has_files (course_id, file_id) VALUES (?, ?)", c.ID, fileId)
+		if err != nil {
+			return 0, err
+		}
+		err = dbi.UpdateFileReferences(exec, fileId)
+		if err != nil {
+			return 0, err
+		}
+
+		if d, ok := copies[fileDirectories[file.ID]]; ok {
+			_, err = exec.Exec("INSERT INTO directory_has_files (directory_id, file_id) VALUES (?, ?)", d.ID, fileId)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	submissions, err := models.Submissions(models.SubmissionWhere.CourseID.EQ(src.ID)).All(context.Background(), exec)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range submissions {
+		cp := &models.Submission{Name: s.Name, Deadline: shiftNull(s.Deadline), CourseID: c.ID, MaxFilesize: s.MaxFilesize, VisibleFrom: shift(s.VisibleFrom), AllowedFileTypes: s.AllowedFileTypes}
+		err = cp.Insert(context.Background(), exec, boil.Infer())
+		if err != nil {
+			return 0, err
+		}
+
+		err = copyLinkedFiles(exec, "submission_has_files", "submission_id", s.ID, cp.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	exams, err := models.Exams(models.ExamWhere.CourseID.EQ(src.ID)).All(context.Background(), exec)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, ex := range exams {
+		cp := &models.Exam{
+			Name:               ex.Name,
+			Description:        ex.Description,
+			Date:               shift(ex.Date),
+			Duration:           ex.Duration,
+			Online:             ex.Online,
+			Location:           ex.Location,
+			CourseID:           c.ID,
+			CreatorID:          userId,
+			RegisterDeadline:   shiftNull(ex.RegisterDeadline),
+			DeregisterDeadline: shiftNull(ex.DeregisterDeadline),
+		}
+		err = cp.Insert(context.Background(), exec, boil.Infer())
+		if err != nil {
+			return 0, err
+		}
+
+		err = copyLinkedFiles(exec, "exam_has_files", "exam_id", ex.ID, cp.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return c.ID, nil
+}
+
+// getLinkedFiles returns the files that aren't deleted and are linked to the entity with the given ID through the given table
+func getLinkedFiles(exec boil.ContextExecutor, table string, column string, id int) (models.FileSlice, error) {
+	var files models.FileSlice
+	query := fmt.Sprintf(`SELECT f.* FROM file f
+		JOIN %s l ON l.file_id = f.id
+		WHERE l.%s = ? AND f.deleted_at IS NULL`, table, column)
+	// only the links of course materials can be soft-deleted
+	if table == "course_has_files" {
+		query += " AND l.deleted_at IS NULL"
+	}
+
+	err := queries.Raw(query, id).Bind(context.Background(), exec, &files)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// copyLinkedFiles copies the files linked to one entity and links the copies to another one through the same table
+func copyLinkedFiles(exec boil.ContextExecutor, table string, column string, fromId int, toId int) error {
+	files, err := getLinkedFiles(exec, table, column, fromId)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		fileId, err := copyFile(exec, file)
+		if err != nil {
+			return err
+		}
+
+		_, err = exec.Exec(fmt.Sprintf("INSERT INTO %s (%s, file_id) VALUES (?, ?)", table, column), toId, fileId)
+		if err != nil {
+			return err
+		}
+		err = dbi.UpdateFileReferences(exec, fileId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyFile creates a new file entry pointing to the same content as the given file, so that both courses can rename or delete their file independently
+// The content is shared, deleting one of the files only removes it once the other one is deleted too.
+func copyFile(exec boil.ContextExecutor, file *models.File) (int, error) {
+	cp := &models.File{Name: file.Name, URI: file.URI, Local: file.Local, BlobID: file.BlobID, MimeType: file.MimeType, UploaderID: file.UploaderID}
+	err := cp.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	err = dbi.CopyIndexedFile(exec, file.ID, cp.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return cp.ID, nil
+}
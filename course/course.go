@@ -3,18 +3,17 @@ package course
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"time"
 
 	coursematerial "learningbay24.de/backend/courseMaterial"
 	"learningbay24.de/backend/dbi"
 	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/forum"
 	"learningbay24.de/backend/models"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/volatiletech/null/v8"
-	"github.com/volatiletech/sqlboiler/queries"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
@@ -64,8 +63,8 @@ func CreateCourse(db *sql.DB, name string, description null.String, enrollkey st
 
 		return 0, err
 	} else {
-		// TODO: Implement roles assigment for tutors
-		// Gives the user with the ID in the 0 place in the array the role of the creator
+		// Gives the user with the ID in the 0 place in the array the role of the creator;
+		// tutors are assigned afterwards with `PromoteUser`
 		shasc := models.UserHasCourse{UserID: usersid, CourseID: c.ID, RoleID: dbi.CourseAdminRoleId}
 		err = shasc.Insert(context.Background(), tx, boil.Infer())
 		if err != nil {
@@ -185,7 +184,18 @@ func DeleteCourse(db *sql.DB, id int) (int, error) {
 	// Checks if more than 10 Minutes have passed will softdelete if thats the case
 	curTime := time.Now()
 	diff := curTime.Sub(c.CreatedAt.Time)
-	if diff.Minutes() < 10 {
+	hardDelete := diff.Minutes() >= 10
+
+	err = deleteAppointmentsFromCourse(tx, id, hardDelete)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return 0, err
+	}
+
+	if !hardDelete {
 		_, err = c.Delete(context.Background(), tx, false)
 		if err != nil {
 			if e := tx.Rollback(); e != nil {
@@ -211,6 +221,24 @@ func DeleteCourse(db *sql.DB, id int) (int, error) {
 
 	}
 
+	// requests, invites and the log of role changes only matter as long as the course exists
+	for _, table := range []string{"enrollment_request", "course_invite", "course_role_change"} {
+		_, err = tx.Exec("DELETE FROM "+table+" WHERE course_id = ?", id)
+		if err != nil {
+			break
+		}
+	}
+	// the course neither requires other courses nor is it required by them anymore
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM course_requires_course WHERE course_id = ? OR required_course_id = ?", id, id)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return 0, err
+	}
+
 	_, err = c.Delete(context.Background(), tx, true)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
@@ -219,7 +247,10 @@ func DeleteCourse(db *sql.DB, id int) (int, error) {
 		return 0, err
 	}
 
-	_, err = f.Delete(context.Background(), tx, true)
+	err = forum.DeleteAllEntries(tx, f.ID)
+	if err == nil {
+		_, err = f.Delete(context.Background(), tx, true)
+	}
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
 			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
@@ -233,7 +264,26 @@ func DeleteCourse(db *sql.DB, id int) (int, error) {
 	return c.ID, nil
 }
 
-// GetEnrolledCoursesFromUser takes the ID of a User and returns a slice of Courses in which he is enrolled
+// deleteAppointmentsFromCourse deletes all appointments of a course together with the series linking them
+func deleteAppointmentsFromCourse(exec boil.ContextExecutor, courseId int, hardDelete bool) error {
+	_, err := models.Appointments(models.AppointmentWhere.CourseID.EQ(courseId)).DeleteAll(context.Background(), exec, hardDelete)
+	if err != nil {
+		return err
+	}
+
+	if hardDelete {
+		_, err = exec.Exec("DELETE FROM appointment_series WHERE course_id = ?", courseId)
+	} else {
+		_, err = exec.Exec("UPDATE appointment_series SET deleted_at = ? WHERE course_id = ? AND deleted_at IS NULL", time.Now(), courseId)
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetEnrolledCoursesFromUser takes the ID of a User and returns a slice of Courses in which he is enrolled, except archived ones
 func GetEnrolledCoursesFromUser(db *sql.DB, uid int) ([]*models.Course, error) {
 
 	courses, err := models.Courses(
@@ -242,9 +292,11 @@ func GetEnrolledCoursesFromUser(db *sql.DB, uid int) ([]*models.Course, error) {
 		qm.Where("user_has_course.user_id=?", uid),
 		qm.And("user_has_course.course_id = course.id"),
 		qm.And("user_has_course.role_id = ?", dbi.CourseUserRoleId),
+		qm.And("course.archived_at IS NULL"),
 		qm.Or("user_has_course.user_id=?", uid),
 		qm.And("user_has_course.course_id = course.id"),
 		qm.And("user_has_course.role_id = ?", dbi.CourseModeratorRoleId),
+		qm.And("course.archived_at IS NULL"),
 	).All(context.Background(), db)
 	if err != nil {
 		return nil, err
@@ -253,7 +305,7 @@ func GetEnrolledCoursesFromUser(db *sql.DB, uid int) ([]*models.Course, error) {
 	return courses, nil
 }
 
-// GetCoursesFromUser takes the ID of a User and returns a slice of Courses in which he is enrolled
+// GetCoursesFromUser takes the ID of a User and returns a slice of Courses in which he is enrolled, except archived ones
 func GetCreatedCoursesFromUser(db *sql.DB, uid int) ([]*models.Course, error) {
 
 	courses, err := models.Courses(
@@ -262,6 +314,7 @@ func GetCreatedCoursesFromUser(db *sql.DB, uid int) ([]*models.Course, error) {
 		qm.Where("user_has_course.user_id=?", uid),
 		qm.And("user_has_course.course_id = course.id"),
 		qm.And("user_has_course.role_id = ?", dbi.CourseAdminRoleId),
+		qm.And("course.archived_at IS NULL"),
 	).All(context.Background(), db)
 	if err != nil {
 		return nil, err
@@ -312,75 +365,114 @@ func DeleteUserFromCourse(db *sql.DB, uid int, cid int) error {
 		return err
 	}
 
+	// the seat that was freed goes to the next user on the waitlist
+	c, err := lockCourse(tx, cid)
+	if err == nil {
+		err = fillFromWaitlist(tx, c)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
 	if e := tx.Commit(); e != nil {
 		return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 	}
 	return nil
 }
 
-// EnrollUser takes a UserID, CourseID and Enrollkey and adds the User to the course if the enrollkey is correct
-func EnrollUser(db *sql.DB, uid int, cid int, enrollkey string) (*models.User, error) {
+// EnrollUser takes a UserID, CourseID and Enrollkey and adds the User to the course, depending on its enroll mode.
+// Courses that require approval only store a request, full courses put the user on their waitlist.
+func EnrollUser(db *sql.DB, uid int, cid int, enrollkey string) (*models.User, EnrollmentStatus, error) {
 
 	tx, err := db.BeginTx(context.Background(), nil)
 	if err != nil {
 
-		return nil, err
+		return nil, "", err
 	}
 
-	c, err := models.FindCourse(context.Background(), db, cid)
+	c, err := lockCourse(tx, cid)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
-			return nil, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
 
-		return nil, err
+		return nil, "", err
 	}
-	if c.EnrollKey != enrollkey {
+	if c.ArchivedAt.Valid {
 		if e := tx.Rollback(); e != nil {
-			return nil, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
-		fmt.Println("enrollkey:", enrollkey, "expected:", c.EnrollKey)
-		return nil, errs.ErrWrongEnrollkey
-
+		return nil, "", errs.ErrCourseArchived
 	}
-	u, err := models.FindUser(context.Background(), db, uid)
+	switch EnrollMode(c.EnrollMode) {
+	case EnrollClosed:
+		if e := tx.Rollback(); e != nil {
+			return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return nil, "", errs.ErrEnrollmentClosed
+	case EnrollWithKey:
+		if c.EnrollKey != enrollkey {
+			if e := tx.Rollback(); e != nil {
+				return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+			return nil, "", errs.ErrWrongEnrollkey
+		}
+	}
+	missing, err := GetMissingPrerequisites(tx, uid, cid)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
-			return nil, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
 
-		return nil, err
+		return nil, "", err
 	}
+	if len(missing) > 0 {
+		if e := tx.Rollback(); e != nil {
+			return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
 
-	var uhex models.UserHasCourseSlice
-	// first check if relation already exists in the database and either insert a new row or reset deleted_at
-	err = queries.Raw("select * from user_has_course where course_id=? AND user_id=?", cid, uid).Bind(context.Background(), db, &uhex)
-	if err != nil {
-		return nil, err
+		return nil, "", errs.ErrMissingPrerequisites
 	}
-	if len(uhex) > 0 {
-		uhex[0].DeletedAt = null.TimeFromPtr(nil)
-		_, err = uhex[0].Update(context.Background(), db, boil.Infer())
-		if err != nil {
-			return nil, err
+	u, err := models.FindUser(context.Background(), tx, uid)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
-		return u, nil
-	}
 
-	userhascourse := models.UserHasCourse{UserID: uid, CourseID: cid, RoleID: dbi.CourseUserRoleId}
-	err = userhascourse.Insert(context.Background(), tx, boil.Infer())
+		return nil, "", err
+	}
+
+	status := StatusEnrolled
+	enrolled, err := models.UserHasCourseExists(context.Background(), tx, uid, cid)
+	if err == nil && !enrolled {
+		var full bool
+		full, err = isFull(tx, c)
+		switch {
+		case err != nil:
+		case EnrollMode(c.EnrollMode) == EnrollWithApproval:
+			status, err = requestEnrollment(tx, uid, cid, requestPending)
+		case full:
+			status, err = requestEnrollment(tx, uid, cid, requestWaitlisted)
+		default:
+			err = admit(tx, uid, cid)
+		}
+	}
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
-			return nil, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
 
-		return nil, err
+		return nil, "", err
 	}
 
 	if e := tx.Commit(); e != nil {
-		return nil, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		return nil, "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 	}
-	return u, nil
+	return u, status, nil
 }
 
 func GetCourseRole(db *sql.DB, user_id int, course_id int) (int, error) {
@@ -391,22 +483,3 @@ func GetCourseRole(db *sql.DB, user_id int, course_id int) (int, error) {
 	}
 	return userhascourse.RoleID, nil
 }
-
-// Search in course_name and course_descripton for the searchterm
-func SearchCourse(db *sql.DB, searchterm string) ([]*models.Course, error) {
-	searchterm = "%" + searchterm + "%"
-	courses, err := models.Courses(
-		qm.Select(models.CourseColumns.ID, models.CourseColumns.Name, models.CourseColumns.Description, models.CourseColumns.ForumID, models.CourseColumns.CreatedAt, models.CourseColumns.UpdatedAt),
-		qm.Where(models.CourseColumns.Name+" LIKE ?", searchterm),
-		qm.Or(models.CourseColumns.Description+" LIKE ?", searchterm),
-	).All(context.Background(), db)
-	if err != nil {
-		// avoid no courses available being a `404`
-		if errors.Is(err, sql.ErrNoRows) {
-			return []*models.Course{}, nil
-		}
-
-		return nil, err
-	}
-	return courses, nil
-}
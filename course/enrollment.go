@@ -0,0 +1,323 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/notification"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// EnrollMode decides how users enroll in a course
+type EnrollMode int8
+
+const (
+	EnrollWithKey EnrollMode = iota
+	EnrollOpen
+	EnrollWithApproval
+	EnrollClosed
+)
+
+// EnrollmentStatus tells how far a user got trying to enroll in a course
+type EnrollmentStatus string
+
+const (
+	StatusEnrolled   EnrollmentStatus = "enrolled"
+	StatusPending    EnrollmentStatus = "pending"
+	StatusWaitlisted EnrollmentStatus = "waitlisted"
+)
+
+// Values of the `status` column of `enrollment_request`
+const (
+	requestPending int8 = iota
+	requestWaitlisted
+)
+
+// EnrollmentRequest is a user waiting for the approval of a course admin or for a free seat in the course
+type EnrollmentRequest struct {
+	UserID    int              `boil:"user_id" json:"user_id"`
+	CourseID  int              `boil:"course_id" json:"course_id"`
+	Firstname string           `boil:"firstname" json:"firstname"`
+	Surname   string           `boil:"surname" json:"surname"`
+	Status    EnrollmentStatus `boil:"-" json:"status"`
+	RawStatus int8             `boil:"status" json:"-"`
+	CreatedAt null.Time        `boil:"created_at" json:"created_at"`
+}
+
+// SetEnrollmentOptions takes the ID of a course, how users enroll in it and the maximum amount of users and overwrites the corresponding options of the course.
+// If the capacity grows, users on the waitlist are admitted right away.
+func SetEnrollmentOptions(db *sql.DB, courseId int, mode EnrollMode, capacity null.Int) error {
+	if mode < EnrollWithKey || mode > EnrollClosed {
+		return errs.ErrInvalidEnrollMode
+	}
+	if capacity.Valid && capacity.Int < 1 {
+		return errs.ErrInvalidCapacity
+	}
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	c, err := lockCourse(tx, courseId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	c.EnrollMode = int8(mode)
+	c.Capacity = capacity
+	_, err = c.Update(context.Background(), tx, boil.Whitelist(models.CourseColumns.EnrollMode, models.CourseColumns.Capacity, models.CourseColumns.UpdatedAt))
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	err = fillFromWaitlist(tx, c)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return nil
+}
+
+// GetEnrollmentRequests takes the ID of a course and returns the users waiting to be admitted, pending requests first, each in the order they asked
+func GetEnrollmentRequests(db *sql.DB, courseId int) ([]*EnrollmentRequest, error) {
+	requests := []*EnrollmentRequest{}
+	err := queries.Raw(`SELECT er.user_id, er.course_id, u.firstname, u.surname, er.status, er.created_at FROM enrollment_request er
+		JOIN user u ON u.id = er.user_id
+		WHERE er.course_id = ? AND u.deleted_at IS NULL
+		ORDER BY er.status, er.created_at, er.user_id`, courseId).Bind(context.Background(), db, &requests)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range requests {
+		r.Status = requestStatus(r.RawStatus)
+	}
+
+	return requests, nil
+}
+
+// ApproveEnrollmentRequest takes the ID of a user and of a course they asked to enroll in and admits them.
+// If the course is full, the user is put on its waitlist instead.
+func ApproveEnrollmentRequest(db *sql.DB, userId int, courseId int) (EnrollmentStatus, error) {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	status, err := approveEnrollmentRequest(tx, userId, courseId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return "", fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return "", err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return "", fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return status, nil
+}
+
+func approveEnrollmentRequest(exec boil.ContextExecutor, userId int, courseId int) (EnrollmentStatus, error) {
+	var status int8
+	err := exec.QueryRow("SELECT status FROM enrollment_request WHERE user_id = ? AND course_id = ?", userId, courseId).Scan(&status)
+	if err != nil {
+		return "", err
+	}
+	// waitlisted users are approved already
+	if status != requestPending {
+		return "", sql.ErrNoRows
+	}
+
+	c, err := lockCourse(exec, courseId)
+	if err != nil {
+		return "", err
+	}
+	if c.ArchivedAt.Valid {
+		return "", errs.ErrCourseArchived
+	}
+
+	full, err := isFull(exec, c)
+	if err != nil {
+		return "", err
+	}
+	if full {
+		_, err = exec.Exec("UPDATE enrollment_request SET status = ? WHERE user_id = ? AND course_id = ?", requestWaitlisted, userId, courseId)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = notification.Create(exec, userId, fmt.Sprintf("You are on the waitlist of %s", c.Name), "Your request was approved, you will be enrolled as soon as a seat is free.", fmt.Sprintf("/courses/%d", courseId))
+		if err != nil {
+			return "", err
+		}
+		return StatusWaitlisted, nil
+	}
+
+	err = admitFromRequest(exec, userId, c)
+	if err != nil {
+		return "", err
+	}
+
+	return StatusEnrolled, nil
+}
+
+// RejectEnrollmentRequest takes the ID of a user and of a course they asked to enroll in and removes their request, including their place on the waitlist
+func RejectEnrollmentRequest(db *sql.DB, userId int, courseId int) error {
+	c, err := models.FindCourse(context.Background(), db, courseId)
+	if err != nil {
+		return err
+	}
+
+	res, err := db.Exec("DELETE FROM enrollment_request WHERE user_id = ? AND course_id = ?", userId, courseId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = notification.Create(db, userId, fmt.Sprintf("Your request to enroll in %s was rejected", c.Name), "", fmt.Sprintf("/courses/%d", courseId))
+	if err != nil {
+		log.Errorf("Unable to notify user about rejected enrollment request: %s", err.Error())
+	}
+
+	return nil
+}
+
+func requestStatus(status int8) EnrollmentStatus {
+	if status == requestWaitlisted {
+		return StatusWaitlisted
+	}
+	return StatusPending
+}
+
+// requestEnrollment stores that a user wants to enroll in a course. Asking again doesn't change the position on the waitlist.
+func requestEnrollment(exec boil.ContextExecutor, userId int, courseId int, status int8) (EnrollmentStatus, error) {
+	var existing int8
+	err := exec.QueryRow("SELECT status FROM enrollment_request WHERE user_id = ? AND course_id = ?", userId, courseId).Scan(&existing)
+	if err == nil {
+		return requestStatus(existing), nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	_, err = exec.Exec("INSERT INTO enrollment_request (user_id, course_id, status) VALUES (?, ?, ?)", userId, courseId, status)
+	if err != nil {
+		return "", err
+	}
+
+	return requestStatus(status), nil
+}
+
+// lockCourse returns a course and locks it until the end of the transaction, so that concurrent enrollments can't take more seats than it has.
+// Every transaction that admits users after checking isFull has to get the course this way.
+func lockCourse(exec boil.ContextExecutor, courseId int) (*models.Course, error) {
+	return models.Courses(models.CourseWhere.ID.EQ(courseId), qm.For("update")).One(context.Background(), exec)
+}
+
+// isFull checks whether all seats of a course are taken. Course admins and moderators don't take a seat.
+func isFull(exec boil.ContextExecutor, c *models.Course) (bool, error) {
+	if !c.Capacity.Valid {
+		return false, nil
+	}
+
+	enrolled, err := models.UserHasCourses(
+		models.UserHasCourseWhere.CourseID.EQ(c.ID),
+		models.UserHasCourseWhere.RoleID.EQ(dbi.CourseUserRoleId),
+	).Count(context.Background(), exec)
+	if err != nil {
+		return false, err
+	}
+
+	return enrolled >= int64(c.Capacity.Int), nil
+}
+
+// admit adds a user to a course as a regular user
+func admit(exec boil.ContextExecutor, userId int, courseId int) error {
+	var uhcs models.UserHasCourseSlice
+	// first check if relation already exists in the database and either insert a new row or reset deleted_at
+	err := queries.Raw("SELECT * FROM user_has_course WHERE course_id = ? AND user_id = ?", courseId, userId).Bind(context.Background(), exec, &uhcs)
+	if err != nil {
+		return err
+	}
+	if len(uhcs) > 0 {
+		// staff that was removed from the course comes back as a regular user
+		uhcs[0].RoleID = dbi.CourseUserRoleId
+		uhcs[0].DeletedAt = null.TimeFromPtr(nil)
+		_, err = uhcs[0].Update(context.Background(), exec, boil.Infer())
+		return err
+	}
+
+	uhc := models.UserHasCourse{UserID: userId, CourseID: courseId, RoleID: dbi.CourseUserRoleId}
+	return uhc.Insert(context.Background(), exec, boil.Infer())
+}
+
+// admitFromRequest admits a user that was waiting for approval or a free seat and tells them about it
+func admitFromRequest(exec boil.ContextExecutor, userId int, c *models.Course) error {
+	err := admit(exec, userId, c.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.Exec("DELETE FROM enrollment_request WHERE user_id = ? AND course_id = ?", userId, c.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = notification.Create(exec, userId, fmt.Sprintf("You are now enrolled in %s", c.Name), "", fmt.Sprintf("/courses/%d", c.ID))
+	return err
+}
+
+// fillFromWaitlist admits users from the waitlist of a course, first come first served, until it is full.
+// Archived courses don't admit anyone.
+func fillFromWaitlist(exec boil.ContextExecutor, c *models.Course) error {
+	if c.ArchivedAt.Valid {
+		return nil
+	}
+
+	for {
+		full, err := isFull(exec, c)
+		if err != nil || full {
+			return err
+		}
+
+		var userId int
+		err = exec.QueryRow(`SELECT user_id FROM enrollment_request WHERE course_id = ? AND status = ?
+			ORDER BY created_at, user_id LIMIT 1`, c.ID, requestWaitlisted).Scan(&userId)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = admitFromRequest(exec, userId, c)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package course
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// Maximum amount of rows a CSV file for bulk enrollment may have
+const maxCSVRows = 5000
+
+// Invite is a link that enrolls users in a course without the enroll key
+type Invite struct {
+	ID          int       `boil:"id" json:"id"`
+	CourseID    int       `boil:"course_id" json:"course_id"`
+	Token       string    `boil:"token" json:"token"`
+	RoleID      int       `boil:"role_id" json:"role_id"`
+	MaxUses     null.Int  `boil:"max_uses" json:"max_uses"`
+	Uses        int       `boil:"uses" json:"uses"`
+	ExpiresAt   null.Time `boil:"expires_at" json:"expires_at"`
+	CreatedByID int       `boil:"created_by_id" json:"created_by_id"`
+	CreatedAt   null.Time `boil:"created_at" json:"created_at"`
+}
+
+// CSVEnrollmentResult is the outcome of enrolling the user of a single row of a CSV file
+type CSVEnrollmentResult struct {
+	Row    int              `json:"row"`
+	Email  string           `json:"email"`
+	Status EnrollmentStatus `json:"status,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// newInviteToken generates a random token that is hard enough to guess to protect an invite
+func newInviteToken() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b[:]), nil
+}
+
+// CreateInvite takes the ID of a course, of the user creating the invite, the course role it grants, how often it can be used and when it expires and creates an invite.
+// Leaving maxUses or expiresAt empty makes the invite unlimited.
+func CreateInvite(db *sql.DB, courseId int, createdById int, roleId int, maxUses null.Int, expiresAt null.Time) (*Invite, error) {
+	// admins can only change by transferring the ownership
+	if roleId != dbi.CourseModeratorRoleId && roleId != dbi.CourseUserRoleId {
+		return nil, errs.ErrInvalidCourseRole
+	}
+	if maxUses.Valid && maxUses.Int < 1 {
+		return nil, errs.ErrInvalidInviteUses
+	}
+	if expiresAt.Valid && expiresAt.Time.Before(time.Now()) {
+		return nil, errs.ErrInviteExpiryPast
+	}
+
+	if _, err := models.FindCourse(context.Background(), db, courseId); err != nil {
+		return nil, err
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := db.Exec("INSERT INTO course_invite (course_id, token, role_id, max_uses, expires_at, created_by_id) VALUES (?, ?, ?, ?, ?, ?)",
+		courseId, token, roleId, maxUses, expiresAt, createdById)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return getInvite(db, "id = ?", id)
+}
+
+// GetInvites takes the ID of a course and returns its invites that weren't revoked, latest first
+func GetInvites(db *sql.DB, courseId int) ([]*Invite, error) {
+	invites := []*Invite{}
+	err := queries.Raw(`SELECT id, course_id, token, role_id, max_uses, uses, expires_at, created_by_id, created_at FROM course_invite
+		WHERE course_id = ? AND deleted_at IS NULL
+		ORDER BY created_at DESC, id DESC`, courseId).Bind(context.Background(), db, &invites)
+	if err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+// RevokeInvite takes the ID of a course and of one of its invites and stops the invite from working
+func RevokeInvite(db *sql.DB, courseId int, inviteId int) error {
+	res, err := db.Exec("UPDATE course_invite SET deleted_at = ? WHERE id = ? AND course_id = ? AND deleted_at IS NULL", time.Now(), inviteId, courseId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RedeemInvite takes the ID of a user and the token of an invite and enrolls the user in the course of the invite, with the role the invite grants.
+// Invites bypass the enroll key, enroll mode and approval, but users still need the prerequisites and a free seat to be enrolled as regular users.
+// Returns the ID of the course.
+func RedeemInvite(db *sql.DB, userId int, token string) (int, error) {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	courseId, err := redeemInvite(tx, userId, token)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return 0, err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return courseId, nil
+}
+
+func redeemInvite(exec boil.ContextExecutor, userId int, token string) (int, error) {
+	// lock the invite, so that concurrent redemptions can't exceed its uses
+	invite, err := getInvite(exec, "token = ? AND deleted_at IS NULL FOR UPDATE", token)
+	if err != nil {
+		return 0, err
+	}
+	if invite.ExpiresAt.Valid && invite.ExpiresAt.Time.Before(time.Now()) {
+		return 0, errs.ErrInviteExpired
+	}
+	if invite.MaxUses.Valid && invite.Uses >= invite.MaxUses.Int {
+		return 0, errs.ErrInviteUsedUp
+	}
+
+	c, err := lockCourse(exec, invite.CourseID)
+	if err != nil {
+		return 0, err
+	}
+	if c.ArchivedAt.Valid {
+		return 0, errs.ErrCourseArchived
+	}
+
+	uhc, err := models.FindUserHasCourse(context.Background(), exec, userId, c.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	// members that already have the role or a better one don't use up the invite
+	if uhc != nil && uhc.RoleID <= invite.RoleID {
+		return c.ID, nil
+	}
+
+	if invite.RoleID == dbi.CourseUserRoleId {
+		missing, err := GetMissingPrerequisites(exec, userId, c.ID)
+		if err != nil {
+			return 0, err
+		}
+		if len(missing) > 0 {
+			return 0, errs.ErrMissingPrerequisites
+		}
+
+		full, err := isFull(exec, c)
+		if err != nil {
+			return 0, err
+		}
+		if full {
+			return 0, errs.ErrCourseFull
+		}
+
+		err = admit(exec, userId, c.ID)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		err = setCourseRole(exec, invite.CreatedByID, userId, c.ID, invite.RoleID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// an earlier request is answered by the invite
+	_, err = exec.Exec("DELETE FROM enrollment_request WHERE user_id = ? AND course_id = ?", userId, c.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = exec.Exec("UPDATE course_invite SET uses = uses + 1 WHERE id = ?", invite.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.ID, nil
+}
+
+func getInvite(exec boil.ContextExecutor, where string, args ...interface{}) (*Invite, error) {
+	var invite Invite
+	err := queries.Raw(`SELECT id, course_id, token, role_id, max_uses, uses, expires_at, created_by_id, created_at FROM course_invite
+		WHERE `+where, args...).Bind(context.Background(), exec, &invite)
+	if err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+// EnrollUsersFromCSV takes the ID of a course and a CSV file of e-mail addresses and enrolls the corresponding users the same way `EnrollUser` does.
+// Only the enroll key is given, the enroll mode still applies: courses requiring approval get a pending request, full courses put the users
+// on their waitlist and closed or archived courses reject them, as do missing prerequisites.
+// The e-mail addresses are read from the column named "email" or, if there is no such header, the first column.
+// Every row is enrolled on its own, so that a failing row doesn't affect the others; the result tells the outcome of each row.
+func EnrollUsersFromCSV(db *sql.DB, courseId int, r io.Reader) ([]*CSVEnrollmentResult, error) {
+	c, err := models.FindCourse(context.Background(), db, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrInvalidCSV, err.Error())
+	}
+	if len(records) > maxCSVRows {
+		return nil, fmt.Errorf("%w: more than %d rows", errs.ErrInvalidCSV, maxCSVRows)
+	}
+
+	column, first := 0, 0
+	if len(records) > 0 {
+		for i, field := range records[0] {
+			name := strings.ToLower(strings.TrimSpace(field))
+			if name == "email" || name == "e-mail" || name == "mail" {
+				column, first = i, 1
+				break
+			}
+		}
+	}
+
+	results := []*CSVEnrollmentResult{}
+	seen := make(map[string]bool)
+	for i := first; i < len(records); i++ {
+		if column >= len(records[i]) {
+			continue
+		}
+		email := strings.TrimSpace(records[i][column])
+		if email == "" {
+			continue
+		}
+
+		result := &CSVEnrollmentResult{Row: i + 1, Email: email}
+		results = append(results, result)
+
+		if seen[strings.ToLower(email)] {
+			result.Error = "Duplicate e-mail address"
+			continue
+		}
+		seen[strings.ToLower(email)] = true
+
+		u, err := models.Users(models.UserWhere.Email.EQ(email)).One(context.Background(), db)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				result.Error = "No user with this e-mail address"
+			} else {
+				result.Error = err.Error()
+			}
+			continue
+		}
+
+		// the course's own key passes the key check, everything else is up to the enroll mode
+		_, status, err := EnrollUser(db, u.ID, c.ID, c.EnrollKey)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.Status = status
+	}
+
+	return results, nil
+}
@@ -0,0 +1,138 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// Prerequisite is a course whose certificate is required to enroll in another course
+type Prerequisite struct {
+	CourseID         int    `boil:"course_id" json:"course_id"`
+	RequiredCourseID int    `boil:"required_course_id" json:"required_course_id"`
+	Name             string `boil:"name" json:"name"`
+}
+
+// GetPrerequisites takes the ID of a course and returns all courses a certificate is required from to enroll in it
+func GetPrerequisites(db *sql.DB, courseId int) ([]*Prerequisite, error) {
+	prerequisites := []*Prerequisite{}
+	err := queries.Raw(`SELECT crc.course_id, crc.required_course_id, c.name FROM course_requires_course crc
+		JOIN course c ON c.id = crc.required_course_id
+		WHERE crc.course_id = ? AND c.deleted_at IS NULL
+		ORDER BY c.name`, courseId).Bind(context.Background(), db, &prerequisites)
+	if err != nil {
+		return nil, err
+	}
+
+	return prerequisites, nil
+}
+
+// requiresCourse checks whether enrolling in the course with the given ID transitively requires a certificate of the other one
+func requiresCourse(exec boil.ContextExecutor, courseId int, requiredCourseId int) (bool, error) {
+	visited := map[int]bool{courseId: true}
+	queue := []int{courseId}
+
+	for len(queue) > 0 {
+		var required []struct {
+			ID int `boil:"required_course_id"`
+		}
+		err := queries.Raw("SELECT required_course_id FROM course_requires_course WHERE course_id = ?", queue[0]).Bind(context.Background(), exec, &required)
+		if err != nil {
+			return false, err
+		}
+		queue = queue[1:]
+
+		for _, r := range required {
+			if r.ID == requiredCourseId {
+				return true, nil
+			}
+			if !visited[r.ID] {
+				visited[r.ID] = true
+				queue = append(queue, r.ID)
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// AddPrerequisite takes the ID of a course and of another course, whose certificate will be required to enroll in the first one
+func AddPrerequisite(db *sql.DB, courseId int, requiredCourseId int) error {
+	if courseId == requiredCourseId {
+		return errs.ErrPrerequisiteCycle
+	}
+
+	_, err := models.FindCourse(context.Background(), db, requiredCourseId)
+	if err != nil {
+		return err
+	}
+
+	// only a direct requirement exists already, one implied by another prerequisite can still be added
+	var existing struct {
+		Count int `boil:"count"`
+	}
+	err = queries.Raw("SELECT COUNT(*) AS count FROM course_requires_course WHERE course_id = ? AND required_course_id = ?", courseId, requiredCourseId).Bind(context.Background(), db, &existing)
+	if err != nil {
+		return err
+	}
+	if existing.Count > 0 {
+		return errs.ErrPrerequisiteExists
+	}
+
+	// the required course may not in turn require the course, as nobody could ever enroll in either of them
+	cycle, err := requiresCourse(db, requiredCourseId, courseId)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return errs.ErrPrerequisiteCycle
+	}
+
+	_, err = db.Exec("INSERT INTO course_requires_course (course_id, required_course_id) VALUES (?, ?)", courseId, requiredCourseId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemovePrerequisite takes the ID of a course and of one of its prerequisites and removes the requirement
+func RemovePrerequisite(db *sql.DB, courseId int, requiredCourseId int) error {
+	res, err := db.Exec("DELETE FROM course_requires_course WHERE course_id = ? AND required_course_id = ?", courseId, requiredCourseId)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetMissingPrerequisites takes the ID of a user and of a course and returns all prerequisites of the course the user doesn't hold a certificate for
+func GetMissingPrerequisites(exec boil.ContextExecutor, userId int, courseId int) ([]*Prerequisite, error) {
+	missing := []*Prerequisite{}
+	err := queries.Raw(`SELECT crc.course_id, crc.required_course_id, c.name FROM course_requires_course crc
+		JOIN course c ON c.id = crc.required_course_id
+		WHERE crc.course_id = ? AND c.deleted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM certificate cert
+			WHERE cert.user_id = ? AND cert.linked_course_id = crc.required_course_id AND cert.deleted_at IS NULL
+		)
+		ORDER BY c.name`, courseId, userId).Bind(context.Background(), exec, &missing)
+	if err != nil {
+		return nil, err
+	}
+
+	return missing, nil
+}
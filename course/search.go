@@ -0,0 +1,329 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"unicode"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// Maximum amount of courses on a page of search results
+const maxPageSize = 100
+
+// Maximum amount of courses found by the fulltext index that are scored for a search term
+const maxSearchCandidates = 1000
+
+// The columns of a SearchHit, taking the ID of the user role and of the searching user as arguments
+const searchColumns = `SELECT c.id, c.name, c.description, c.enroll_mode, c.capacity, c.archived_at,
+			(SELECT COUNT(*) FROM user_has_course u WHERE u.course_id = c.id AND u.role_id = ? AND u.deleted_at IS NULL) AS users,
+			EXISTS (SELECT 1 FROM user_has_course m WHERE m.course_id = c.id AND m.user_id = ? AND m.deleted_at IS NULL) AS enrolled`
+
+// Words shorter than this aren't part of the fulltext index. Longer words are searched for by this many of their first letters.
+const minSearchWordLength = 3
+
+// SearchQuery describes which courses to search for. Null filters aren't applied.
+type SearchQuery struct {
+	Term            string
+	FieldOfStudyID  null.Int
+	Semester        null.Int
+	OpenSeats       bool
+	Enrolled        null.Bool
+	IncludeArchived bool
+	Page            int
+	PageSize        int
+}
+
+// SearchHit is a course found by `SearchCourse`, without anything only members of the course should see
+type SearchHit struct {
+	ID          int         `boil:"id" json:"id"`
+	Name        string      `boil:"name" json:"name"`
+	Description null.String `boil:"description" json:"description"`
+	EnrollMode  int8        `boil:"enroll_mode" json:"enroll_mode"`
+	Capacity    null.Int    `boil:"capacity" json:"capacity"`
+	Users       int         `boil:"users" json:"users"`
+	Enrolled    bool        `boil:"enrolled" json:"enrolled"`
+	ArchivedAt  null.Time   `boil:"archived_at" json:"archived_at,omitempty"`
+	Score       float64     `boil:"-" json:"score"`
+}
+
+// SearchResult is a page of search results together with the amount of courses found in total
+type SearchResult struct {
+	Courses  []*SearchHit `json:"courses"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
+
+// SearchCourse takes the ID of the searching user and a query and returns the matching courses, best matches first.
+// Every word of the term has to appear in the name or the description of a course, but may contain typos or only be the beginning of a word.
+// The fulltext index finds the candidates by the first letters of the words, so typos there aren't found. The best candidates of the index
+// are ranked by how well they match, the pages and the total are taken from that ranking.
+// Without a term, all courses matching the filters are returned by name. Archived courses are only included if asked for.
+func SearchCourse(db *sql.DB, userId int, query SearchQuery) (*SearchResult, error) {
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+	if query.Page < 1 || query.PageSize < 1 || query.PageSize > maxPageSize {
+		return nil, errs.ErrInvalidPagination
+	}
+
+	terms := tokenize(query.Term)
+	match := fulltextQuery(terms)
+	if len(terms) > 0 && match == "" {
+		return nil, errs.ErrSearchTermTooShort
+	}
+
+	where := " FROM course c WHERE c.deleted_at IS NULL"
+	args := []interface{}{}
+	if match != "" {
+		where += " AND MATCH (c.name, c.description) AGAINST (? IN BOOLEAN MODE)"
+		args = append(args, match)
+	}
+	if !query.IncludeArchived {
+		where += " AND c.archived_at IS NULL"
+	}
+	if query.FieldOfStudyID.Valid || query.Semester.Valid {
+		where += " AND EXISTS (SELECT 1 FROM field_of_study_has_course fos WHERE fos.course_id = c.id"
+		if query.FieldOfStudyID.Valid {
+			where += " AND fos.field_of_study_id = ?"
+			args = append(args, query.FieldOfStudyID.Int)
+		}
+		if query.Semester.Valid {
+			where += " AND fos.semester = ?"
+			args = append(args, query.Semester.Int)
+		}
+		where += ")"
+	}
+	if query.OpenSeats {
+		where += " AND (c.capacity IS NULL OR c.capacity > (SELECT COUNT(*) FROM user_has_course s WHERE s.course_id = c.id AND s.role_id = ? AND s.deleted_at IS NULL))"
+		args = append(args, dbi.CourseUserRoleId)
+	}
+	if query.Enrolled.Valid {
+		if !query.Enrolled.Bool {
+			where += " AND NOT"
+		} else {
+			where += " AND"
+		}
+		where += " EXISTS (SELECT 1 FROM user_has_course e WHERE e.course_id = c.id AND e.user_id = ? AND e.deleted_at IS NULL)"
+		args = append(args, userId)
+	}
+
+	if match == "" {
+		return searchByName(db, userId, query, where, args)
+	}
+
+	// the typos are scored for all candidates found by the index, so that the ranking and the total are the same on every page
+	candidates := []*SearchHit{}
+	err := queries.Raw("SELECT c.id, c.name, c.description"+where+" ORDER BY MATCH (c.name, c.description) AGAINST (? IN BOOLEAN MODE) DESC, c.id LIMIT ?",
+		append(args, match, maxSearchCandidates)...).Bind(context.Background(), db, &candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []*SearchHit{}
+	for _, c := range candidates {
+		score, ok := scoreCourse(terms, c.Name, c.Description.String)
+		if !ok {
+			continue
+		}
+		c.Score = score
+		hits = append(hits, c)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Name != hits[j].Name {
+			return hits[i].Name < hits[j].Name
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	result := &SearchResult{Courses: []*SearchHit{}, Total: len(hits), Page: query.Page, PageSize: query.PageSize}
+	start := (query.Page - 1) * query.PageSize
+	if start >= len(hits) {
+		return result, nil
+	}
+	end := start + query.PageSize
+	if end > len(hits) {
+		end = len(hits)
+	}
+
+	ids := make([]interface{}, 0, end-start)
+	for _, h := range hits[start:end] {
+		ids = append(ids, h.ID)
+	}
+	page := []*SearchHit{}
+	err = queries.Raw(searchColumns+" FROM course c WHERE c.id IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")+")",
+		append([]interface{}{dbi.CourseUserRoleId, userId}, ids...)...).Bind(context.Background(), db, &page)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int]*SearchHit, len(page))
+	for _, h := range page {
+		byId[h.ID] = h
+	}
+	for _, h := range hits[start:end] {
+		if c, ok := byId[h.ID]; ok {
+			c.Score = h.Score
+			result.Courses = append(result.Courses, c)
+		}
+	}
+
+	return result, nil
+}
+
+// searchByName returns a page of all courses matching the filters, ordered by their name
+func searchByName(db *sql.DB, userId int, query SearchQuery, where string, args []interface{}) (*SearchResult, error) {
+	var total struct {
+		Count int `boil:"count"`
+	}
+	err := queries.Raw("SELECT COUNT(*) AS count"+where, args...).Bind(context.Background(), db, &total)
+	if err != nil {
+		return nil, err
+	}
+
+	courses := []*SearchHit{}
+	err = queries.Raw(searchColumns+where+" ORDER BY c.name, c.id LIMIT ? OFFSET ?",
+		append(append([]interface{}{dbi.CourseUserRoleId, userId}, args...), query.PageSize, (query.Page-1)*query.PageSize)...).Bind(context.Background(), db, &courses)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchResult{Courses: courses, Total: total.Count, Page: query.Page, PageSize: query.PageSize}, nil
+}
+
+// fulltextQuery turns the words of a search term into a query of the fulltext index in boolean mode, which requires the beginning of every word.
+// Words shorter than the index's minimum aren't part of the query.
+func fulltextQuery(terms []string) string {
+	var query strings.Builder
+	for _, t := range terms {
+		runes := []rune(t)
+		if len(runes) < minSearchWordLength {
+			continue
+		}
+		query.WriteString("+" + string(runes[:minSearchWordLength]) + "* ")
+	}
+
+	return strings.TrimSpace(query.String())
+}
+
+// tokenize splits a text into lower case words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// scoreCourse rates how well the name and description of a course match the words of a search term.
+// Matches in the name count three times as much as matches in the description. Returns false if a word doesn't match at all.
+func scoreCourse(terms []string, name string, description string) (float64, bool) {
+	nameWords := tokenize(name)
+	descriptionWords := tokenize(description)
+
+	score := 0.0
+	for _, t := range terms {
+		best := 3 * matchWords(t, nameWords)
+		if s := matchWords(t, descriptionWords); s > best {
+			best = s
+		}
+		if best == 0 {
+			return 0, false
+		}
+		score += best
+	}
+
+	// the whole term in the name is better than its words spread across it
+	if len(terms) > 1 && strings.Contains(strings.Join(nameWords, " "), strings.Join(terms, " ")) {
+		score += 1
+	}
+
+	return score, true
+}
+
+// matchWords returns how well a word of the search term matches the best of the given words: 1 if it is equal,
+// 0.8 if it is the beginning of the word, less the more typos it takes to get there and 0 if it doesn't match
+func matchWords(term string, words []string) float64 {
+	best := 0.0
+	for _, w := range words {
+		var s float64
+		switch {
+		case w == term:
+			s = 1
+		case len(term) > 1 && strings.HasPrefix(w, term):
+			s = 0.8
+		default:
+			if d := editDistance(term, w); d <= allowedTypos(term) {
+				s = 0.7 - 0.2*float64(d-1)
+			}
+		}
+		if s > best {
+			best = s
+		}
+	}
+
+	return best
+}
+
+// allowedTypos returns how many typos a word of a search term may contain, short words have to be exact
+func allowedTypos(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the amount of inserted, deleted, substituted or swapped adjacent characters that turn a into b
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// three rows suffice, as swaps only look back two characters
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,56 @@
+package course
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("algebra", "algebra"))
+	assert.Equal(t, 1, editDistance("algebra", "algerba"))
+	assert.Equal(t, 1, editDistance("algebra", "algbra"))
+	assert.Equal(t, 1, editDistance("übung", "ubung"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+	assert.Equal(t, 2, editDistance("statistik", "statsitk"))
+}
+
+func TestScoreCourse(t *testing.T) {
+	name, description := "Lineare Algebra I", "Vektorräume, Matrizen und lineare Abbildungen"
+
+	exact, ok := scoreCourse(tokenize("algebra"), name, description)
+	assert.True(t, ok)
+	typo, ok := scoreCourse(tokenize("algerba"), name, description)
+	assert.True(t, ok)
+	prefix, ok := scoreCourse(tokenize("alg"), name, description)
+	assert.True(t, ok)
+	inDescription, ok := scoreCourse(tokenize("matrizen"), name, description)
+	assert.True(t, ok)
+	assert.Greater(t, exact, prefix)
+	assert.Greater(t, prefix, typo)
+	assert.Greater(t, typo, inDescription)
+
+	phrase, ok := scoreCourse(tokenize("Lineare Algebra"), name, description)
+	assert.True(t, ok)
+	spread, ok := scoreCourse(tokenize("Algebra Lineare"), name, description)
+	assert.True(t, ok)
+	assert.Greater(t, phrase, spread)
+
+	_, ok = scoreCourse(tokenize("algebra analysis"), name, description)
+	assert.False(t, ok)
+	// short words have to be exact
+	_, ok = scoreCourse(tokenize("ix"), name, description)
+	assert.False(t, ok)
+
+	empty, ok := scoreCourse(tokenize(""), name, description)
+	assert.True(t, ok)
+	assert.Zero(t, empty)
+}
+
+func TestFulltextQuery(t *testing.T) {
+	assert.Equal(t, "+lin* +alg*", fulltextQuery(tokenize("Lineare Algerba")))
+	// words too short for the index are only scored
+	assert.Equal(t, "+ana*", fulltextQuery(tokenize("Analysis II")))
+	assert.Equal(t, "+übu*", fulltextQuery(tokenize("Übung")))
+	assert.Equal(t, "", fulltextQuery(tokenize("ix")))
+}
@@ -0,0 +1,244 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/notification"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// StaffMember is a user that administrates or moderates a course
+type StaffMember struct {
+	UserID    int    `boil:"user_id" json:"user_id"`
+	Firstname string `boil:"firstname" json:"firstname"`
+	Surname   string `boil:"surname" json:"surname"`
+	RoleID    int    `boil:"role_id" json:"role_id"`
+}
+
+// RoleChange is an entry of the log of role changes in a course
+type RoleChange struct {
+	ID          int       `boil:"id" json:"id"`
+	CourseID    int       `boil:"course_id" json:"course_id"`
+	UserID      int       `boil:"user_id" json:"user_id"`
+	ChangedByID int       `boil:"changed_by_id" json:"changed_by_id"`
+	OldRoleID   null.Int  `boil:"old_role_id" json:"old_role_id"`
+	NewRoleID   int       `boil:"new_role_id" json:"new_role_id"`
+	CreatedAt   null.Time `boil:"created_at" json:"created_at"`
+}
+
+// GetCourseStaff takes the ID of a course and returns its admin and moderators
+func GetCourseStaff(db *sql.DB, courseId int) ([]*StaffMember, error) {
+	staff := []*StaffMember{}
+	err := queries.Raw(`SELECT u.id AS user_id, u.firstname, u.surname, uhc.role_id FROM user_has_course uhc
+		JOIN user u ON u.id = uhc.user_id
+		WHERE uhc.course_id = ? AND uhc.role_id <= ? AND uhc.deleted_at IS NULL AND u.deleted_at IS NULL
+		ORDER BY uhc.role_id, u.surname, u.firstname`, courseId, dbi.CourseModeratorRoleId).Bind(context.Background(), db, &staff)
+	if err != nil {
+		return nil, err
+	}
+
+	return staff, nil
+}
+
+// GetRoleChanges takes the ID of a course and returns the log of its role changes, latest first
+func GetRoleChanges(db *sql.DB, courseId int) ([]*RoleChange, error) {
+	changes := []*RoleChange{}
+	err := queries.Raw(`SELECT id, course_id, user_id, changed_by_id, old_role_id, new_role_id, created_at FROM course_role_change
+		WHERE course_id = ?
+		ORDER BY created_at DESC, id DESC`, courseId).Bind(context.Background(), db, &changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// PromoteUser takes the ID of the user making the change, of a user enrolled in a course and of the course and makes the user a moderator (tutor) of the course.
+// Users that aren't enrolled in the course have to be invited with the moderator role instead. Roles in archived courses can't be changed.
+func PromoteUser(db *sql.DB, changedById int, userId int, courseId int) error {
+	return changeCourseRole(db, changedById, userId, courseId, dbi.CourseModeratorRoleId)
+}
+
+// DemoteUser takes the ID of the user making the change, of a moderator and of a course and makes the moderator a regular user of the course
+func DemoteUser(db *sql.DB, changedById int, userId int, courseId int) error {
+	return changeCourseRole(db, changedById, userId, courseId, dbi.CourseUserRoleId)
+}
+
+// TransferOwnership takes the ID of the user making the change, of a user enrolled in a course and of the course and makes the user the admin of the course.
+// The previous admin stays in the course as a moderator.
+func TransferOwnership(db *sql.DB, changedById int, userId int, courseId int) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := models.FindUserHasCourse(context.Background(), tx, userId, courseId); err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	admins, err := models.UserHasCourses(
+		models.UserHasCourseWhere.CourseID.EQ(courseId),
+		models.UserHasCourseWhere.RoleID.EQ(dbi.CourseAdminRoleId),
+	).All(context.Background(), tx)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	for _, admin := range admins {
+		if admin.UserID == userId {
+			continue
+		}
+
+		err = setCourseRole(tx, changedById, admin.UserID, courseId, dbi.CourseModeratorRoleId)
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+			return err
+		}
+	}
+
+	err = setCourseRole(tx, changedById, userId, courseId, dbi.CourseAdminRoleId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return nil
+}
+
+// changeCourseRole gives a user a role in a course other than admin, which can only be handed over by `TransferOwnership`
+func changeCourseRole(db *sql.DB, changedById int, userId int, courseId int, roleId int) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := dbi.CheckCourseWritable(tx, courseId); err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	uhc, err := models.FindUserHasCourse(context.Background(), tx, userId, courseId)
+	if err != nil && err != sql.ErrNoRows {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+	if uhc != nil && uhc.RoleID == dbi.CourseAdminRoleId {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return errs.ErrCourseAdminRole
+	}
+	// only enrolled users can be promoted or demoted, anyone else has to join the course first, e.g. by an invite
+	if uhc == nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return sql.ErrNoRows
+	}
+
+	err = setCourseRole(tx, changedById, userId, courseId, roleId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return nil
+}
+
+// setCourseRole gives a user a role in a course, adding them to the course if necessary and it isn't archived, and records the change.
+// Only invites may add users this way, as it skips the enroll mode, the prerequisites and the capacity of the course.
+// Setting the role a user already has is not recorded.
+func setCourseRole(exec boil.ContextExecutor, changedById int, userId int, courseId int, roleId int) error {
+	c, err := models.FindCourse(context.Background(), exec, courseId)
+	if err != nil {
+		return err
+	}
+	if _, err := models.FindUser(context.Background(), exec, userId); err != nil {
+		return err
+	}
+
+	var oldRoleId null.Int
+	// a user that left the course still has a deleted row, which is reused as in `EnrollUser`
+	var uhcs models.UserHasCourseSlice
+	err = queries.Raw("SELECT * FROM user_has_course WHERE course_id = ? AND user_id = ?", courseId, userId).Bind(context.Background(), exec, &uhcs)
+	if err != nil {
+		return err
+	}
+
+	// archived courses don't take new members, not even as staff
+	if c.ArchivedAt.Valid && (len(uhcs) == 0 || uhcs[0].DeletedAt.Valid) {
+		return errs.ErrCourseArchived
+	}
+
+	if len(uhcs) > 0 {
+		uhc := uhcs[0]
+		if !uhc.DeletedAt.Valid {
+			if uhc.RoleID == roleId {
+				return nil
+			}
+			oldRoleId = null.IntFrom(uhc.RoleID)
+		}
+
+		uhc.RoleID = roleId
+		uhc.DeletedAt = null.TimeFromPtr(nil)
+		_, err = uhc.Update(context.Background(), exec, boil.Infer())
+	} else {
+		uhc := models.UserHasCourse{UserID: userId, CourseID: courseId, RoleID: roleId}
+		err = uhc.Insert(context.Background(), exec, boil.Infer())
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.Exec("INSERT INTO course_role_change (course_id, user_id, changed_by_id, old_role_id, new_role_id) VALUES (?, ?, ?, ?, ?)",
+		courseId, userId, changedById, oldRoleId, roleId)
+	if err != nil {
+		return err
+	}
+
+	var title string
+	switch roleId {
+	case dbi.CourseAdminRoleId:
+		title = fmt.Sprintf("You are now the admin of %s", c.Name)
+	case dbi.CourseModeratorRoleId:
+		title = fmt.Sprintf("You are now a moderator of %s", c.Name)
+	default:
+		title = fmt.Sprintf("You are no longer a moderator of %s", c.Name)
+	}
+	_, err = notification.Create(exec, userId, title, "", fmt.Sprintf("/courses/%d", courseId))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
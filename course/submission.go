@@ -6,12 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
+	"learningbay24.de/backend/config"
 	"learningbay24.de/backend/dbi"
 	"learningbay24.de/backend/errs"
 	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/notification"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries/qm"
@@ -25,7 +29,53 @@ func GetSubmission(db *sql.DB, sid int) (*models.Submission, error) {
 	return s, nil
 }
 
-func CreateSubmission(db *sql.DB, name string, deadline string, cid int, maxfilesize int, visiblefrom string) (int, error) {
+// parseFileTypes takes comma separated file extensions, like "pdf, .zip", and returns them normalized, or null if there are none.
+// Only extensions allowed by the config can be allowed for a submission.
+func parseFileTypes(fileTypes string) (null.String, error) {
+	var types []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(fileTypes, ",") {
+		t = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(t), "."))
+		if t == "" || seen[t] {
+			continue
+		}
+
+		allowed := false
+		for _, e := range config.Conf.Files.AllowedFileTypes {
+			if t == e {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return null.String{}, fmt.Errorf("%w: %s", errs.ErrFileExtensionNotAllowed, t)
+		}
+
+		seen[t] = true
+		types = append(types, t)
+	}
+
+	if len(types) == 0 {
+		return null.String{}, nil
+	}
+	return null.StringFrom(strings.Join(types, ",")), nil
+}
+
+// SubmissionFilePolicy returns which files may be submitted to a submission, as specified by its allowed file types and maximum file size in MB
+func SubmissionFilePolicy(s *models.Submission) dbi.FilePolicy {
+	var p dbi.FilePolicy
+	if s.AllowedFileTypes.Valid {
+		p.AllowedFileTypes = strings.Split(s.AllowedFileTypes.String, ",")
+	}
+	if s.MaxFilesize > 0 {
+		p.MaxSize = int64(s.MaxFilesize) << 20
+	}
+	return p
+}
+
+// CreateSubmission creates a submission in a course. allowedFileTypes are the comma separated extensions of the files that may be submitted,
+// all file types allowed by the server if it is empty.
+func CreateSubmission(db *sql.DB, name string, deadline string, cid int, maxfilesize int, visiblefrom string, allowedFileTypes string) (int, error) {
 
 	var dtime null.Time
 	var parseddtime time.Time
@@ -68,7 +118,16 @@ func CreateSubmission(db *sql.DB, name string, deadline string, cid int, maxfile
 		}
 	}
 
-	s := &models.Submission{Name: name, Deadline: dtime, CourseID: cid, MaxFilesize: maxfilesize, VisibleFrom: vtime}
+	fileTypes, err := parseFileTypes(allowedFileTypes)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := dbi.CheckCourseWritable(db, cid); err != nil {
+		return 0, err
+	}
+
+	s := &models.Submission{Name: name, Deadline: dtime, CourseID: cid, MaxFilesize: maxfilesize, VisibleFrom: vtime, AllowedFileTypes: fileTypes}
 
 	// Inserts into database
 	err = s.Insert(context.Background(), db, boil.Infer())
@@ -79,7 +138,8 @@ func CreateSubmission(db *sql.DB, name string, deadline string, cid int, maxfile
 	return s.ID, nil
 }
 
-func EditSubmission(db *sql.DB, sid int, name string, deadline string, maxfilesize int, visiblefrom string) (int, error) {
+// EditSubmission changes a submission, see `CreateSubmission` for allowedFileTypes
+func EditSubmission(db *sql.DB, sid int, name string, deadline string, maxfilesize int, visiblefrom string, allowedFileTypes string) (int, error) {
 
 	var dtime null.Time
 	var parseddtime time.Time
@@ -122,6 +182,11 @@ func EditSubmission(db *sql.DB, sid int, name string, deadline string, maxfilesi
 		}
 	}
 
+	fileTypes, err := parseFileTypes(allowedFileTypes)
+	if err != nil {
+		return 0, err
+	}
+
 	// Begins the transaction
 	tx, err := db.BeginTx(context.Background(), nil)
 	if err != nil {
@@ -136,8 +201,10 @@ func EditSubmission(db *sql.DB, sid int, name string, deadline string, maxfilesi
 	s.Deadline = dtime
 	s.VisibleFrom = vtime
 	s.MaxFilesize = maxfilesize
+	s.AllowedFileTypes = fileTypes
 
-	_, err = s.Update(context.Background(), tx, boil.Infer())
+	// whether the users were notified is only changed by the scheduler
+	_, err = s.Update(context.Background(), tx, boil.Blacklist(models.SubmissionColumns.NotifiedAt))
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
 			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
@@ -174,14 +241,23 @@ func DeleteSubmission(db *sql.DB, sid int) (int, error) {
 }
 
 func CreateSubmissionHasFiles(db *sql.DB, submission_id int, fileName string, uri string, uploaderId int, local bool, file io.Reader, fileSize int) error {
-	tx, err := db.BeginTx(context.Background(), nil)
+	course_id, err := GetCourseIdBySubmission(db, submission_id)
 	if err != nil {
 		return err
 	}
+	if err := dbi.CheckCourseWritable(db, course_id); err != nil {
+		return err
+	}
+
+	// the file is saved before the transaction, as saving it may be rejected
 	file_id, err := dbi.SaveFile(db, fileName, uri, uploaderId, local, &file, fileSize)
 	if err != nil {
 		return err
 	}
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
 	_, err = tx.Exec("INSERT INTO submission_has_files(submission_id,file_id) VALUES (?,?);", submission_id, file_id)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
@@ -189,6 +265,13 @@ func CreateSubmissionHasFiles(db *sql.DB, submission_id int, fileName string, ur
 		}
 		return err
 	}
+	err = dbi.UpdateFileReferences(tx, file_id)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
 	if e := tx.Commit(); e != nil {
 		return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 	}
@@ -205,6 +288,7 @@ func DeleteSubmissionHasFiles(db *sql.DB, submission_id int, file_id int) error
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
+		return err
 	}
 
 	_, err = file.Delete(context.Background(), tx, false)
@@ -212,10 +296,21 @@ func DeleteSubmissionHasFiles(db *sql.DB, submission_id int, file_id int) error
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
+		return err
 	}
 
 	_, err = tx.Exec("DELETE FROM submission_has_files WHERE submission_id = ? AND file_id = ? ;", submission_id, file_id)
 	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+	err = dbi.UpdateFileReferences(tx, file_id)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
 		return err
 	}
 	if e := tx.Commit(); e != nil {
@@ -267,6 +362,13 @@ func CreateUserSubmission(db *sql.DB, name string, submitter_id int, submission_
 	} else {
 		nullname = null.NewString(name, true)
 	}
+	course_id, err := GetCourseIdBySubmission(db, submission_id)
+	if err != nil {
+		return 0, err
+	}
+	if err := dbi.CheckCourseWritable(db, course_id); err != nil {
+		return 0, err
+	}
 	if ignores_submission_deadline == 0 {
 		subm, err := models.FindSubmission(context.Background(), db, submission_id)
 		if err != nil {
@@ -365,12 +467,26 @@ func DeleteUserSubmission(db *sql.DB, user_submission_id int, user_id int) (int,
 	return uhassubmission.ID, nil
 }
 
+// CreateUserSubmissionHasFiles saves a file and adds it to a user submission. Only files the submission allows are accepted.
 func CreateUserSubmissionHasFiles(db *sql.DB, user_submission_id int, fileName string, uri string, uploaderId int, local bool, file io.Reader, fileSize int) error {
-	tx, err := db.BeginTx(context.Background(), nil)
+	us, err := GetUserSubmission(db, user_submission_id)
 	if err != nil {
 		return err
 	}
-	file_id, err := dbi.SaveFile(db, fileName, uri, uploaderId, local, &file, fileSize)
+	s, err := GetSubmission(db, us.SubmissionID)
+	if err != nil {
+		return err
+	}
+	if err := dbi.CheckCourseWritable(db, s.CourseID); err != nil {
+		return err
+	}
+
+	// the file is saved before the transaction, as saving it may be rejected
+	file_id, err := dbi.SaveFileWithPolicy(db, fileName, uri, uploaderId, local, &file, fileSize, SubmissionFilePolicy(s))
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(context.Background(), nil)
 	if err != nil {
 		return err
 	}
@@ -381,6 +497,13 @@ func CreateUserSubmissionHasFiles(db *sql.DB, user_submission_id int, fileName s
 		}
 		return err
 	}
+	err = dbi.UpdateFileReferences(tx, file_id)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
 	if e := tx.Commit(); e != nil {
 		return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 	}
@@ -406,6 +529,7 @@ func DeleteUserSubmissionHasFiles(db *sql.DB, user_submission_id int, file_id in
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
+		return err
 	}
 
 	_, err = file.Delete(context.Background(), tx, false)
@@ -413,10 +537,21 @@ func DeleteUserSubmissionHasFiles(db *sql.DB, user_submission_id int, file_id in
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
+		return err
 	}
 
 	_, err = tx.Exec("DELETE FROM user_submission_has_files WHERE user_submission_id = ? AND file_id = ? ;", user_submission_id, file_id)
 	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+	err = dbi.UpdateFileReferences(tx, file_id)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
 		return err
 	}
 	if e := tx.Commit(); e != nil {
@@ -446,6 +581,11 @@ func GradeUserSubmission(db *sql.DB, user_submission_id int, grade int) error {
 		return err
 	}
 
+	// failing to notify the user shouldn't undo the grading
+	if err := notification.NotifyUserSubmissionGraded(db, user_submission_id); err != nil {
+		log.Errorf("Unable to notify user about graded submission: %s", err.Error())
+	}
+
 	return nil
 }
 
@@ -483,6 +623,24 @@ func GetFileFromUserSubmission(db *sql.DB, user_submission_id int) (*models.File
 	return files, nil
 }
 
+// GetSubmissionFile returns a file of a submission, or sql.ErrNoRows if the submission doesn't have it
+func GetSubmissionFile(db *sql.DB, submission_id int, file_id int) (*models.File, error) {
+	return models.Files(
+		qm.InnerJoin("submission_has_files on submission_has_files.file_id = file.id"),
+		qm.Where("submission_has_files.submission_id = ?", submission_id),
+		models.FileWhere.ID.EQ(file_id),
+	).One(context.Background(), db)
+}
+
+// GetUserSubmissionFile returns a file of a user submission, or sql.ErrNoRows if the user submission doesn't have it
+func GetUserSubmissionFile(db *sql.DB, user_submission_id int, file_id int) (*models.File, error) {
+	return models.Files(
+		qm.InnerJoin("user_submission_has_files on user_submission_has_files.file_id = file.id"),
+		qm.Where("user_submission_has_files.user_submission_id = ?", user_submission_id),
+		models.FileWhere.ID.EQ(file_id),
+	).One(context.Background(), db)
+}
+
 func GetCourseIdBySubmission(db *sql.DB, submission_id int) (int, error) {
 	submission, err := GetSubmission(db, submission_id)
 	if err != nil {
@@ -0,0 +1,198 @@
+package courseexport
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"learningbay24.de/backend/errs"
+
+	"github.com/volatiletech/null/v8"
+)
+
+// Name of the manifest of an IMS Common Cartridge, e.g. exported by Moodle
+const cartridgeManifestName = "imsmanifest.xml"
+
+// Maximum size of the XML files of a cartridge, which are read into memory as a whole
+const maxCartridgeXMLSize = 16 << 20
+
+type ccManifest struct {
+	Title         string       `xml:"metadata>lom>general>title>string"`
+	Description   string       `xml:"metadata>lom>general>description>string"`
+	Organizations []ccItem     `xml:"organizations>organization>item"`
+	Resources     []ccResource `xml:"resources>resource"`
+}
+
+type ccItem struct {
+	IdentifierRef string   `xml:"identifierref,attr"`
+	Title         string   `xml:"title"`
+	Items         []ccItem `xml:"item"`
+}
+
+type ccResource struct {
+	Identifier string `xml:"identifier,attr"`
+	Type       string `xml:"type,attr"`
+	Href       string `xml:"href,attr"`
+	Files      []struct {
+		Href string `xml:"href,attr"`
+	} `xml:"file"`
+}
+
+// href returns the main file of a resource
+func (r *ccResource) href() string {
+	if r.Href != "" || len(r.Files) == 0 {
+		return r.Href
+	}
+	return r.Files[0].Href
+}
+
+type ccWebLink struct {
+	Title string `xml:"title"`
+	URL   struct {
+		Href string `xml:"href,attr"`
+	} `xml:"url"`
+}
+
+type ccTopic struct {
+	Title string `xml:"title"`
+	Text  string `xml:"text"`
+}
+
+// readCartridge converts an IMS Common Cartridge into a manifest. Modules become directories, web content becomes materials,
+// web links become remote files and discussion topics become forum threads; other resources, like quizzes, are skipped.
+func readCartridge(entries map[string]*zip.File) (*Manifest, error) {
+	var cc ccManifest
+	if err := readXML(entries[cartridgeManifestName], &cc); err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string]*ccResource, len(cc.Resources))
+	for i := range cc.Resources {
+		resources[cc.Resources[i].Identifier] = &cc.Resources[i]
+	}
+
+	m := &Manifest{Version: manifestVersion, ExportedAt: time.Now()}
+	m.Course.Name = strings.TrimSpace(cc.Title)
+	if d := strings.TrimSpace(cc.Description); d != "" {
+		m.Course.Description = null.StringFrom(d)
+	}
+
+	var walk func(items []ccItem, directoryId null.Int) error
+	walk = func(items []ccItem, directoryId null.Int) error {
+		for _, item := range items {
+			title := strings.TrimSpace(item.Title)
+
+			if len(item.Items) > 0 {
+				// the root item of an organization usually has no title and only groups the modules
+				id := directoryId
+				if title != "" {
+					d := &Directory{ID: len(m.Directories) + 1, ParentID: directoryId, Name: title, VisibleFrom: m.ExportedAt}
+					m.Directories = append(m.Directories, d)
+					id = null.IntFrom(d.ID)
+				}
+
+				if err := walk(item.Items, id); err != nil {
+					return err
+				}
+				continue
+			}
+
+			r, ok := resources[item.IdentifierRef]
+			if !ok {
+				continue
+			}
+			if err := addResource(m, entries, r, title, directoryId); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(cc.Organizations, null.Int{}); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// addResource adds a resource of a cartridge to the manifest, using title as its name
+func addResource(m *Manifest, entries map[string]*zip.File, r *ccResource, title string, directoryId null.Int) error {
+	href := r.href()
+	if href == "" {
+		return nil
+	}
+
+	switch {
+	case r.Type == "webcontent":
+		if _, ok := entries[href]; !ok {
+			return fmt.Errorf("%w: %s is missing", errs.ErrInvalidArchive, href)
+		}
+
+		// materials are named like uploads, with an extension
+		name := title
+		if name == "" {
+			name = path.Base(href)
+		} else if path.Ext(name) == "" {
+			name += path.Ext(href)
+		}
+		m.Materials = append(m.Materials, &File{Name: name, Path: href, DirectoryID: directoryId})
+	case strings.HasPrefix(r.Type, "imswl_"):
+		var link ccWebLink
+		if err := readXML(entries[href], &link); err != nil {
+			return err
+		}
+		if link.URL.Href == "" {
+			return nil
+		}
+
+		name := strings.TrimSpace(link.Title)
+		if name == "" {
+			name = title
+		}
+		m.Materials = append(m.Materials, &File{Name: name, URI: link.URL.Href, DirectoryID: directoryId})
+	case strings.HasPrefix(r.Type, "imsdt_"):
+		var topic ccTopic
+		if err := readXML(entries[href], &topic); err != nil {
+			return err
+		}
+
+		subject := strings.TrimSpace(topic.Title)
+		if subject == "" {
+			subject = title
+		}
+		content := strings.TrimSpace(topic.Text)
+		// forum entries can't be empty
+		if subject == "" || content == "" {
+			return nil
+		}
+		m.Forum = append(m.Forum, &ForumEntry{ID: len(m.Forum) + 1, Subject: subject, Content: content, CreatedAt: m.ExportedAt})
+	}
+
+	return nil
+}
+
+func readXML(zf *zip.File, v interface{}) error {
+	if zf == nil {
+		return fmt.Errorf("%w: referenced file is missing", errs.ErrInvalidArchive)
+	}
+	if zf.UncompressedSize64 > maxCartridgeXMLSize {
+		return fmt.Errorf("%w: %s is too large", errs.ErrInvalidArchive, zf.Name)
+	}
+
+	rc, err := zf.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s", errs.ErrInvalidArchive, err.Error())
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxCartridgeXMLSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %s", errs.ErrInvalidArchive, zf.Name, err.Error())
+	}
+
+	return nil
+}
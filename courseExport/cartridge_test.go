@@ -0,0 +1,111 @@
+package courseexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"learningbay24.de/backend/errs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/volatiletech/null/v8"
+)
+
+const testCartridgeManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest identifier="cctd0001" xmlns="http://www.imsglobal.org/xsd/imsccv1p1/imscp_v1p1" xmlns:lomimscc="http://ltsc.ieee.org/xsd/imsccv1p1/LOM/manifest">
+  <metadata>
+    <schema>IMS Common Cartridge</schema>
+    <lomimscc:lom>
+      <lomimscc:general>
+        <lomimscc:title><lomimscc:string>Algorithms</lomimscc:string></lomimscc:title>
+      </lomimscc:general>
+    </lomimscc:lom>
+  </metadata>
+  <organizations>
+    <organization identifier="org" structure="rooted-hierarchy">
+      <item identifier="root">
+        <item identifier="week1">
+          <title>Week 1</title>
+          <item identifier="i1" identifierref="r1"><title>Slides</title></item>
+          <item identifier="i2" identifierref="r2"><title>Visualizations</title></item>
+        </item>
+        <item identifier="i3" identifierref="r3"><title>Introductions</title></item>
+        <item identifier="i4" identifierref="r4"><title>Quiz</title></item>
+      </item>
+    </organization>
+  </organizations>
+  <resources>
+    <resource identifier="r1" type="webcontent" href="web_resources/slides.pdf"><file href="web_resources/slides.pdf"/></resource>
+    <resource identifier="r2" type="imswl_xmlv1p1"><file href="r2.xml"/></resource>
+    <resource identifier="r3" type="imsdt_xmlv1p1"><file href="r3.xml"/></resource>
+    <resource identifier="r4" type="imsqti_xmlv1p2/imscc_xmlv1p1/assessment"><file href="r4.xml"/></resource>
+  </resources>
+</manifest>`
+
+func TestReadCartridge(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		cartridgeManifestName:      testCartridgeManifest,
+		"web_resources/slides.pdf": "%PDF-1.4",
+		"r2.xml":                   `<webLink xmlns="http://www.imsglobal.org/xsd/imsccv1p1/imswl_v1p1"><title>VisuAlgo</title><url href="https://visualgo.net"/></webLink>`,
+		"r3.xml":                   `<topic xmlns="http://www.imsglobal.org/xsd/imsccv1p1/imsdt_v1p1"><title>Introduce yourself</title><text texttype="text/plain">Who are you?</text></topic>`,
+	} {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	m, err := readCartridge(entries)
+	assert.NoError(t, err)
+	assert.Equal(t, "Algorithms", m.Course.Name)
+	assert.Len(t, m.Directories, 1)
+	assert.Equal(t, "Week 1", m.Directories[0].Name)
+	assert.False(t, m.Directories[0].ParentID.Valid)
+	assert.Equal(t, []*File{
+		{Name: "Slides.pdf", Path: "web_resources/slides.pdf", DirectoryID: null.IntFrom(1)},
+		{Name: "VisuAlgo", URI: "https://visualgo.net", DirectoryID: null.IntFrom(1)},
+	}, m.Materials)
+	assert.Len(t, m.Forum, 1)
+	assert.Equal(t, "Introduce yourself", m.Forum[0].Subject)
+	assert.Equal(t, "Who are you?", m.Forum[0].Content)
+	assert.NoError(t, validateManifest(m))
+
+	delete(entries, "web_resources/slides.pdf")
+	_, err = readCartridge(entries)
+	assert.ErrorIs(t, err, errs.ErrInvalidArchive)
+}
+
+func TestValidateManifest(t *testing.T) {
+	m := &Manifest{
+		Directories: []*Directory{
+			{ID: 1, Name: "a", ParentID: null.IntFrom(2)},
+			{ID: 2, Name: "b", ParentID: null.IntFrom(1)},
+		},
+	}
+	assert.ErrorIs(t, validateManifest(m), errs.ErrInvalidArchive)
+
+	m.Directories[1].ParentID = null.IntFrom(3)
+	assert.NoError(t, validateManifest(m))
+
+	m.Forum = []*ForumEntry{
+		{ID: 2, InReplyTo: null.IntFrom(1), Subject: "Re: Question", Content: "Answer"},
+		{ID: 1, Subject: "Question", Content: "?"},
+	}
+	assert.ErrorIs(t, validateManifest(m), errs.ErrInvalidArchive)
+
+	m.Forum[0], m.Forum[1] = m.Forum[1], m.Forum[0]
+	assert.NoError(t, validateManifest(m))
+
+	m.Materials = []*File{{Name: "empty"}}
+	assert.ErrorIs(t, validateManifest(m), errs.ErrInvalidArchive)
+}
@@ -0,0 +1,203 @@
+package courseexport
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// A file linked to a course, submission or exam, together with the directory it is in, if any
+type linkedFile struct {
+	ID          int      `boil:"id"`
+	Name        string   `boil:"name"`
+	URI         string   `boil:"uri"`
+	Local       int8     `boil:"local"`
+	BlobID      null.Int `boil:"blob_id"`
+	DirectoryID null.Int `boil:"directory_id"`
+}
+
+// GetManifest takes the ID of a course and describes it and all of its contents, except for its users and what they submitted
+func GetManifest(db *sql.DB, courseId int) (*Manifest, error) {
+	c, err := models.FindCourse(context.Background(), db, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		Version:    manifestVersion,
+		ExportedAt: time.Now(),
+		Course:     Course{Name: c.Name, Description: c.Description, EnrollKey: c.EnrollKey, EnrollMode: c.EnrollMode, Capacity: c.Capacity},
+	}
+	// every local file gets its own path, so that files with the same name don't collide
+	count := 0
+	toFiles := func(linked []*linkedFile) []*File {
+		files := make([]*File, 0, len(linked))
+		for _, l := range linked {
+			f := &File{Name: l.Name, DirectoryID: l.DirectoryID}
+			if l.Local == 1 {
+				count++
+				f.Path = fmt.Sprintf("%s%d/%s", filesDir, count, path.Base(l.Name))
+				f.source = &models.File{ID: l.ID, Local: l.Local, BlobID: l.BlobID}
+			} else {
+				f.URI = l.URI
+			}
+			files = append(files, f)
+		}
+		return files
+	}
+
+	directories, err := models.Directories(models.DirectoryWhere.CourseID.EQ(c.ID), qm.OrderBy(models.DirectoryColumns.ID)).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range directories {
+		m.Directories = append(m.Directories, &Directory{ID: d.ID, ParentID: d.ParentID, Name: d.Name, VisibleFrom: d.VisibleFrom})
+	}
+
+	var materials []*linkedFile
+	err = queries.Raw(`SELECT f.id, f.name, f.uri, f.local, f.blob_id, d.id AS directory_id FROM file f
+		JOIN course_has_files chf ON chf.file_id = f.id
+		LEFT JOIN directory_has_files dhf ON dhf.file_id = f.id
+		LEFT JOIN directory d ON d.id = dhf.directory_id AND d.course_id = chf.course_id AND d.deleted_at IS NULL
+		WHERE chf.course_id = ? AND chf.deleted_at IS NULL AND f.deleted_at IS NULL
+		ORDER BY f.id`, c.ID).Bind(context.Background(), db, &materials)
+	if err != nil {
+		return nil, err
+	}
+	m.Materials = toFiles(materials)
+
+	submissions, err := models.Submissions(models.SubmissionWhere.CourseID.EQ(c.ID), qm.OrderBy(models.SubmissionColumns.ID)).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range submissions {
+		linked, err := getLinkedFiles(db, "submission_has_files", "submission_id", s.ID)
+		if err != nil {
+			return nil, err
+		}
+		m.Submissions = append(m.Submissions, &Submission{Name: s.Name, Deadline: s.Deadline, MaxFilesize: s.MaxFilesize, VisibleFrom: s.VisibleFrom, AllowedFileTypes: s.AllowedFileTypes, Files: toFiles(linked)})
+	}
+
+	exams, err := models.Exams(models.ExamWhere.CourseID.EQ(c.ID), qm.OrderBy(models.ExamColumns.ID)).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+	for _, ex := range exams {
+		linked, err := getLinkedFiles(db, "exam_has_files", "exam_id", ex.ID)
+		if err != nil {
+			return nil, err
+		}
+		m.Exams = append(m.Exams, &Exam{
+			Name:               ex.Name,
+			Description:        ex.Description,
+			Date:               ex.Date,
+			Duration:           ex.Duration,
+			Online:             ex.Online,
+			Location:           ex.Location,
+			RegisterDeadline:   ex.RegisterDeadline,
+			DeregisterDeadline: ex.DeregisterDeadline,
+			Files:              toFiles(linked),
+		})
+	}
+
+	err = queries.Raw("SELECT id, repeat_distance FROM appointment_series WHERE course_id = ? AND deleted_at IS NULL ORDER BY id", c.ID).Bind(context.Background(), db, &m.Series)
+	if err != nil {
+		return nil, err
+	}
+
+	appointments, err := models.Appointments(models.AppointmentWhere.CourseID.EQ(c.ID), qm.OrderBy(models.AppointmentColumns.Date)).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range appointments {
+		m.Appointments = append(m.Appointments, &Appointment{Date: a.Date, Duration: a.Duration, Location: a.Location, Online: a.Online, SeriesID: a.SeriesID})
+	}
+
+	err = queries.Raw(`SELECT fe.id, fe.in_reply_to, fe.subject, fe.content, CONCAT(u.firstname, ' ', u.surname) AS author, fe.created_at FROM forum_entry fe
+		JOIN user u ON u.id = fe.author_id
+		WHERE fe.forum_id = ? AND fe.deleted_at IS NULL
+		ORDER BY fe.id`, c.ForumID).Bind(context.Background(), db, &m.Forum)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// getLinkedFiles returns the files that aren't deleted and are linked to the entity with the given ID through the given table
+func getLinkedFiles(db *sql.DB, table string, column string, id int) ([]*linkedFile, error) {
+	var files []*linkedFile
+	err := queries.Raw(fmt.Sprintf(`SELECT f.id, f.name, f.uri, f.local, f.blob_id FROM file f
+		JOIN %s l ON l.file_id = f.id
+		WHERE l.%s = ? AND f.deleted_at IS NULL
+		ORDER BY f.id`, table, column), id).Bind(context.Background(), db, &files)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// WriteArchive writes a zip archive of the manifest together with the contents of its local files to w
+func WriteArchive(db *sql.DB, w io.Writer, m *Manifest) error {
+	zw := zip.NewWriter(w)
+
+	mw, err := zw.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+
+	files := m.Materials
+	for _, s := range m.Submissions {
+		files = append(files, s.Files...)
+	}
+	for _, ex := range m.Exams {
+		files = append(files, ex.Files...)
+	}
+
+	for _, f := range files {
+		if f.Path == "" {
+			continue
+		}
+
+		err = writeFile(db, zw, f)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeFile(db *sql.DB, zw *zip.Writer, f *File) error {
+	src, err := dbi.OpenFile(db, f.source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	fw, err := zw.Create(f.Path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fw, src)
+	return err
+}
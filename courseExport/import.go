@@ -0,0 +1,359 @@
+package courseexport
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// Maximum size of a manifest, which is read into memory as a whole
+const maxManifestSize = 16 << 20
+
+// ImportCourse takes the ID of a user, an archive written by `WriteArchive` or an IMS Common Cartridge and a name and recreates the course of the archive with the user as its admin.
+// If name is empty, the course keeps the name from the archive. Forum entries are attributed to the importing user, as users aren't part of an archive.
+// Returns the ID of the new course.
+func ImportCourse(db *sql.DB, userId int, r io.ReaderAt, size int64, name string) (int, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errs.ErrInvalidArchive, err.Error())
+	}
+
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	var m *Manifest
+	switch {
+	case entries[manifestName] != nil:
+		m, err = readManifest(entries[manifestName])
+	case entries[cartridgeManifestName] != nil:
+		m, err = readCartridge(entries)
+	default:
+		err = fmt.Errorf("%w: neither %s nor %s found", errs.ErrInvalidArchive, manifestName, cartridgeManifestName)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if name != "" {
+		m.Course.Name = name
+	}
+	if m.Course.Name == "" {
+		return 0, errs.ErrEmptyName
+	}
+	if err := validateManifest(m); err != nil {
+		return 0, err
+	}
+
+	// files are saved on their own, so they have to be removed again if the course can't be created
+	fileIds := make(map[*File]int)
+	removeFiles := func() {
+		for _, id := range fileIds {
+			// NOTE: disregard error, only god can help us now
+			_ = dbi.DeleteFile(db, id)
+		}
+	}
+
+	for _, f := range m.files() {
+		id, err := saveFile(db, userId, f, entries)
+		if err != nil {
+			removeFiles()
+			return 0, err
+		}
+		fileIds[f] = id
+	}
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		removeFiles()
+		return 0, err
+	}
+
+	id, err := importManifest(tx, userId, m, fileIds)
+	if err != nil {
+		removeFiles()
+		if e := tx.Rollback(); e != nil {
+			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return 0, err
+	}
+
+	if e := tx.Commit(); e != nil {
+		removeFiles()
+		return 0, fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	return id, nil
+}
+
+func readManifest(zf *zip.File) (*Manifest, error) {
+	if zf.UncompressedSize64 > maxManifestSize {
+		return nil, fmt.Errorf("%w: manifest is too large", errs.ErrInvalidArchive)
+	}
+
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrInvalidArchive, err.Error())
+	}
+	defer rc.Close()
+
+	var m Manifest
+	if err := json.NewDecoder(io.LimitReader(rc, maxManifestSize)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrInvalidArchive, err.Error())
+	}
+	if m.Version < 1 || m.Version > manifestVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errs.ErrInvalidArchive, m.Version)
+	}
+
+	return &m, nil
+}
+
+// files returns all files of the manifest, wherever they are used
+func (m *Manifest) files() []*File {
+	files := append([]*File{}, m.Materials...)
+	for _, s := range m.Submissions {
+		files = append(files, s.Files...)
+	}
+	for _, ex := range m.Exams {
+		files = append(files, ex.Files...)
+	}
+
+	return files
+}
+
+// saveFile saves a file of the manifest like an upload of the user, reading the content of local files from the archive
+func saveFile(db *sql.DB, userId int, f *File, entries map[string]*zip.File) (int, error) {
+	if f.Path == "" {
+		var file io.Reader
+		return dbi.SaveFile(db, f.Name, f.URI, userId, false, &file, 0)
+	}
+
+	zf, ok := entries[f.Path]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is missing", errs.ErrInvalidArchive, f.Path)
+	}
+
+	rc, err := zf.Open()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errs.ErrInvalidArchive, err.Error())
+	}
+	defer rc.Close()
+
+	// the name on disk only depends on the path, which can't point outside of the files directory this way
+	var file io.Reader = rc
+	return dbi.SaveFile(db, path.Base(f.Path), "", userId, true, &file, int(zf.UncompressedSize64))
+}
+
+func importManifest(exec boil.ContextExecutor, userId int, m *Manifest, fileIds map[*File]int) (int, error) {
+	f := &models.Forum{Name: m.Course.Name}
+	err := f.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	c := &models.Course{Name: m.Course.Name, Description: m.Course.Description, EnrollKey: m.Course.EnrollKey, ForumID: f.ID, EnrollMode: m.Course.EnrollMode, Capacity: m.Course.Capacity}
+	err = c.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	uhc := models.UserHasCourse{UserID: userId, CourseID: c.ID, RoleID: dbi.CourseAdminRoleId}
+	err = uhc.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	// directories are created first and nested afterwards, as a parent may come after its children
+	directories := make(map[int]*models.Directory, len(m.Directories))
+	for _, d := range m.Directories {
+		dir := &models.Directory{Name: d.Name, CourseID: c.ID, VisibleFrom: d.VisibleFrom}
+		err = dir.Insert(context.Background(), exec, boil.Infer())
+		if err != nil {
+			return 0, err
+		}
+		directories[d.ID] = dir
+	}
+
+	for _, d := range m.Directories {
+		if !d.ParentID.Valid {
+			continue
+		}
+		// unknown parents leave the directory at the top level
+		parent, ok := directories[d.ParentID.Int]
+		if !ok {
+			continue
+		}
+
+		dir := directories[d.ID]
+		dir.ParentID = null.IntFrom(parent.ID)
+		_, err = dir.Update(context.Background(), exec, boil.Whitelist(models.DirectoryColumns.ParentID))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, file := range m.Materials {
+		err = linkFile(exec, file, fileIds[file], "course_has_files", "course_id", c.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		if d, ok := directories[file.DirectoryID.Int]; ok && file.DirectoryID.Valid {
+			_, err = exec.Exec("INSERT INTO directory_has_files (directory_id, file_id) VALUES (?, ?)", d.ID, fileIds[file])
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for _, s := range m.Submissions {
+		sub := &models.Submission{Name: s.Name, Deadline: s.Deadline, CourseID: c.ID, MaxFilesize: s.MaxFilesize, VisibleFrom: s.VisibleFrom, AllowedFileTypes: s.AllowedFileTypes}
+		err = sub.Insert(context.Background(), exec, boil.Infer())
+		if err != nil {
+			return 0, err
+		}
+
+		for _, file := range s.Files {
+			err = linkFile(exec, file, fileIds[file], "submission_has_files", "submission_id", sub.ID)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for _, ex := range m.Exams {
+		e := &models.Exam{
+			Name:               ex.Name,
+			Description:        ex.Description,
+			Date:               ex.Date,
+			Duration:           ex.Duration,
+			Online:             ex.Online,
+			Location:           ex.Location,
+			CourseID:           c.ID,
+			CreatorID:          userId,
+			RegisterDeadline:   ex.RegisterDeadline,
+			DeregisterDeadline: ex.DeregisterDeadline,
+		}
+		err = e.Insert(context.Background(), exec, boil.Infer())
+		if err != nil {
+			return 0, err
+		}
+
+		for _, file := range ex.Files {
+			err = linkFile(exec, file, fileIds[file], "exam_has_files", "exam_id", e.ID)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	series := make(map[int]int, len(m.Series))
+	for _, s := range m.Series {
+		res, err := exec.Exec("INSERT INTO appointment_series (course_id, repeat_distance) VALUES (?, ?)", c.ID, s.RepeatDistance)
+		if err != nil {
+			return 0, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		series[s.ID] = int(id)
+	}
+
+	for _, a := range m.Appointments {
+		appointment := &models.Appointment{Date: a.Date, Location: a.Location, Online: a.Online, CourseID: c.ID, Duration: a.Duration}
+		if id, ok := series[a.SeriesID.Int]; ok && a.SeriesID.Valid {
+			appointment.SeriesID = null.IntFrom(id)
+		}
+		err = appointment.Insert(context.Background(), exec, boil.Infer())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// replies come after the entry they reply to, see `validateManifest`
+	entries := make(map[int]int, len(m.Forum))
+	for _, e := range m.Forum {
+		entry := &models.ForumEntry{Subject: e.Subject, Content: e.Content, AuthorID: userId, ForumID: f.ID, CreatedAt: e.CreatedAt}
+		if e.InReplyTo.Valid {
+			entry.InReplyTo = null.IntFrom(entries[e.InReplyTo.Int])
+		}
+
+		err = entry.Insert(context.Background(), exec, boil.Infer())
+		if err != nil {
+			return 0, err
+		}
+		entries[e.ID] = entry.ID
+	}
+
+	return c.ID, nil
+}
+
+// linkFile links a saved file to the entity with the given ID through the given table and gives it the name it has in the manifest
+func linkFile(exec boil.ContextExecutor, file *File, fileId int, table string, column string, id int) error {
+	_, err := exec.Exec(fmt.Sprintf("INSERT INTO %s (%s, file_id) VALUES (?, ?)", table, column), id, fileId)
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.Exec("UPDATE file SET name = ? WHERE id = ?", file.Name, fileId)
+	if err != nil {
+		return err
+	}
+
+	err = dbi.RenameIndexedFile(exec, fileId, file.Name)
+	if err != nil {
+		return err
+	}
+
+	return dbi.UpdateFileReferences(exec, fileId)
+}
+
+// validateManifest checks the references inside of a manifest that can't be fixed on import:
+// no directory may be contained in itself, every file needs content and forum entries may only reply to entries that come before them.
+func validateManifest(m *Manifest) error {
+	parents := make(map[int]null.Int, len(m.Directories))
+	for _, d := range m.Directories {
+		parents[d.ID] = d.ParentID
+	}
+
+	for _, d := range m.Directories {
+		seen := map[int]bool{d.ID: true}
+		for p := d.ParentID; p.Valid; p = parents[p.Int] {
+			if seen[p.Int] {
+				return fmt.Errorf("%w: directory %d is contained in itself", errs.ErrInvalidArchive, d.ID)
+			}
+			seen[p.Int] = true
+		}
+	}
+
+	for _, f := range m.files() {
+		if f.Path == "" && f.URI == "" {
+			return fmt.Errorf("%w: file %q has neither a path nor an URI", errs.ErrInvalidArchive, f.Name)
+		}
+	}
+
+	entries := make(map[int]bool, len(m.Forum))
+	for _, e := range m.Forum {
+		if e.Subject == "" || e.Content == "" {
+			return fmt.Errorf("%w: forum entry %d is empty", errs.ErrInvalidArchive, e.ID)
+		}
+		if e.InReplyTo.Valid && !entries[e.InReplyTo.Int] {
+			return fmt.Errorf("%w: forum entry %d replies to unknown entry %d", errs.ErrInvalidArchive, e.ID, e.InReplyTo.Int)
+		}
+		entries[e.ID] = true
+	}
+
+	return nil
+}
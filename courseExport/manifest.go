@@ -0,0 +1,104 @@
+// Package courseexport moves courses between LearningBay24 instances as zip archives
+package courseexport
+
+import (
+	"time"
+
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+)
+
+// Version of the archive format written by `WriteArchive`
+const manifestVersion = 1
+
+// Name of the manifest inside of an archive
+const manifestName = "course.json"
+
+// Directory inside of an archive that holds the contents of local files
+const filesDir = "files/"
+
+// Manifest describes a course and everything in it except its users. IDs are only valid inside of the manifest and are replaced on import.
+type Manifest struct {
+	Version      int            `json:"version"`
+	ExportedAt   time.Time      `json:"exported_at"`
+	Course       Course         `json:"course"`
+	Directories  []*Directory   `json:"directories"`
+	Materials    []*File        `json:"materials"`
+	Submissions  []*Submission  `json:"submissions"`
+	Exams        []*Exam        `json:"exams"`
+	Series       []*Series      `json:"appointment_series"`
+	Appointments []*Appointment `json:"appointments"`
+	Forum        []*ForumEntry  `json:"forum"`
+}
+
+type Course struct {
+	Name        string      `json:"name"`
+	Description null.String `json:"description"`
+	EnrollKey   string      `json:"enroll_key"`
+	EnrollMode  int8        `json:"enroll_mode"`
+	Capacity    null.Int    `json:"capacity"`
+}
+
+type Directory struct {
+	ID          int       `json:"id"`
+	ParentID    null.Int  `json:"parent_id"`
+	Name        string    `json:"name"`
+	VisibleFrom time.Time `json:"visible_from"`
+}
+
+// File is either a local file, whose content is stored in the archive at Path, or a web link to URI
+type File struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path,omitempty"`
+	URI         string   `json:"uri,omitempty"`
+	DirectoryID null.Int `json:"directory_id,omitempty"`
+
+	// the file the content of a local file is read from on export
+	source *models.File
+}
+
+type Submission struct {
+	Name        string    `json:"name"`
+	Deadline    null.Time `json:"deadline"`
+	MaxFilesize int       `json:"max_filesize"`
+	VisibleFrom time.Time `json:"visible_from"`
+	Files       []*File   `json:"files"`
+	// Comma separated extensions of the files that may be submitted, missing in archives of older versions
+	AllowedFileTypes null.String `json:"allowed_file_types"`
+}
+
+type Exam struct {
+	Name               string      `json:"name"`
+	Description        string      `json:"description"`
+	Date               time.Time   `json:"date"`
+	Duration           int         `json:"duration"`
+	Online             int8        `json:"online"`
+	Location           null.String `json:"location"`
+	RegisterDeadline   null.Time   `json:"register_deadline"`
+	DeregisterDeadline null.Time   `json:"deregister_deadline"`
+	Files              []*File     `json:"files"`
+}
+
+type Series struct {
+	ID             int `boil:"id" json:"id"`
+	RepeatDistance int `boil:"repeat_distance" json:"repeat_distance"`
+}
+
+type Appointment struct {
+	Date     time.Time   `json:"date"`
+	Duration int         `json:"duration"`
+	Location null.String `json:"location"`
+	Online   int8        `json:"online"`
+	SeriesID null.Int    `json:"series_id"`
+}
+
+// ForumEntry is an entry of the forum of the course. The author is only kept by name, as users aren't part of an archive.
+type ForumEntry struct {
+	ID        int       `boil:"id" json:"id"`
+	InReplyTo null.Int  `boil:"in_reply_to" json:"in_reply_to"`
+	Subject   string    `boil:"subject" json:"subject"`
+	Content   string    `boil:"content" json:"content"`
+	Author    string    `boil:"author" json:"author"`
+	CreatedAt time.Time `boil:"created_at" json:"created_at"`
+}
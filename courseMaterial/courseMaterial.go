@@ -11,6 +11,8 @@ import (
 	"learningbay24.de/backend/models"
 
 	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries"
 )
@@ -37,23 +39,64 @@ func GetAllMaterialsFromCourse(db *sql.DB, courseId int) ([]*models.File, error)
 	return files, nil
 }
 
-// CreateMaterial takes a fileName, URI, associated uploader-id, course, id and indicator if file is local or remote
-// Created struct gets inserted into database
-func CreateMaterial(dbHandle *sql.DB, fileName string, uri string, uploaderId, courseId int, local bool, file io.Reader, fileSize int) error {
+// CreateMaterial takes a fileName, URI, associated uploader-id, course, id, indicator if file is local or remote and the directory to put it in
+// Created struct gets inserted into database; if directoryId is null, the file is put at the top level of the course
+func CreateMaterial(dbHandle *sql.DB, fileName string, uri string, uploaderId, courseId int, local bool, file io.Reader, fileSize int, directoryId null.Int) error {
+	if err := dbi.CheckCourseWritable(dbHandle, courseId); err != nil {
+		return err
+	}
+	if directoryId.Valid {
+		if _, err := getDirectoryFromCourse(dbHandle, courseId, directoryId.Int); err != nil {
+			return err
+		}
+	}
+
 	fileId, err := dbi.SaveFile(dbHandle, fileName, uri, uploaderId, local, &file, fileSize)
 	if err != nil {
 		return err
 	}
 
+	// the file is linked to the course in one transaction and deleted again if that fails, so that it doesn't stay without a course
+	err = linkMaterial(dbHandle, courseId, fileId, directoryId)
+	if err != nil {
+		if e := dbi.DeleteFile(dbHandle, fileId); e != nil {
+			log.Errorf("Unable to delete file %d that couldn't be added to course %d: %s", fileId, courseId, e.Error())
+		}
+		return err
+	}
+
+	return nil
+}
+
+// linkMaterial adds a saved file to a course and puts it into the given directory
+func linkMaterial(db *sql.DB, courseId int, fileId int, directoryId null.Int) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
 	chf := models.CourseHasFile{
 		CourseID: courseId, FileID: fileId,
 	}
-
-	err = chf.Insert(context.Background(), dbHandle, boil.Infer())
+	err = chf.Insert(context.Background(), tx, boil.Infer())
+	if err == nil {
+		err = dbi.UpdateFileReferences(tx, fileId)
+	}
+	if err == nil && directoryId.Valid {
+		err = setMaterialDirectory(tx, fileId, directoryId)
+	}
 	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
 		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -69,6 +112,8 @@ func DeleteMaterialFromCourse(db *sql.DB, courseId, fileId int) error {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
+
+		return err
 	}
 
 	_, err = cm.Delete(context.Background(), tx, false)
@@ -76,6 +121,8 @@ func DeleteMaterialFromCourse(db *sql.DB, courseId, fileId int) error {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
+
+		return err
 	}
 
 	chf, err := models.FindCourseHasFile(context.Background(), tx, courseId, fileId)
@@ -83,6 +130,8 @@ func DeleteMaterialFromCourse(db *sql.DB, courseId, fileId int) error {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
+
+		return err
 	}
 
 	_, err = chf.Delete(context.Background(), tx, false)
@@ -90,6 +139,26 @@ func DeleteMaterialFromCourse(db *sql.DB, courseId, fileId int) error {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
+
+		return err
+	}
+
+	err = dbi.DeleteIndexedFile(tx, fileId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	err = dbi.UpdateFileReferences(tx, fileId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
 	}
 
 	if e := tx.Commit(); e != nil {
@@ -130,6 +199,19 @@ func DeleteAllMaterialsFromCourse(db *sql.DB, courseId int, hardDelete bool) err
 		return err
 	}
 
+	fileIds := make([]int, len(materials))
+	for i, m := range materials {
+		fileIds[i] = m.ID
+	}
+	err = dbi.UpdateFileReferences(tx, fileIds...)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
 	if e := tx.Commit(); e != nil {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
@@ -146,17 +228,43 @@ func RenameMaterialFromCourse(db *sql.DB, fileId int, fileName string) error {
 		return errs.ErrEmptyFileName
 	}
 
-	cm, err := models.FindFile(context.Background(), db, fileId)
+	tx, err := db.BeginTx(context.Background(), nil)
 	if err != nil {
 		return err
 	}
 
+	cm, err := models.FindFile(context.Background(), tx, fileId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
 	cm.Name = fileName
 
-	_, err = cm.Update(context.Background(), db, boil.Infer())
+	_, err = cm.Update(context.Background(), tx, boil.Infer())
 	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
 		return err
 	}
 
+	err = dbi.RenameIndexedFile(tx, fileId, fileName)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return fmt.Errorf("unable to commit transaction: %w", e)
+	}
+
 	return nil
 }
@@ -0,0 +1,426 @@
+package coursematerial
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// Material is a file of a course as it is shown to its users.
+// The URI is only set for remote files, as local ones have to be downloaded through the API.
+type Material struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}
+
+// Directory is a directory of a course together with its contents
+type Directory struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	VisibleFrom time.Time    `json:"visible_from"`
+	Directories []*Directory `json:"directories"`
+	Files       []*Material  `json:"files"`
+}
+
+// Tree is the top level of the materials of a course
+type Tree struct {
+	Directories []*Directory `json:"directories"`
+	Files       []*Material  `json:"files"`
+}
+
+// A file of a course together with the directory it is in, if any
+type courseFile struct {
+	ID          int      `boil:"id"`
+	Name        string   `boil:"name"`
+	URI         string   `boil:"uri"`
+	Local       int8     `boil:"local"`
+	DirectoryID null.Int `boil:"directory_id"`
+}
+
+// getCourseFiles returns all files of a course together with the directory they are in
+func getCourseFiles(exec boil.ContextExecutor, courseId int) ([]*courseFile, error) {
+	files := []*courseFile{}
+	err := queries.Raw(`SELECT f.id, f.name, f.uri, f.local, d.id AS directory_id FROM file f
+		JOIN course_has_files chf ON chf.file_id = f.id
+		LEFT JOIN directory_has_files dhf ON dhf.file_id = f.id
+		LEFT JOIN directory d ON d.id = dhf.directory_id AND d.course_id = chf.course_id AND d.deleted_at IS NULL
+		WHERE chf.course_id = ? AND chf.deleted_at IS NULL AND f.deleted_at IS NULL
+		ORDER BY f.name`, courseId).Bind(context.Background(), exec, &files)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// getDirectoryFromCourse returns the directory with the given ID if it is part of the course
+func getDirectoryFromCourse(exec boil.ContextExecutor, courseId int, directoryId int) (*models.Directory, error) {
+	return models.Directories(
+		models.DirectoryWhere.ID.EQ(directoryId),
+		models.DirectoryWhere.CourseID.EQ(courseId),
+	).One(context.Background(), exec)
+}
+
+// GetMaterialTree takes the ID of a course and returns its directories and files arranged as a tree.
+// Unless showHidden is set, directories that aren't visible yet are left out together with their contents.
+func GetMaterialTree(db *sql.DB, courseId int, showHidden bool) (*Tree, error) {
+	dirs, err := models.Directories(
+		models.DirectoryWhere.CourseID.EQ(courseId),
+		qm.OrderBy(models.DirectoryColumns.Name),
+	).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := getCourseFiles(db, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTree(dirs, files, showHidden, time.Now()), nil
+}
+
+// buildTree arranges the given directories and files as a tree
+func buildTree(dirs models.DirectorySlice, files []*courseFile, showHidden bool, now time.Time) *Tree {
+	tree := &Tree{Directories: []*Directory{}, Files: []*Material{}}
+
+	nodes := make(map[int]*Directory, len(dirs))
+	for _, d := range dirs {
+		nodes[d.ID] = &Directory{ID: d.ID, Name: d.Name, VisibleFrom: d.VisibleFrom, Directories: []*Directory{}, Files: []*Material{}}
+	}
+
+	for _, d := range dirs {
+		if !showHidden && d.VisibleFrom.After(now) {
+			continue
+		}
+
+		if !d.ParentID.Valid {
+			tree.Directories = append(tree.Directories, nodes[d.ID])
+			continue
+		}
+
+		// directories in hidden or deleted ones aren't reachable from the top level and thereby dropped
+		if parent, ok := nodes[d.ParentID.Int]; ok {
+			parent.Directories = append(parent.Directories, nodes[d.ID])
+		}
+	}
+
+	for _, f := range files {
+		m := &Material{ID: f.ID, Name: f.Name}
+		if f.Local == 0 {
+			m.URI = f.URI
+		}
+
+		if !f.DirectoryID.Valid {
+			tree.Files = append(tree.Files, m)
+			continue
+		}
+
+		if dir, ok := nodes[f.DirectoryID.Int]; ok {
+			dir.Files = append(dir.Files, m)
+		}
+	}
+
+	return tree
+}
+
+// IsMaterialVisible takes the ID of a course and of a file in it and checks whether the directory the file is in, and all directories above it, are visible already
+func IsMaterialVisible(db *sql.DB, courseId int, fileId int) (bool, error) {
+	files, err := getCourseFiles(db, courseId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, f := range files {
+		if f.ID != fileId {
+			continue
+		}
+
+		now := time.Now()
+		dirId := f.DirectoryID
+		for dirId.Valid {
+			d, err := getDirectoryFromCourse(db, courseId, dirId.Int)
+			if err != nil {
+				return false, err
+			}
+			if d.VisibleFrom.After(now) {
+				return false, nil
+			}
+			dirId = d.ParentID
+		}
+
+		return true, nil
+	}
+
+	return false, sql.ErrNoRows
+}
+
+// CreateDirectory takes the ID of a course, the ID of the directory to create it in, a name and the time it will be visible from and creates a directory.
+// If parentId is null, the directory is created at the top level of the course. If visibleFrom is null, it is visible right away.
+func CreateDirectory(db *sql.DB, courseId int, parentId null.Int, name string, visibleFrom null.Time) (int, error) {
+	if name == "" {
+		return 0, errs.ErrEmptyName
+	}
+	if err := dbi.CheckCourseWritable(db, courseId); err != nil {
+		return 0, err
+	}
+
+	if parentId.Valid {
+		if _, err := getDirectoryFromCourse(db, courseId, parentId.Int); err != nil {
+			return 0, err
+		}
+	}
+
+	d := models.Directory{Name: name, CourseID: courseId, ParentID: parentId, VisibleFrom: visibleFrom.Time}
+	if !visibleFrom.Valid {
+		d.VisibleFrom = time.Now()
+	}
+
+	err := d.Insert(context.Background(), db, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	return d.ID, nil
+}
+
+// EditDirectory takes the ID of a course and of a directory in it and sets its name and the time it will be visible from.
+// Empty or null values are left unchanged.
+func EditDirectory(db *sql.DB, courseId int, directoryId int, name string, visibleFrom null.Time) error {
+	if err := dbi.CheckCourseWritable(db, courseId); err != nil {
+		return err
+	}
+
+	d, err := getDirectoryFromCourse(db, courseId, directoryId)
+	if err != nil {
+		return err
+	}
+
+	if name != "" {
+		d.Name = name
+	}
+	if visibleFrom.Valid {
+		d.VisibleFrom = visibleFrom.Time
+	}
+
+	_, err = d.Update(context.Background(), db, boil.Infer())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MoveDirectory takes the ID of a course, of a directory in it and of the directory to move it to and moves the directory together with its contents.
+// If parentId is null, the directory is moved to the top level of the course.
+func MoveDirectory(db *sql.DB, courseId int, directoryId int, parentId null.Int) error {
+	if err := dbi.CheckCourseWritable(db, courseId); err != nil {
+		return err
+	}
+
+	d, err := getDirectoryFromCourse(db, courseId, directoryId)
+	if err != nil {
+		return err
+	}
+
+	// the new parent may neither be the directory itself nor be contained in it
+	for id := parentId; id.Valid; {
+		if id.Int == directoryId {
+			return errs.ErrDirectoryCycle
+		}
+
+		parent, err := getDirectoryFromCourse(db, courseId, id.Int)
+		if err != nil {
+			return err
+		}
+		id = parent.ParentID
+	}
+
+	d.ParentID = parentId
+	_, err = d.Update(context.Background(), db, boil.Infer())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteDirectory takes the ID of a course and of a directory in it and soft-deletes the directory together with all directories and files in it
+func DeleteDirectory(db *sql.DB, courseId int, directoryId int) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	err = dbi.CheckCourseWritable(tx, courseId)
+	var dirs models.DirectorySlice
+	if err == nil {
+		dirs, err = models.Directories(models.DirectoryWhere.CourseID.EQ(courseId)).All(context.Background(), tx)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	children := make(map[int][]*models.Directory)
+	var root *models.Directory
+	for _, d := range dirs {
+		if d.ID == directoryId {
+			root = d
+		}
+		if d.ParentID.Valid {
+			children[d.ParentID.Int] = append(children[d.ParentID.Int], d)
+		}
+	}
+	if root == nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", sql.ErrNoRows, e)
+		}
+
+		return sql.ErrNoRows
+	}
+
+	toDelete := models.DirectorySlice{root}
+	inSubtree := map[int]bool{root.ID: true}
+	for i := 0; i < len(toDelete); i++ {
+		for _, c := range children[toDelete[i].ID] {
+			inSubtree[c.ID] = true
+			toDelete = append(toDelete, c)
+		}
+	}
+
+	files, err := getCourseFiles(tx, courseId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	var fileIds []int
+	for _, f := range files {
+		if f.DirectoryID.Valid && inSubtree[f.DirectoryID.Int] {
+			fileIds = append(fileIds, f.ID)
+		}
+	}
+
+	if len(fileIds) > 0 {
+		_, err = models.Files(models.FileWhere.ID.IN(fileIds)).DeleteAll(context.Background(), tx, false)
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+
+			return err
+		}
+
+		_, err = models.CourseHasFiles(
+			models.CourseHasFileWhere.CourseID.EQ(courseId),
+			models.CourseHasFileWhere.FileID.IN(fileIds),
+		).DeleteAll(context.Background(), tx, false)
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+
+			return err
+		}
+
+		err = dbi.UpdateFileReferences(tx, fileIds...)
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+
+			return err
+		}
+	}
+
+	_, err = toDelete.DeleteAll(context.Background(), tx, false)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MoveMaterial takes the ID of a course, of a file in it and of the directory to move it to.
+// If directoryId is null, the file is moved to the top level of the course.
+func MoveMaterial(db *sql.DB, courseId int, fileId int, directoryId null.Int) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	err = dbi.CheckCourseWritable(tx, courseId)
+	if err == nil {
+		_, err = models.FindCourseHasFile(context.Background(), tx, courseId, fileId)
+	}
+	if err == nil && directoryId.Valid {
+		_, err = getDirectoryFromCourse(tx, courseId, directoryId.Int)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	err = setMaterialDirectory(tx, fileId, directoryId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// setMaterialDirectory takes the ID of a file and puts it into the given directory, removing it from the one it was in before
+func setMaterialDirectory(exec boil.ContextExecutor, fileId int, directoryId null.Int) error {
+	_, err := exec.Exec("DELETE FROM directory_has_files WHERE file_id = ?", fileId)
+	if err != nil {
+		return err
+	}
+
+	if !directoryId.Valid {
+		return nil
+	}
+
+	_, err = exec.Exec("INSERT INTO directory_has_files (directory_id, file_id) VALUES (?, ?)", directoryId.Int, fileId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package coursematerial
+
+import (
+	"testing"
+	"time"
+
+	"learningbay24.de/backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/volatiletech/null/v8"
+)
+
+func TestBuildTree(t *testing.T) {
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	dirs := models.DirectorySlice{
+		{ID: 1, Name: "Lectures", VisibleFrom: now.Add(-time.Hour)},
+		{ID: 2, Name: "Week 1", ParentID: null.IntFrom(1), VisibleFrom: now.Add(-time.Hour)},
+		{ID: 3, Name: "Solutions", VisibleFrom: now.Add(time.Hour)},
+		{ID: 4, Name: "Sheet 1", ParentID: null.IntFrom(3), VisibleFrom: now.Add(-time.Hour)},
+	}
+	files := []*courseFile{
+		{ID: 10, Name: "syllabus.pdf", URI: "/files/syllabus.pdf", Local: 1},
+		{ID: 11, Name: "slides.pdf", URI: "/files/slides.pdf", Local: 1, DirectoryID: null.IntFrom(2)},
+		{ID: 12, Name: "solution.pdf", URI: "/files/solution.pdf", Local: 1, DirectoryID: null.IntFrom(4)},
+		{ID: 13, Name: "wiki", URI: "https://example.com", Local: 0, DirectoryID: null.IntFrom(1)},
+	}
+
+	tree := buildTree(dirs, files, false, now)
+	assert.Len(t, tree.Directories, 1)
+	assert.Equal(t, "Lectures", tree.Directories[0].Name)
+	assert.Len(t, tree.Directories[0].Directories, 1)
+	assert.Equal(t, []*Material{{ID: 11, Name: "slides.pdf"}}, tree.Directories[0].Directories[0].Files)
+	assert.Equal(t, []*Material{{ID: 13, Name: "wiki", URI: "https://example.com"}}, tree.Directories[0].Files)
+	assert.Equal(t, []*Material{{ID: 10, Name: "syllabus.pdf"}}, tree.Files)
+
+	tree = buildTree(dirs, files, true, now)
+	assert.Len(t, tree.Directories, 2)
+	assert.Equal(t, "Solutions", tree.Directories[1].Name)
+	assert.Equal(t, []*Material{{ID: 12, Name: "solution.pdf"}}, tree.Directories[1].Directories[0].Files)
+}
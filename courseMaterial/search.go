@@ -0,0 +1,216 @@
+package coursematerial
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// Words shorter than this aren't part of the fulltext index
+const minSearchWordLength = 3
+
+// Maximum amount of files returned by a search
+const maxSearchFiles = 50
+
+// Maximum amount of pages shown per file found
+const maxSearchMatches = 5
+
+// Amount of characters shown around a match
+const snippetContext = 80
+
+// MaterialHit is a file found by `SearchMaterials` together with the pages it matched on
+type MaterialHit struct {
+	FileID     int              `json:"file_id"`
+	Name       string           `json:"name"`
+	CourseID   int              `json:"course_id"`
+	CourseName string           `json:"course_name"`
+	Matches    []*MaterialMatch `json:"matches"`
+}
+
+// MaterialMatch is a page of a file that matches a search. The page is null for files without pages.
+type MaterialMatch struct {
+	Page    null.Int `json:"page"`
+	Snippet string   `json:"snippet"`
+}
+
+type contentRow struct {
+	FileID     int      `boil:"file_id"`
+	Page       null.Int `boil:"page"`
+	Name       string   `boil:"name"`
+	Content    string   `boil:"content"`
+	CourseID   int      `boil:"course_id"`
+	CourseName string   `boil:"course_name"`
+	RoleID     int      `boil:"role_id"`
+}
+
+// SearchMaterials takes the ID of a user and a search term and returns the files, of the courses the user is enrolled in,
+// whose name or text contains every word of the term, best matches first. Files in directories that aren't visible yet
+// are only found by course moderators and admins.
+func SearchMaterials(db *sql.DB, userId int, term string) ([]*MaterialHit, error) {
+	words := searchWords(term)
+	var query strings.Builder
+	for _, w := range words {
+		if len([]rune(w)) >= minSearchWordLength {
+			query.WriteString("+" + w + "* ")
+		}
+	}
+	if query.Len() == 0 {
+		return nil, errs.ErrSearchTermTooShort
+	}
+
+	rows := []*contentRow{}
+	err := queries.Raw(`SELECT fc.file_id, fc.page, f.name, fc.content, c.id AS course_id, c.name AS course_name, uhc.role_id
+		FROM file_content fc
+		JOIN file f ON f.id = fc.file_id AND f.deleted_at IS NULL
+		JOIN course_has_files chf ON chf.file_id = f.id AND chf.deleted_at IS NULL
+		JOIN course c ON c.id = chf.course_id AND c.deleted_at IS NULL
+		JOIN user_has_course uhc ON uhc.course_id = c.id AND uhc.user_id = ? AND uhc.deleted_at IS NULL
+		WHERE MATCH (fc.name, fc.content) AGAINST (? IN BOOLEAN MODE)
+		ORDER BY MATCH (fc.name, fc.content) AGAINST (? IN BOOLEAN MODE) DESC, fc.file_id, fc.page
+		LIMIT 1000`, userId, query.String(), query.String()).Bind(context.Background(), db, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// files of hidden directories, per course only regular users are enrolled in
+	hidden := make(map[int]map[int]bool)
+	hits := []*MaterialHit{}
+	byFile := make(map[int]*MaterialHit)
+	for _, r := range rows {
+		if r.RoleID == dbi.CourseUserRoleId {
+			if _, ok := hidden[r.CourseID]; !ok {
+				if hidden[r.CourseID], err = hiddenFiles(db, r.CourseID); err != nil {
+					return nil, err
+				}
+			}
+			if hidden[r.CourseID][r.FileID] {
+				continue
+			}
+		}
+
+		hit, ok := byFile[r.FileID]
+		if !ok {
+			if len(hits) == maxSearchFiles {
+				continue
+			}
+			hit = &MaterialHit{FileID: r.FileID, Name: r.Name, CourseID: r.CourseID, CourseName: r.CourseName, Matches: []*MaterialMatch{}}
+			byFile[r.FileID] = hit
+			hits = append(hits, hit)
+		}
+		if len(hit.Matches) < maxSearchMatches {
+			hit.Matches = append(hit.Matches, &MaterialMatch{Page: r.Page, Snippet: snippet(r.Content, words)})
+		}
+	}
+
+	return hits, nil
+}
+
+// hiddenFiles returns the files of a course that are in directories which aren't visible yet
+func hiddenFiles(db *sql.DB, courseId int) (map[int]bool, error) {
+	dirs, err := models.Directories(
+		models.DirectoryWhere.CourseID.EQ(courseId),
+		qm.OrderBy(models.DirectoryColumns.Name),
+	).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := getCourseFiles(db, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make(map[int]bool, len(files))
+	var walk func(dirs []*Directory, files []*Material)
+	walk = func(dirs []*Directory, files []*Material) {
+		for _, f := range files {
+			visible[f.ID] = true
+		}
+		for _, d := range dirs {
+			walk(d.Directories, d.Files)
+		}
+	}
+	tree := buildTree(dirs, files, false, time.Now())
+	walk(tree.Directories, tree.Files)
+
+	hidden := make(map[int]bool)
+	for _, f := range files {
+		if !visible[f.ID] {
+			hidden[f.ID] = true
+		}
+	}
+
+	return hidden, nil
+}
+
+// searchWords splits a search term into lower case words, dropping the operators of boolean fulltext searches
+func searchWords(term string) []string {
+	return strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// snippet cuts the part of a text around the first occurrence of any of the words out of it.
+// If none of them occur, e.g. because the file was found by its name, the beginning of the text is returned.
+func snippet(text string, words []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	// lower casing may change the length of a few characters, in which case positions can't be mapped back
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	pos, length := -1, 0
+	for _, w := range words {
+		if i := indexRunes(lower, []rune(w)); i >= 0 && (pos < 0 || i < pos) {
+			pos, length = i, len([]rune(w))
+		}
+	}
+	if pos < 0 {
+		pos = 0
+	}
+
+	start := pos - snippetContext
+	if start < 0 {
+		start = 0
+	}
+	end := pos + length + snippetContext
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	s := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
+
+func indexRunes(s []rune, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
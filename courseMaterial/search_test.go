@@ -0,0 +1,27 @@
+package coursematerial
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchWords(t *testing.T) {
+	assert.Equal(t, []string{"binäre", "suchbäume", "o", "log", "n"}, searchWords(`+Binäre "Suchbäume*" O(log n)`))
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("a ", 100) + "Binary   Search\nTrees" + strings.Repeat(" b", 100)
+
+	s := snippet(text, []string{"search"})
+	assert.True(t, strings.HasPrefix(s, "…"))
+	assert.True(t, strings.HasSuffix(s, "…"))
+	assert.Contains(t, s, "Binary Search Trees")
+
+	assert.Equal(t, "short text", snippet("short text", []string{"missing"}))
+
+	s = snippet("Über "+strings.Repeat("x", 200), []string{"über"})
+	assert.True(t, strings.HasPrefix(s, "Über x"))
+	assert.True(t, strings.HasSuffix(s, "…"))
+}
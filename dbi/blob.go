@@ -0,0 +1,277 @@
+package dbi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/storage"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// How long the content of a blob is kept after its last reference was removed,
+// so that uploads and links in transactions that aren't committed yet can still refer to it
+const blobGracePeriod = time.Hour
+
+// A content stored once for all local files with the same content, see the file_blob table
+type blob struct {
+	ID         int         `boil:"id"`
+	Sha256     null.String `boil:"sha256"`
+	Size       int64       `boil:"size"`
+	Storage    string      `boil:"storage"`
+	StorageKey string      `boil:"storage_key"`
+	RefCount   int         `boil:"ref_count"`
+	// Version of the malware signatures the content was last scanned with
+	ScannedVersion null.String `boil:"scanned_version"`
+	// Set if the content was found to be infected after it was stored
+	QuarantineID null.Int `boil:"quarantine_id"`
+}
+
+const blobColumns = "id, sha256, size, storage, storage_key, ref_count, scanned_version, quarantine_id"
+
+func findBlob(exec boil.ContextExecutor, id int) (*blob, error) {
+	b := &blob{}
+	err := queries.Raw("SELECT "+blobColumns+" FROM file_blob WHERE id = ?", id).Bind(context.Background(), exec, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// storeBlob takes the content of an upload together with its SHA-256 hash and size and the version of the signatures it was scanned with
+// and returns the ID of the blob storing it. If a blob with the same hash exists it is reused, otherwise the content is stored in the default storage backend.
+// The returned function deletes newly stored content again and has to be called if the transaction isn't committed.
+func storeBlob(exec boil.ContextExecutor, hash string, r io.Reader, size int64, scannedVersion string) (int, func(), error) {
+	discard := func() {}
+
+	existing := &blob{}
+	// the lock keeps the collector from deleting the blob before the file referring to it is committed
+	err := queries.Raw("SELECT "+blobColumns+" FROM file_blob WHERE sha256 = ? FOR UPDATE", hash).Bind(context.Background(), exec, existing)
+	if err == nil {
+		if existing.QuarantineID.Valid {
+			return 0, discard, errs.ErrFileInfected
+		}
+
+		_, err = exec.Exec("UPDATE file_blob SET released_at = NULL WHERE id = ?", existing.ID)
+		if err != nil {
+			return 0, discard, err
+		}
+		return existing.ID, discard, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, discard, err
+	}
+
+	backend, err := storage.Default()
+	if err != nil {
+		return 0, discard, err
+	}
+	key, err := storage.NewKey("")
+	if err != nil {
+		return 0, discard, err
+	}
+	if err := backend.Put(key, r, size); err != nil {
+		return 0, discard, err
+	}
+	discard = func() {
+		if e := backend.Delete(key); e != nil {
+			log.Errorf("Unable to delete content of blob that couldn't be saved: %s", e.Error())
+		}
+	}
+
+	version := null.NewString(scannedVersion, scannedVersion != "")
+	res, err := exec.Exec("INSERT INTO file_blob (sha256, size, storage, storage_key, scanned_version) VALUES (?, ?, ?, ?, ?)", hash, size, backend.Name(), key, version)
+	if err != nil {
+		discard()
+		return 0, func() {}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		discard()
+		return 0, func() {}, err
+	}
+
+	return int(id), discard, nil
+}
+
+// countReferences returns how often the content of a blob is referred to by materials, submissions, answers and exams.
+// Files only count as long as they aren't deleted. Profile pictures are counted too, so that their content is never lost.
+func countReferences(exec boil.ContextExecutor, blobId int) (int, error) {
+	var r struct {
+		Refs int `boil:"refs"`
+	}
+	err := queries.Raw(`SELECT
+		(SELECT COUNT(*) FROM course_has_files l JOIN file f ON f.id = l.file_id
+			WHERE f.blob_id = ? AND f.deleted_at IS NULL AND l.deleted_at IS NULL) +
+		(SELECT COUNT(*) FROM submission_has_files l JOIN file f ON f.id = l.file_id
+			WHERE f.blob_id = ? AND f.deleted_at IS NULL) +
+		(SELECT COUNT(*) FROM user_submission_has_files l JOIN file f ON f.id = l.file_id
+			WHERE f.blob_id = ? AND f.deleted_at IS NULL) +
+		(SELECT COUNT(*) FROM exam_has_files l JOIN file f ON f.id = l.file_id
+			WHERE f.blob_id = ? AND f.deleted_at IS NULL) +
+		(SELECT COUNT(*) FROM user_has_exam l JOIN file f ON f.id = l.file_id
+			WHERE f.blob_id = ? AND f.deleted_at IS NULL) +
+		(SELECT COUNT(*) FROM user u JOIN file f ON f.id = u.profile_picture
+			WHERE f.blob_id = ? AND f.deleted_at IS NULL) AS refs`,
+		blobId, blobId, blobId, blobId, blobId, blobId).Bind(context.Background(), exec, &r)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.Refs, nil
+}
+
+// refreshBlob recounts the references to a blob. A blob without references is released, so that the collector deletes it later on.
+func refreshBlob(exec boil.ContextExecutor, blobId int) (int, error) {
+	refs, err := countReferences(exec, blobId)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = exec.Exec("UPDATE file_blob SET ref_count = ?, released_at = IF(? = 0, COALESCE(released_at, CURRENT_TIMESTAMP), NULL) WHERE id = ?", refs, refs, blobId)
+	if err != nil {
+		return 0, err
+	}
+
+	return refs, nil
+}
+
+// UpdateFileReferences takes the IDs of files that were linked to or unlinked from a course, submission or exam, or deleted,
+// and recounts the references to their contents. Deleted files refund the bytes charged for them to their uploaders.
+func UpdateFileReferences(exec boil.ContextExecutor, fileIds ...int) error {
+	if len(fileIds) == 0 {
+		return nil
+	}
+
+	files, err := models.Files(qm.WithDeleted(), models.FileWhere.ID.IN(fileIds)).All(context.Background(), exec)
+	if err != nil {
+		return err
+	}
+
+	blobIds := make(map[int]bool)
+	for _, f := range files {
+		if f.BlobID.Valid {
+			blobIds[f.BlobID.Int] = true
+		}
+
+		if !f.DeletedAt.Valid || f.ChargedBytes == 0 {
+			continue
+		}
+		// every uploader is charged for their own upload, even if its content was already stored,
+		// so deleting it refunds exactly what was charged, no matter who else refers to the content
+		_, err = exec.Exec("UPDATE user SET uploaded_bytes = GREATEST(uploaded_bytes - ?, 0) WHERE id = ?", f.ChargedBytes, f.UploaderID)
+		if err != nil {
+			return err
+		}
+		_, err = exec.Exec("UPDATE file SET charged_bytes = 0 WHERE id = ?", f.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for id := range blobIds {
+		if _, err := refreshBlob(exec, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CollectBlobs deletes the contents of blobs that haven't been referred to since they were released longer than the grace period ago
+func CollectBlobs(db *sql.DB) error {
+	var released []struct {
+		ID int `boil:"id"`
+	}
+	err := queries.Raw("SELECT id FROM file_blob WHERE ref_count = 0 AND released_at < NOW() - INTERVAL ? SECOND",
+		int(blobGracePeriod.Seconds())).Bind(context.Background(), db, &released)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range released {
+		if err := collectBlob(db, r.ID); err != nil {
+			return fmt.Errorf("unable to collect blob %d: %w", r.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// collectBlob deletes a released blob, unless it was referred to again in the meantime
+func collectBlob(db *sql.DB, id int) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	b := &blob{}
+	err = queries.Raw("SELECT "+blobColumns+" FROM file_blob WHERE id = ? AND released_at < NOW() - INTERVAL ? SECOND FOR UPDATE",
+		id, int(blobGracePeriod.Seconds())).Bind(context.Background(), tx, b)
+	if errors.Is(err, sql.ErrNoRows) {
+		// reused by an upload
+		return tx.Rollback()
+	}
+
+	var refs int
+	if err == nil {
+		// the references are counted again, in case a reference was added without updating them
+		refs, err = refreshBlob(tx, id)
+	}
+	if err == nil && refs == 0 {
+		// only deleted or unused files are left referring to the blob
+		_, err = tx.Exec("UPDATE file SET blob_id = NULL WHERE blob_id = ?", id)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM file_blob WHERE id = ?", id)
+		}
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return fmt.Errorf("unable to commit transaction: %w", e)
+	}
+	if refs > 0 {
+		return nil
+	}
+
+	// the content is only deleted once nothing can refer to it anymore
+	backend, err := storage.Get(b.Storage)
+	if err != nil {
+		return err
+	}
+	if err := backend.Delete(b.StorageKey); err != nil && !errors.Is(err, storage.ErrNotExist) {
+		log.Warnf("Unable to delete content %s of collected blob %d from storage backend %s: %s", b.StorageKey, id, b.Storage, err.Error())
+	}
+
+	log.Debugf("Collected blob %d", id)
+	return nil
+}
+
+// RunBlobCollector deletes the contents of released blobs once per interval. It never returns, so it should run in its own goroutine.
+func RunBlobCollector(db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := CollectBlobs(db); err != nil {
+			log.Errorf("Unable to collect unreferenced file contents: %s", err.Error())
+		}
+
+		<-ticker.C
+	}
+}
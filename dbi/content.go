@@ -0,0 +1,62 @@
+package dbi
+
+import (
+	"errors"
+	"io"
+
+	"learningbay24.de/backend/textExtract"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// indexFile extracts the text of a local file and stores it page by page, so that it can be searched.
+// Files whose text can't be read are still indexed by their name.
+func indexFile(exec boil.ContextExecutor, fileId int, name string, r io.ReaderAt, size int64) error {
+	pages, err := textextract.Extract(name, r, size)
+	if err != nil {
+		if !errors.Is(err, textextract.ErrUnsupported) {
+			log.Warnf("unable to extract text of file %d: %s", fileId, err.Error())
+		}
+		pages = nil
+	}
+	if len(pages) == 0 {
+		pages = []textextract.Page{{}}
+	}
+
+	for _, p := range pages {
+		page := null.NewInt(p.Number, p.Number != 0)
+		_, err := exec.Exec("INSERT INTO file_content (file_id, page, name, content) VALUES (?, ?, ?, ?)", fileId, page, name, p.Text)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexName indexes a file, e.g. a web link, only by its name
+func indexName(exec boil.ContextExecutor, fileId int, name string) error {
+	_, err := exec.Exec("INSERT INTO file_content (file_id, name, content) VALUES (?, ?, '')", fileId, name)
+	return err
+}
+
+// RenameIndexedFile takes the ID of a file and its new name and updates the name it is found by
+func RenameIndexedFile(exec boil.ContextExecutor, fileId int, name string) error {
+	_, err := exec.Exec("UPDATE file_content SET name = ? WHERE file_id = ?", name, fileId)
+	return err
+}
+
+// DeleteIndexedFile takes the ID of a file and removes its contents from the index
+func DeleteIndexedFile(exec boil.ContextExecutor, fileId int) error {
+	_, err := exec.Exec("DELETE FROM file_content WHERE file_id = ?", fileId)
+	return err
+}
+
+// CopyIndexedFile takes the ID of a file and of a copy of it and indexes the copy with the contents of the original
+func CopyIndexedFile(exec boil.ContextExecutor, fileId int, copyId int) error {
+	_, err := exec.Exec(`INSERT INTO file_content (file_id, page, name, content)
+		SELECT ?, page, name, content FROM file_content WHERE file_id = ?`, copyId, fileId)
+	return err
+}
@@ -0,0 +1,23 @@
+package dbi
+
+import (
+	"context"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// CheckCourseWritable takes the ID of a course and returns `errs.ErrCourseArchived` if the course is archived and thus read-only
+func CheckCourseWritable(exec boil.ContextExecutor, courseId int) error {
+	c, err := models.FindCourse(context.Background(), exec, courseId)
+	if err != nil {
+		return err
+	}
+	if c.ArchivedAt.Valid {
+		return errs.ErrCourseArchived
+	}
+
+	return nil
+}
@@ -2,35 +2,73 @@
 package dbi
 
 import (
-	"bufio"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
 
 	"learningbay24.de/backend/config"
 	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/fileType"
 	"learningbay24.de/backend/models"
 
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
-// Save a File to disk, creating a database entry alongside it.
-// The fileName can change, depending on if a file with the same name exists already. If the file is a web link (non local), the fileName will become the name given to the URL.
+// FilePolicy restricts which local files may be saved in a context, e.g. for a submission, in addition to the restrictions of the config
+type FilePolicy struct {
+	// Extensions without their leading dot, all extensions allowed by the config if empty
+	AllowedFileTypes []string
+	// Maximum size in bytes, unlimited if 0
+	MaxSize int64
+}
+
+// allows returns whether files with the given extension without its leading dot may be saved
+func (p FilePolicy) allows(ext string) bool {
+	allowed := false
+	for _, e := range config.Conf.Files.AllowedFileTypes {
+		if ext == e {
+			// file type is allowed per config
+			allowed = true
+			break
+		}
+	}
+	if !allowed || len(p.AllowedFileTypes) == 0 {
+		return allowed
+	}
+
+	for _, e := range p.AllowedFileTypes {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Save a File to the storage backend, creating a database entry alongside it. Local files with the same content share it.
+// If the file is a web link (non local), the fileName will become the name given to the URL.
 // The file represents either a local file or a remote one
 func SaveFile(db *sql.DB, fileName string, uri string, uploaderID int, isLocal bool, file *io.Reader, fileSize int) (int, error) {
-	filePath := config.Conf.Files.Path
+	return SaveFileWithPolicy(db, fileName, uri, uploaderID, isLocal, file, fileSize, FilePolicy{})
+}
 
+// SaveFileWithPolicy saves a file like `SaveFile`, but only accepts local files allowed by the given policy
+func SaveFileWithPolicy(db *sql.DB, fileName string, uri string, uploaderID int, isLocal bool, file *io.Reader, fileSize int, policy FilePolicy) (int, error) {
 	var id int
 	var err error
 
 	if isLocal {
-		id, err = saveLocalFile(db, filePath, fileName, uploaderID, file, fileSize)
+		id, err = saveLocalFile(db, fileName, uploaderID, file, fileSize, policy)
 		if err != nil {
 			return 0, err
 		}
@@ -50,46 +88,68 @@ func SaveFile(db *sql.DB, fileName string, uri string, uploaderID int, isLocal b
 	return id, nil
 }
 
-// Save a file locally by creating a new one, never overwriting an old one. Should a file with the exact same name exist already, append the new one with a suffix of "-<count>"
-func saveLocalFile(db *sql.DB, filePath string, fileName string, uploaderID int, file *io.Reader, fileSize int) (int, error) {
-	// possibly changed name due to a file with the same name already existing
+// Save a local file, buffering its content in a temporary file first, so that its text can be indexed and its exact size is known.
+// The content is hashed while it is buffered and only stored if no blob with the same content exists yet.
+// The uploader is charged for the size of the file either way, fileSize is only used to reject files over the upload limit early.
+// Files are only accepted if their content matches their extension, and archives only if they can be extracted safely.
+func saveLocalFile(db *sql.DB, fileName string, uploaderID int, file *io.Reader, fileSize int, policy FilePolicy) (int, error) {
 	name := fileName
 
 	ext := path.Ext(name)
-	allowed := false
-	if ext != "" {
-		// strip leading dot
-		_ext := ext[1:]
-		for _, e := range config.Conf.Files.AllowedFileTypes {
-			if _ext == e {
-				// file type is allowed per config
-				allowed = true
-				break
-			}
-		}
-	} else {
+	if ext == "" {
 		return 0, errs.ErrNoFileExtension
 	}
-
-	if !allowed {
+	// strip leading dot
+	ext = ext[1:]
+	if !policy.allows(ext) {
 		return 0, errs.ErrFileExtensionNotAllowed
 	}
+	if policy.MaxSize != 0 && int64(fileSize) > policy.MaxSize {
+		return 0, errs.ErrFileTooLarge
+	}
 
-	newName := name
-	// check if file type is valid
-	for num := 0; ; num++ {
-		if _, err := os.Stat(filepath.Join(filePath, newName)); err != nil {
-			if !os.IsNotExist(err) {
-				return 0, err
-			} else {
-				break
-			}
-		} else {
-			if num != 0 {
-				f := strings.TrimSuffix(name, ext)
-				newName = fmt.Sprintf("%s-%d%s", f, num, ext)
-			}
-		}
+	// verify user exists and whether the user reached the upload cap yet
+	user, err := models.FindUser(context.Background(), db, uploaderID)
+	if err != nil {
+		return 0, err
+	}
+	if config.Conf.Files.MaxUploadPerUser != 0 && user.UploadedBytes+fileSize > config.Conf.Files.MaxUploadPerUser {
+		return 0, errs.ErrUploadLimitReached
+	}
+
+	tmp, err := os.CreateTemp("", "learningbay24-upload-*."+ext)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	src := *file
+	if policy.MaxSize != 0 {
+		// the size given by the client can't be trusted
+		src = io.LimitReader(src, policy.MaxSize+1)
+	}
+	size, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if err != nil {
+		return 0, err
+	}
+	if policy.MaxSize != 0 && size > policy.MaxSize {
+		return 0, errs.ErrFileTooLarge
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	mimeType := filetype.Detect(tmp, size)
+	if err := filetype.Check(ext, mimeType); err != nil {
+		return 0, err
+	}
+	if err := filetype.CheckArchive(mimeType, tmp, size); err != nil {
+		return 0, err
+	}
+
+	version, err := scanUpload(db, name, uploaderID, hash, tmp, size)
+	if err != nil {
+		return 0, err
 	}
 
 	tx, err := db.BeginTx(context.Background(), nil)
@@ -97,8 +157,13 @@ func saveLocalFile(db *sql.DB, filePath string, fileName string, uploaderID int,
 		return 0, err
 	}
 
-	// verify user exists and whether the user reached the upload cap yet
-	user, err := models.FindUser(context.Background(), tx, uploaderID)
+	// check again with the actual size, as other uploads could have happened in the meantime.
+	// The user is locked until the upload is charged, so that parallel uploads can't exceed the limit together.
+	user, err = models.Users(
+		qm.Select(models.UserColumns.ID, models.UserColumns.UploadedBytes),
+		models.UserWhere.ID.EQ(uploaderID),
+		qm.For("update"),
+	).One(context.Background(), tx)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
 			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
@@ -107,7 +172,7 @@ func saveLocalFile(db *sql.DB, filePath string, fileName string, uploaderID int,
 		return 0, err
 	}
 
-	if config.Conf.Files.MaxUploadPerUser != 0 && user.UploadedBytes+fileSize > config.Conf.Files.MaxUploadPerUser {
+	if config.Conf.Files.MaxUploadPerUser != 0 && user.UploadedBytes+int(size) > config.Conf.Files.MaxUploadPerUser {
 		if e := tx.Rollback(); e != nil {
 			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
@@ -115,9 +180,7 @@ func saveLocalFile(db *sql.DB, filePath string, fileName string, uploaderID int,
 		return 0, errs.ErrUploadLimitReached
 	}
 
-	fullFile := filepath.Join(filePath, newName)
-	f := models.File{Name: name, URI: fullFile, Local: 1, UploaderID: uploaderID}
-	err = f.Insert(context.Background(), tx, boil.Infer())
+	_, err = tmp.Seek(0, io.SeekStart)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
 			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
@@ -126,8 +189,7 @@ func saveLocalFile(db *sql.DB, filePath string, fileName string, uploaderID int,
 		return 0, err
 	}
 
-	// create file on disk
-	fp, err := os.Create(fullFile)
+	blobId, discard, err := storeBlob(tx, hash, tmp, size, version)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
 			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
@@ -135,11 +197,18 @@ func saveLocalFile(db *sql.DB, filePath string, fileName string, uploaderID int,
 
 		return 0, err
 	}
-	defer fp.Close()
-	// write to the file on disk
-	bufr := bufio.NewReader(*file)
-	_, err = bufr.WriteTo(fp)
+
+	f := models.File{Name: name, Local: 1, BlobID: null.IntFrom(blobId), ChargedBytes: int(size), MimeType: null.StringFrom(mimeType), UploaderID: uploaderID}
+	err = f.Insert(context.Background(), tx, boil.Infer())
+	if err == nil {
+		// charged relative to the current value, like refunds are
+		_, err = tx.Exec("UPDATE user SET uploaded_bytes = uploaded_bytes + ? WHERE id = ?", size, uploaderID)
+	}
+	if err == nil {
+		err = indexFile(tx, f.ID, name, tmp, size)
+	}
 	if err != nil {
+		discard()
 		if e := tx.Rollback(); e != nil {
 			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
@@ -149,11 +218,10 @@ func saveLocalFile(db *sql.DB, filePath string, fileName string, uploaderID int,
 
 	err = tx.Commit()
 	if err != nil {
-		if e := tx.Rollback(); e != nil {
-			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
-		}
+		// nothing refers to the content anymore
+		discard()
 
-		return 0, err
+		return 0, fmt.Errorf("unable to commit transaction: %w", err)
 	}
 
 	return f.ID, nil
@@ -176,6 +244,15 @@ func saveRemoteFile(db *sql.DB, linkName string, u *url.URL, uploaderID int, fil
 		return 0, err
 	}
 
+	err = indexName(tx, f.ID, linkName)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return 0, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return 0, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
@@ -205,8 +282,7 @@ func DeleteFile(db *sql.DB, file_id int) error {
 		return err
 	}
 
-	fi, err := os.Stat(f.URI)
-	if err != nil {
+	if err := DeleteIndexedFile(tx, f.ID); err != nil {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
@@ -214,8 +290,7 @@ func DeleteFile(db *sql.DB, file_id int) error {
 		return err
 	}
 
-	u, err := models.FindUser(context.Background(), tx, f.UploaderID)
-	if err != nil {
+	if _, err = f.Delete(context.Background(), tx, false); err != nil {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
@@ -223,8 +298,8 @@ func DeleteFile(db *sql.DB, file_id int) error {
 		return err
 	}
 
-	u.UploadedBytes -= int(fi.Size())
-	if _, err := u.Update(context.Background(), tx, boil.Infer()); err != nil {
+	// refunds the uploader and releases the content, if nothing else refers to it
+	if err := UpdateFileReferences(tx, f.ID); err != nil {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
@@ -232,7 +307,8 @@ func DeleteFile(db *sql.DB, file_id int) error {
 		return err
 	}
 
-	if err := os.Remove(f.URI); err != nil {
+	legacyPath, err := releasedLegacyContent(tx, f)
+	if err != nil {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
@@ -240,7 +316,7 @@ func DeleteFile(db *sql.DB, file_id int) error {
 		return err
 	}
 
-	if _, err = f.Delete(context.Background(), tx, false); err != nil {
+	if err := tx.Commit(); err != nil {
 		if e := tx.Rollback(); e != nil {
 			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 		}
@@ -248,12 +324,11 @@ func DeleteFile(db *sql.DB, file_id int) error {
 		return err
 	}
 
-	if err := tx.Commit(); err != nil {
-		if e := tx.Rollback(); e != nil {
-			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+	// the content is only removed once no file refers to it anymore
+	if legacyPath != "" {
+		if err := os.Remove(legacyPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Errorf("Unable to remove content %s of deleted file %d: %s", legacyPath, f.ID, err.Error())
 		}
-
-		return err
 	}
 
 	return nil
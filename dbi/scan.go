@@ -0,0 +1,272 @@
+package dbi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"learningbay24.de/backend/config"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/scanner"
+	"learningbay24.de/backend/storage"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// How many blobs are rescanned per query
+const rescanBatchSize = 100
+
+// scanUpload checks the content of an upload for malware and returns the version of the signatures it was scanned with.
+// Infected contents are moved to the quarantine instead of being stored, and errs.ErrFileInfected is returned.
+// Contents too large to be scanned are either rejected or stored without a version, depending on the config.
+func scanUpload(db *sql.DB, name string, uploaderID int, hash string, r io.ReadSeeker, size int64) (string, error) {
+	if scanner.Enabled() && size > scanner.MaxSize() {
+		return "", unscannableUpload(name, uploaderID)
+	}
+
+	sc, err := scanner.Default()
+	if err != nil {
+		return "", err
+	}
+	version, err := sc.Version()
+	if err != nil {
+		return "", fmt.Errorf("unable to get version of malware scanner: %w", err)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	res, err := sc.Scan(r)
+	if errors.Is(err, scanner.ErrTooLarge) {
+		return "", unscannableUpload(name, uploaderID)
+	} else if err != nil {
+		return "", fmt.Errorf("unable to scan upload for malware: %w", err)
+	}
+	if !res.Infected {
+		return version, nil
+	}
+
+	log.Warnf("Upload %s of user %d is infected with %s, moving it to the quarantine", name, uploaderID, res.Signature)
+	if _, err := r.Seek(0, io.SeekStart); err == nil {
+		_, err = quarantine(db, name, null.IntFrom(uploaderID), hash, res.Signature, r, size)
+		if err != nil {
+			log.Errorf("Unable to quarantine infected upload %s of user %d: %s", name, uploaderID, err.Error())
+		}
+	}
+
+	return "", errs.ErrFileInfected
+}
+
+// unscannableUpload applies the policy for uploads that are too large to be scanned
+func unscannableUpload(name string, uploaderID int) error {
+	if config.Conf.Files.Scanner.RejectUnscannable {
+		return errs.ErrFileTooLargeToScan
+	}
+
+	log.Warnf("Upload %s of user %d is too large to be scanned for malware, storing it unscanned", name, uploaderID)
+	return nil
+}
+
+// quarantine stores an infected content in the quarantine backend and inserts a record of it, returning the ID of the record.
+// Contents that are already quarantined aren't stored again, the new record only refers to the existing copy.
+// If the record can't be inserted a newly stored content is deleted again.
+func quarantine(exec boil.ContextExecutor, name string, uploaderID null.Int, hash string, signature string, r io.Reader, size int64) (int, error) {
+	backend, err := storage.Quarantine()
+	if err != nil {
+		return 0, err
+	}
+
+	var existing struct {
+		StorageKey string `boil:"storage_key"`
+	}
+	stored := false
+	err = queries.Raw("SELECT storage_key FROM quarantine WHERE sha256 = ? ORDER BY id LIMIT 1", hash).Bind(context.Background(), exec, &existing)
+	key := existing.StorageKey
+	if errors.Is(err, sql.ErrNoRows) {
+		key, err = storage.NewKey(path.Ext(name))
+		if err != nil {
+			return 0, err
+		}
+		if err := backend.Put(key, r, size); err != nil {
+			return 0, err
+		}
+		stored = true
+	} else if err != nil {
+		return 0, err
+	}
+
+	res, err := exec.Exec("INSERT INTO quarantine (name, uploader_id, sha256, signature, storage_key) VALUES (?, ?, ?, ?, ?)",
+		name, uploaderID, hash, signature, key)
+	var id int64
+	if err == nil {
+		id, err = res.LastInsertId()
+	}
+	if err != nil {
+		if stored {
+			if e := backend.Delete(key); e != nil {
+				log.Errorf("Unable to delete quarantined content that couldn't be recorded: %s", e.Error())
+			}
+		}
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// quarantineBlob moves the content of a stored blob that was found to be infected to the quarantine.
+// The blob is kept, so that the files referring to it can't be downloaded and new uploads of the content are rejected.
+func quarantineBlob(db *sql.DB, b *blob, signature string) error {
+	backend, err := storage.Get(b.Storage)
+	if err != nil {
+		return err
+	}
+	obj, err := backend.Open(b.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	var f struct {
+		Name string `boil:"name"`
+	}
+	err = queries.Raw("SELECT name FROM file WHERE blob_id = ? ORDER BY id LIMIT 1", b.ID).Bind(context.Background(), db, &f)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	id, err := quarantine(tx, f.Name, null.Int{}, b.Sha256.String, signature, obj, b.Size)
+	if err == nil {
+		_, err = tx.Exec("UPDATE file_blob SET quarantine_id = ?, scanned_version = NULL WHERE id = ?", id, b.ID)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return fmt.Errorf("unable to commit transaction: %w", e)
+	}
+
+	// the original is only deleted once the blob refers to the quarantined copy
+	if err := backend.Delete(b.StorageKey); err != nil && !errors.Is(err, storage.ErrNotExist) {
+		log.Errorf("Unable to delete infected content %s of blob %d from storage backend %s: %s", b.StorageKey, b.ID, b.Storage, err.Error())
+	}
+
+	return nil
+}
+
+// RescanBlobs checks all stored contents that weren't scanned with the given version of the signatures yet
+// and moves the ones found to be infected to the quarantine. Contents too large to be scanned are skipped,
+// as are contents that can't be scanned, which are tried again with the next run.
+func RescanBlobs(db *sql.DB, sc scanner.Scanner, version string) error {
+	infected, failed := 0, 0
+	// the blobs are walked by their ID, so that blobs which can't be scanned don't keep the run from progressing
+	lastId := 0
+	for {
+		var blobs []*blob
+		err := queries.Raw("SELECT "+blobColumns+" FROM file_blob WHERE id > ? AND quarantine_id IS NULL AND sha256 IS NOT NULL AND size <= ? AND (scanned_version IS NULL OR scanned_version <> ?) ORDER BY id LIMIT ?",
+			lastId, scanner.MaxSize(), version, rescanBatchSize).Bind(context.Background(), db, &blobs)
+		if err != nil {
+			return err
+		}
+		if len(blobs) == 0 {
+			break
+		}
+
+		for _, b := range blobs {
+			lastId = b.ID
+
+			res, err := rescanBlob(sc, b)
+			if err != nil {
+				log.Errorf("Unable to rescan blob %d: %s", b.ID, err.Error())
+				failed++
+				continue
+			}
+
+			if res.Infected {
+				log.Warnf("Stored content of blob %d is infected with %s, moving it to the quarantine", b.ID, res.Signature)
+				if err := quarantineBlob(db, b, res.Signature); err != nil {
+					log.Errorf("Unable to quarantine blob %d: %s", b.ID, err.Error())
+					failed++
+					continue
+				}
+				infected++
+				continue
+			}
+
+			_, err = db.Exec("UPDATE file_blob SET scanned_version = ? WHERE id = ?", version, b.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if infected > 0 {
+		log.Warnf("Moved %d infected contents to the quarantine", infected)
+	}
+	if failed > 0 {
+		return fmt.Errorf("unable to rescan %d contents", failed)
+	}
+	return nil
+}
+
+// rescanBlob scans the content of a blob. Missing contents are considered clean, as there is nothing left to serve.
+func rescanBlob(sc scanner.Scanner, b *blob) (scanner.Result, error) {
+	backend, err := storage.Get(b.Storage)
+	if err != nil {
+		return scanner.Result{}, err
+	}
+	obj, err := backend.Open(b.StorageKey)
+	if errors.Is(err, storage.ErrNotExist) {
+		log.Warnf("Unable to rescan missing content %s in storage backend %s", b.StorageKey, b.Storage)
+		return scanner.Result{}, nil
+	} else if err != nil {
+		return scanner.Result{}, err
+	}
+	defer obj.Close()
+
+	return sc.Scan(obj)
+}
+
+// RunRescanner rescans the stored contents whenever the signatures of the configured scanner were updated, checking for updates once per interval.
+// It never returns, so it should run in its own goroutine.
+func RunRescanner(db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := rescan(db); err != nil {
+			log.Errorf("Unable to rescan stored file contents for malware: %s", err.Error())
+		}
+
+		<-ticker.C
+	}
+}
+
+func rescan(db *sql.DB) error {
+	sc, err := scanner.Default()
+	if err != nil {
+		return err
+	}
+	version, err := sc.Version()
+	if err != nil {
+		return err
+	}
+
+	return RescanBlobs(db, sc, version)
+}
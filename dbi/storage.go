@@ -0,0 +1,308 @@
+package dbi
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"learningbay24.de/backend/config"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/storage"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// FileContent is the content of a local file, opened from the storage backend it is stored in
+type FileContent struct {
+	storage.Object
+	// SHA-256 hash of the content, hex encoded, empty if the content wasn't hashed yet
+	Sha256 string
+}
+
+// OpenFile takes a local file and returns its content from the storage backend it is stored in
+func OpenFile(exec boil.ContextExecutor, f *models.File) (*FileContent, error) {
+	if f.Local != 1 || !f.BlobID.Valid {
+		return nil, fmt.Errorf("%w: file %d has no stored content", storage.ErrNotExist, f.ID)
+	}
+
+	b, err := findBlob(exec, f.BlobID.Int)
+	if err != nil {
+		return nil, err
+	}
+	if b.QuarantineID.Valid {
+		return nil, errs.ErrFileInfected
+	}
+
+	backend, err := storage.Get(b.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := backend.Open(b.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileContent{Object: obj, Sha256: b.Sha256.String}, nil
+}
+
+// AdoptLegacyFiles moves local files saved before storage backends existed, which store their absolute path in their URI,
+// into the local backend. Files outside of the configured path can't be adopted and are left alone.
+func AdoptLegacyFiles(db *sql.DB) error {
+	files, err := models.Files(
+		models.FileWhere.Local.EQ(1),
+		models.FileWhere.BlobID.IsNull(),
+		models.FileWhere.URI.NEQ(""),
+	).All(context.Background(), db)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		rel, err := filepath.Rel(config.Conf.Files.Path, f.URI)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			log.Warnf("Unable to adopt file %d, as %s is outside of the files path", f.ID, f.URI)
+			continue
+		}
+
+		blobId, err := adoptBlob(db, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+
+		_, err = db.Exec("UPDATE file SET uri = '', blob_id = ? WHERE id = ?", blobId, f.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(files) > 0 {
+		log.Infof("Adopted %d files into the local storage backend", len(files))
+	}
+
+	return nil
+}
+
+// releasedLegacyContent returns the path of the content of a deleted local file that wasn't adopted into a storage backend,
+// if no other file refers to it. Copies of a file, e.g. in a copied course, share its URI, so it has to be kept as long as one of them is left.
+func releasedLegacyContent(exec boil.ContextExecutor, f *models.File) (string, error) {
+	if f.Local == 0 || f.BlobID.Valid || f.URI == "" {
+		return "", nil
+	}
+
+	// the files sharing the URI are locked, so that the content isn't removed while it is copied again
+	var shared struct {
+		Count int `boil:"count"`
+	}
+	err := queries.Raw("SELECT COUNT(*) AS count FROM file WHERE uri = ? AND local = 1 AND deleted_at IS NULL AND id <> ? FOR UPDATE",
+		f.URI, f.ID).Bind(context.Background(), exec, &shared)
+	if err != nil {
+		return "", err
+	}
+	if shared.Count > 0 {
+		return "", nil
+	}
+
+	return f.URI, nil
+}
+
+// adoptBlob returns the ID of the blob of a content in the local backend, creating it if needed.
+// The blob is hashed by `DeduplicateBlobs` afterwards.
+func adoptBlob(db *sql.DB, key string) (int, error) {
+	// the key is unique per backend, so servers adopting the same content at the same time get the same blob
+	res, err := db.Exec("INSERT INTO file_blob (storage, storage_key) VALUES (?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)", storage.LocalName, key)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// DeduplicateBlobs hashes the contents stored before uploads were deduplicated. Blobs with the same content are combined,
+// so that every content is stored only once, and the references to all of them are counted.
+func DeduplicateBlobs(db *sql.DB) error {
+	var blobs []*blob
+	err := queries.Raw("SELECT "+blobColumns+" FROM file_blob WHERE sha256 IS NULL").Bind(context.Background(), db, &blobs)
+	if err != nil {
+		return err
+	}
+
+	combined := 0
+	for _, b := range blobs {
+		backend, err := storage.Get(b.Storage)
+		if err != nil {
+			return err
+		}
+
+		obj, err := backend.Open(b.StorageKey)
+		if errors.Is(err, storage.ErrNotExist) {
+			log.Warnf("Unable to hash missing content %s in storage backend %s", b.StorageKey, b.Storage)
+			continue
+		} else if err != nil {
+			return err
+		}
+		h := sha256.New()
+		size, err := io.Copy(h, obj)
+		obj.Close()
+		if err != nil {
+			return fmt.Errorf("unable to hash %s: %w", b.StorageKey, err)
+		}
+
+		duplicate, err := hashBlob(db, b, hex.EncodeToString(h.Sum(nil)), size)
+		if err != nil {
+			return err
+		}
+		if !duplicate {
+			continue
+		}
+
+		combined++
+		// no other blob can refer to the key, as keys are unique per backend
+		if err := backend.Delete(b.StorageKey); err != nil {
+			log.Warnf("Unable to delete duplicate content %s from storage backend %s: %s", b.StorageKey, b.Storage, err.Error())
+		}
+	}
+
+	if len(blobs) > 0 {
+		log.Infof("Hashed %d file contents, %d of which were duplicates", len(blobs), combined)
+	}
+
+	return nil
+}
+
+// hashBlob stores the hash and size of a blob. If another blob already has the same hash, the files of the blob refer to that one instead
+// and the blob is removed, which is reported by returning true. Blobs hashed by another server in the meantime are left alone.
+func hashBlob(db *sql.DB, b *blob, hash string, size int64) (bool, error) {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	current := &blob{}
+	err = queries.Raw("SELECT "+blobColumns+" FROM file_blob WHERE id = ? AND sha256 IS NULL FOR UPDATE", b.ID).Bind(context.Background(), tx, current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, tx.Rollback()
+	}
+
+	existing := &blob{}
+	if err == nil {
+		err = queries.Raw("SELECT "+blobColumns+" FROM file_blob WHERE sha256 = ? FOR UPDATE", hash).Bind(context.Background(), tx, existing)
+	}
+	duplicate := err == nil && existing.ID != b.ID
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+
+	if err == nil {
+		err = chargeLegacyUpload(tx, b.ID, size)
+	}
+
+	if err == nil && duplicate {
+		_, err = tx.Exec("UPDATE file SET blob_id = ? WHERE blob_id = ?", existing.ID, b.ID)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM file_blob WHERE id = ?", b.ID)
+		}
+		if err == nil {
+			_, err = refreshBlob(tx, existing.ID)
+		}
+	} else if err == nil {
+		_, err = tx.Exec("UPDATE file_blob SET sha256 = ?, size = ? WHERE id = ?", hash, size, b.ID)
+		if err == nil {
+			_, err = refreshBlob(tx, b.ID)
+		}
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return false, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return false, err
+	}
+
+	if e := tx.Commit(); e != nil {
+		return false, fmt.Errorf("unable to commit transaction: %w", e)
+	}
+
+	return duplicate, nil
+}
+
+// chargeLegacyUpload records the bytes charged for a content stored before uploads were deduplicated.
+// Back then only the original upload was charged, not its copies, so the oldest remaining file of the blob is the one to be refunded when it is deleted.
+func chargeLegacyUpload(exec boil.ContextExecutor, blobId int, size int64) error {
+	var charged struct {
+		Count int `boil:"count"`
+	}
+	err := queries.Raw("SELECT COUNT(*) AS count FROM file WHERE blob_id = ? AND charged_bytes > 0", blobId).Bind(context.Background(), exec, &charged)
+	if err != nil || charged.Count > 0 {
+		return err
+	}
+
+	_, err = exec.Exec("UPDATE file SET charged_bytes = ? WHERE blob_id = ? AND deleted_at IS NULL ORDER BY id LIMIT 1", size, blobId)
+	return err
+}
+
+// MigrateStorage takes the name of a storage backend and moves the contents of all blobs stored in other backends into it.
+// Contents are copied before the blobs refer to their new location and only deleted from their old backend afterwards,
+// so that the server can keep running during the migration.
+func MigrateStorage(db *sql.DB, target string) error {
+	to, err := storage.Get(target)
+	if err != nil {
+		return err
+	}
+
+	var blobs []*blob
+	// the contents of quarantined blobs are already gone
+	err = queries.Raw("SELECT "+blobColumns+" FROM file_blob WHERE storage <> ? AND quarantine_id IS NULL", target).Bind(context.Background(), db, &blobs)
+	if err != nil {
+		return err
+	}
+
+	moved := 0
+	for _, b := range blobs {
+		from, err := storage.Get(b.Storage)
+		if err != nil {
+			return err
+		}
+
+		obj, err := from.Open(b.StorageKey)
+		if errors.Is(err, storage.ErrNotExist) {
+			log.Warnf("Skipping missing content %s in storage backend %s", b.StorageKey, b.Storage)
+			continue
+		} else if err != nil {
+			return err
+		}
+		err = to.Put(b.StorageKey, obj, obj.Size())
+		obj.Close()
+		if err != nil {
+			return fmt.Errorf("unable to copy %s: %w", b.StorageKey, err)
+		}
+
+		_, err = db.Exec("UPDATE file_blob SET storage = ? WHERE id = ?", target, b.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := from.Delete(b.StorageKey); err != nil {
+			log.Warnf("Unable to delete %s from storage backend %s after moving it: %s", b.StorageKey, b.Storage, err.Error())
+		}
+
+		moved++
+		log.Debugf("Moved %s from %s to %s", b.StorageKey, b.Storage, target)
+	}
+
+	log.Infof("Moved %d of %d file contents to storage backend %s", moved, len(blobs), target)
+	return nil
+}
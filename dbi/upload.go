@@ -0,0 +1,374 @@
+package dbi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"learningbay24.de/backend/config"
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/storage"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// How long uploads are kept after their last chunk was received, so that clients can resume them after losing their connection
+const uploadExpiry = 24 * time.Hour
+
+// Upload is a file uploaded in chunks, see the upload table. It is saved as a file once all of its bytes were received.
+type Upload struct {
+	ID         int       `boil:"id" json:"id"`
+	UploaderID int       `boil:"uploader_id" json:"-"`
+	Name       string    `boil:"name" json:"name"`
+	Size       int64     `boil:"size" json:"size"`
+	Received   int64     `boil:"received" json:"received"`
+	CreatedAt  time.Time `boil:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `boil:"updated_at" json:"updated_at"`
+}
+
+const uploadColumns = "id, uploader_id, name, size, received, created_at, updated_at"
+
+// Complete returns whether all bytes of the upload were received
+func (u *Upload) Complete() bool {
+	return u.Received == u.Size
+}
+
+// A chunk of an upload, see the upload_chunk table
+type uploadChunk struct {
+	ID         int    `boil:"id"`
+	Offset     int64  `boil:"offset"`
+	Size       int64  `boil:"size"`
+	Storage    string `boil:"storage"`
+	StorageKey string `boil:"storage_key"`
+}
+
+const uploadChunkColumns = "id, `offset`, size, storage, storage_key"
+
+func uploadChunks(exec boil.ContextExecutor, uploadId int) ([]*uploadChunk, error) {
+	var chunks []*uploadChunk
+	err := queries.Raw("SELECT "+uploadChunkColumns+" FROM upload_chunk WHERE upload_id = ? ORDER BY `offset`", uploadId).Bind(context.Background(), exec, &chunks)
+	if err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func deleteChunkContent(storageName string, key string) {
+	backend, err := storage.Get(storageName)
+	if err == nil {
+		err = backend.Delete(key)
+	}
+	if err != nil && !errors.Is(err, storage.ErrNotExist) {
+		log.Warnf("Unable to delete upload chunk %s from storage backend %s: %s", key, storageName, err.Error())
+	}
+}
+
+// CreateUpload starts an upload of a file with the given name and size in bytes. The file has to be allowed by the policy
+// and fit into the upload limit of the uploader, counting the uploads they haven't finished yet.
+func CreateUpload(db *sql.DB, uploaderID int, name string, size int64, policy FilePolicy) (*Upload, error) {
+	if name == "" {
+		return nil, errs.ErrEmptyFileName
+	}
+	ext := path.Ext(name)
+	if ext == "" {
+		return nil, errs.ErrNoFileExtension
+	}
+	if !policy.allows(ext[1:]) {
+		return nil, errs.ErrFileExtensionNotAllowed
+	}
+	if size < 0 {
+		return nil, errs.ErrBodyConversion
+	}
+	if policy.MaxSize != 0 && size > policy.MaxSize {
+		return nil, errs.ErrFileTooLarge
+	}
+
+	user, err := models.FindUser(context.Background(), db, uploaderID, models.UserColumns.ID, models.UserColumns.UploadedBytes)
+	if err != nil {
+		return nil, err
+	}
+	if config.Conf.Files.MaxUploadPerUser != 0 {
+		var pending struct {
+			Size int64 `boil:"size"`
+		}
+		err = queries.Raw("SELECT COALESCE(SUM(size), 0) AS size FROM upload WHERE uploader_id = ?", uploaderID).Bind(context.Background(), db, &pending)
+		if err != nil {
+			return nil, err
+		}
+		if int64(user.UploadedBytes)+pending.Size+size > int64(config.Conf.Files.MaxUploadPerUser) {
+			return nil, errs.ErrUploadLimitReached
+		}
+	}
+
+	res, err := db.Exec("INSERT INTO upload (uploader_id, name, size) VALUES (?, ?, ?)", uploaderID, name, size)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetUpload(db, uploaderID, int(id))
+}
+
+// GetUpload returns an upload of the given user
+func GetUpload(db *sql.DB, uploaderID int, id int) (*Upload, error) {
+	u := &Upload{}
+	err := queries.Raw("SELECT "+uploadColumns+" FROM upload WHERE id = ? AND uploader_id = ?", id, uploaderID).Bind(context.Background(), db, u)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// WriteUploadChunk appends a chunk read from r to an upload of the given user and returns how many bytes were received in total.
+// The offset has to match the bytes received so far. If reading the chunk fails, the bytes read until then are kept, so the client can resume from there.
+// Chunks are kept in the storage backend, so that any server can receive the next one. If several are sent at once, only the first one to be recorded is kept.
+func WriteUploadChunk(db *sql.DB, uploaderID int, id int, offset int64, r io.Reader) (int64, error) {
+	u, err := GetUpload(db, uploaderID, id)
+	if err != nil {
+		return 0, err
+	}
+	if offset != u.Received {
+		return u.Received, errs.ErrUploadOffsetMismatch
+	}
+
+	// the chunk is spooled first, as its size has to be known to store it and a broken connection shouldn't lose what was received
+	tmp, err := os.CreateTemp("", "learningbay24-chunk-*")
+	if err != nil {
+		return u.Received, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	n, readErr := io.Copy(tmp, io.LimitReader(r, u.Size-u.Received))
+	if readErr == nil && n == u.Size-u.Received {
+		// the chunk must not be larger than what is left of the upload
+		if m, _ := r.Read(make([]byte, 1)); m > 0 {
+			readErr = errs.ErrFileTooLarge
+		}
+	}
+	if n == 0 {
+		return u.Received, readErr
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return u.Received, err
+	}
+
+	backend, err := storage.Default()
+	if err != nil {
+		return u.Received, err
+	}
+	key, err := storage.NewKey("")
+	if err != nil {
+		return u.Received, err
+	}
+	if err := backend.Put(key, tmp, n); err != nil {
+		return u.Received, err
+	}
+
+	received, err := addUploadChunk(db, id, offset, n, backend.Name(), key)
+	if err != nil {
+		deleteChunkContent(backend.Name(), key)
+		return received, err
+	}
+
+	return received, readErr
+}
+
+// addUploadChunk records a stored chunk, unless another chunk was recorded at the same offset in the meantime
+func addUploadChunk(db *sql.DB, id int, offset int64, size int64, storageName string, key string) (int64, error) {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return offset, err
+	}
+
+	var u struct {
+		Received int64 `boil:"received"`
+	}
+	err = queries.Raw("SELECT received FROM upload WHERE id = ? FOR UPDATE", id).Bind(context.Background(), tx, &u)
+	if err == nil && u.Received != offset {
+		err = errs.ErrUploadOffsetMismatch
+	}
+	if err == nil {
+		_, err = tx.Exec("INSERT INTO upload_chunk (upload_id, `offset`, size, storage, storage_key) VALUES (?, ?, ?, ?, ?)", id, offset, size, storageName, key)
+	}
+	if err == nil {
+		_, err = tx.Exec("UPDATE upload SET received = received + ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", size, id)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return u.Received, fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return u.Received, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return offset, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return offset + size, nil
+}
+
+// chunkReader reads the chunks of an upload one after another
+type chunkReader struct {
+	chunks  []*uploadChunk
+	current storage.Object
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+
+			backend, err := storage.Get(r.chunks[0].Storage)
+			if err != nil {
+				return 0, err
+			}
+			obj, err := backend.Open(r.chunks[0].StorageKey)
+			if err != nil {
+				return 0, err
+			}
+			r.current = obj
+			r.chunks = r.chunks[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// OpenUpload opens the content of a complete upload of the given user, so that it can be saved as a file
+func OpenUpload(db *sql.DB, uploaderID int, id int) (*Upload, io.ReadCloser, error) {
+	u, err := GetUpload(db, uploaderID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !u.Complete() {
+		return nil, nil, errs.ErrUploadIncomplete
+	}
+
+	chunks, err := uploadChunks(db, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the chunks have to cover the upload without gaps
+	var next int64
+	for _, c := range chunks {
+		if c.Offset != next {
+			return nil, nil, fmt.Errorf("upload %d is missing the chunk at %d", id, next)
+		}
+		next += c.Size
+	}
+	if next != u.Size {
+		return nil, nil, fmt.Errorf("upload %d is missing the chunk at %d", id, next)
+	}
+
+	return u, &chunkReader{chunks: chunks}, nil
+}
+
+// DeleteUpload deletes an upload of the given user together with its chunks,
+// either because it was canceled or because it was saved as a file
+func DeleteUpload(db *sql.DB, uploaderID int, id int) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	// the upload is locked, so that no chunk is added while it is deleted
+	var u struct {
+		ID int `boil:"id"`
+	}
+	err = queries.Raw("SELECT id FROM upload WHERE id = ? AND uploader_id = ? FOR UPDATE", id, uploaderID).Bind(context.Background(), tx, &u)
+	var chunks []*uploadChunk
+	if err == nil {
+		chunks, err = uploadChunks(tx, id)
+	}
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM upload_chunk WHERE upload_id = ?", id)
+	}
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM upload WHERE id = ?", id)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	// the chunks are only deleted once nothing refers to them anymore
+	for _, c := range chunks {
+		deleteChunkContent(c.Storage, c.StorageKey)
+	}
+	return nil
+}
+
+// CollectUploads deletes uploads that weren't continued or saved as a file for longer than the expiry
+func CollectUploads(db *sql.DB) error {
+	var expired []*Upload
+	err := queries.Raw("SELECT "+uploadColumns+" FROM upload WHERE updated_at < NOW() - INTERVAL ? SECOND",
+		int(uploadExpiry.Seconds())).Bind(context.Background(), db, &expired)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range expired {
+		if err := DeleteUpload(db, u.UploaderID, u.ID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("unable to delete expired upload %d: %w", u.ID, err)
+		}
+	}
+	if len(expired) > 0 {
+		log.Infof("Deleted %d abandoned uploads", len(expired))
+	}
+
+	return nil
+}
+
+// RunUploadCollector deletes abandoned uploads once per interval. It never returns, so it should run in its own goroutine.
+func RunUploadCollector(db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := CollectUploads(db); err != nil {
+			log.Errorf("Unable to delete abandoned uploads: %s", err.Error())
+		}
+
+		<-ticker.C
+	}
+}
@@ -92,7 +92,16 @@ func DeleteUser(db *sql.DB, id int) error {
 	}
 	flog.Infof("Deleted %d entries from user_submission", us)
 
-	f, err := models.Files(models.FileWhere.UploaderID.EQ(id)).DeleteAll(context.Background(), tx, false)
+	files, err := models.Files(models.FileWhere.UploaderID.EQ(id)).All(context.Background(), tx)
+	if err != nil {
+		flog.Errorf("Unable to get files: %s", err.Error())
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
+	f, err := files.DeleteAll(context.Background(), tx, false)
 	if err != nil {
 		flog.Errorf("Unable to delete files: %s", err.Error())
 		if e := tx.Rollback(); e != nil {
@@ -102,6 +111,20 @@ func DeleteUser(db *sql.DB, id int) error {
 	}
 	flog.Infof("Deleted %d entries from file", f)
 
+	fileIds := make([]int, len(files))
+	for i, file := range files {
+		fileIds[i] = file.ID
+	}
+	// contents shared with other users' files are kept
+	err = UpdateFileReferences(tx, fileIds...)
+	if err != nil {
+		flog.Errorf("Unable to update file references: %s", err.Error())
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
 	// don't delete forum_entry
 
 	notif, err := models.Notifications(models.NotificationWhere.UserToID.EQ(id)).DeleteAll(context.Background(), tx, false)
@@ -134,7 +157,41 @@ func DeleteUser(db *sql.DB, id int) error {
 	}
 	flog.Infof("Deleted %d entries from user", user)
 
-	// TODO: user_has_field_of_study?
+	ufos, err := tx.Exec("DELETE FROM user_has_field_of_study WHERE user_id = ?", id)
+	if err != nil {
+		flog.Errorf("Unable to delete user_has_field_of_study: %s", err.Error())
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+	if n, err := ufos.RowsAffected(); err == nil {
+		flog.Infof("Deleted %d entries from user_has_field_of_study", n)
+	}
+
+	er, err := tx.Exec("DELETE FROM enrollment_request WHERE user_id = ?", id)
+	if err != nil {
+		flog.Errorf("Unable to delete enrollment_request: %s", err.Error())
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+	if n, err := er.RowsAffected(); err == nil {
+		flog.Infof("Deleted %d entries from enrollment_request", n)
+	}
+
+	cf, err := tx.Exec("DELETE FROM calendar_feed WHERE user_id = ?", id)
+	if err != nil {
+		flog.Errorf("Unable to delete calendar_feed: %s", err.Error())
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+	if n, err := cf.RowsAffected(); err == nil {
+		flog.Infof("Deleted %d entries from calendar_feed", n)
+	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("unable to commit transaction: %w", err)
@@ -7,6 +7,12 @@ import (
 var (
 	ErrFileExtensionNotAllowed error = errors.New("File extension is not allowed")
 	ErrNoFileExtension         error = errors.New("File has no extension")
+	ErrFileContentMismatch     error = errors.New("File content doesn't match its extension")
+	ErrUnsafeArchive           error = errors.New("Archive contains unsafe paths or extracts to too much data")
+	ErrUncheckableArchive      error = errors.New("Archives of this type can't be checked and aren't accepted, use zip or tar instead")
+	ErrFileTooLarge            error = errors.New("File is larger than allowed")
+	ErrFileInfected            error = errors.New("File contains malware")
+	ErrFileTooLargeToScan      error = errors.New("File is too large to be scanned for malware")
 	ErrEmptyFileName           error = errors.New("Filename can't be empty")
 	ErrEmptyName               error = errors.New("Name can't be empty")
 
@@ -16,6 +22,10 @@ var (
 	ErrNotCourseAdmin     error = errors.New("Course admin permissions required")
 	ErrNotCourseModerator error = errors.New("Course moderator permissions required")
 	ErrNotCourseUser      error = errors.New("Course user permissions required")
+	ErrNotEntryAuthor     error = errors.New("Only the author can edit this entry")
+
+	ErrInvalidSignature    error = errors.New("Invalid signature of the download link")
+	ErrDownloadLinkExpired error = errors.New("Download link has expired")
 
 	ErrParameterConversion error = errors.New("Unable to convert parameter item")
 	ErrNoQuery             error = errors.New("Unable to find query parameter")
@@ -31,11 +41,51 @@ var (
 	ErrDeleteExamNotEmpty       error = errors.New("Cannot delete exam when users are still registered")
 	ErrExamHasntEnded           error = errors.New("Exam hasn't ended yet")
 
-	ErrNoUploads          error = errors.New("This item doesn't have any associated uploads")
-	ErrUploadLimitReached error = errors.New("The upload limit has been reached")
+	ErrNoUploads            error = errors.New("This item doesn't have any associated uploads")
+	ErrUploadLimitReached   error = errors.New("The upload limit has been reached")
+	ErrUploadIncomplete     error = errors.New("Upload hasn't received all of its bytes yet")
+	ErrUploadOffsetMismatch error = errors.New("Offset doesn't match the bytes received so far")
+
+	ErrCourseNotEmpty    error = errors.New("Course is not empty")
+	ErrWrongEnrollkey    error = errors.New("Wrong enroll key")
+	ErrCourseAdminRole   error = errors.New("The course admin can only change by transferring the ownership")
+	ErrEnrollmentClosed  error = errors.New("Course is closed for enrollment")
+	ErrInvalidEnrollMode error = errors.New("Invalid enroll mode")
+	ErrInvalidCapacity   error = errors.New("Capacity has to be at least 1")
+	ErrCourseFull        error = errors.New("Course is full")
+	ErrCourseArchived    error = errors.New("Course is archived and can't be changed")
+
+	ErrInvalidCourseRole error = errors.New("Invalid course role")
+	ErrInvalidInviteUses error = errors.New("Invites have to be usable at least once")
+	ErrInviteExpiryPast  error = errors.New("Expiry date of the invite can't be in the past")
+	ErrInviteExpired     error = errors.New("Invite has expired")
+	ErrInviteUsedUp      error = errors.New("Invite has been used up")
+	ErrInvalidCSV        error = errors.New("Invalid CSV file")
+	ErrInvalidArchive    error = errors.New("Invalid course archive")
+
+	ErrMissingPrerequisites error = errors.New("Certificates of required courses are missing")
+	ErrPrerequisiteExists   error = errors.New("Course is already required")
+	ErrPrerequisiteCycle    error = errors.New("Courses can't require each other")
+
+	ErrInvalidRepeatDistance error = errors.New("Invalid repeat distance")
+	ErrInvalidAppointment    error = errors.New("Appointments need a date and a positive duration")
+	ErrSeriesEndMissing      error = errors.New("Repeating appointments need an end date or a number of occurrences")
+	ErrSeriesEndBeforeStart  error = errors.New("End date of the series can't be before its first appointment")
+	ErrTooManyOccurrences    error = errors.New("Series has too many appointments")
+	ErrInvalidICal           error = errors.New("Invalid iCalendar file")
+	ErrUnsupportedRecurrence error = errors.New("Recurrence rule is not supported")
+	ErrInvalidTimeRange      error = errors.New("Start of the time range can't be after its end")
+	ErrInvalidPagination     error = errors.New("Page has to be at least 1 and page size between 1 and 100")
+	ErrSearchTermTooShort    error = errors.New("Search term needs a word of at least 3 characters")
+
+	ErrInvalidSemester error = errors.New("Semester is out of range")
+	ErrSemesterNotSet  error = errors.New("User hasn't set their semester")
+
+	ErrDirectoryCycle error = errors.New("Directory can't be moved into itself")
 
-	ErrCourseNotEmpty error = errors.New("Course is not empty")
-	ErrWrongEnrollkey error = errors.New("Wrong enroll key")
+	ErrEmptySubject   error = errors.New("Subject can't be empty")
+	ErrSubjectTooLong error = errors.New("Subject can't be longer than 64 characters")
+	ErrEmptyContent   error = errors.New("Content can't be empty")
 
 	ErrVisibleTimePast                        error = errors.New("VisibleFrom time can't be in the past")
 	ErrDeadlineTimePast                       error = errors.New("Deadline time can't be in the past")
@@ -8,10 +8,12 @@ import (
 	"strconv"
 	"time"
 
+	"learningbay24.de/backend/certificate"
 	"learningbay24.de/backend/course"
 	"learningbay24.de/backend/dbi"
 	"learningbay24.de/backend/errs"
 	"learningbay24.de/backend/models"
+	"learningbay24.de/backend/notification"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/volatiletech/null/v8"
@@ -188,6 +190,9 @@ func (p *PublicController) CreateExam(name, description string, date time.Time,
 	if err != nil {
 		return 0, err
 	}
+	if c.ArchivedAt.Valid {
+		return 0, errs.ErrCourseArchived
+	}
 	if name == "" {
 		y, m, d := date.Date()
 		creator, err := models.FindUser(context.Background(), p.Database, creatorId)
@@ -289,6 +294,9 @@ func (p *PublicController) UploadExamFile(fileName string, uri string, uploaderI
 	if err != nil {
 		return err
 	}
+	if err := dbi.CheckCourseWritable(p.Database, ex.CourseID); err != nil {
+		return err
+	}
 
 	fileId, err := dbi.SaveFile(p.Database, fileName, uri, uploaderId, local, &file, fileSize)
 	if err != nil {
@@ -330,6 +338,16 @@ func (p *PublicController) UploadExamFile(fileName string, uri string, uploaderI
 		return err
 	}
 
+	err = dbi.UpdateFileReferences(tx, fileId)
+	if err != nil {
+		// NOTE: disregard error, only god can help us now
+		_ = dbi.DeleteFile(p.Database, fileId)
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+		return err
+	}
+
 	if e := tx.Commit(); e != nil {
 		// NOTE: disregard error, only god can help us now
 		_ = dbi.DeleteFile(p.Database, fileId)
@@ -341,7 +359,7 @@ func (p *PublicController) UploadExamFile(fileName string, uri string, uploaderI
 // DeleteExamFile takes a transaction and examId and deletes the file associated to the exam
 func (p *PublicController) DeleteExamFile(tx *sql.Tx, examId int) error {
 	var files []*models.File
-	err := queries.Raw("select * from file, exam_has_files where exam_has_files.exam_id=? AND exam_has_files.file_id = file.id AND file.id is null", examId).Bind(context.Background(), p.Database, &files)
+	err := queries.Raw("select * from file, exam_has_files where exam_has_files.exam_id=? AND exam_has_files.file_id = file.id AND file.deleted_at is null", examId).Bind(context.Background(), tx, &files)
 	if err != nil {
 		return err
 	}
@@ -351,6 +369,11 @@ func (p *PublicController) DeleteExamFile(tx *sql.Tx, examId int) error {
 		if err != nil {
 			return err
 		}
+
+		err = dbi.UpdateFileReferences(tx, files[0].ID)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -366,6 +389,9 @@ func (p *PublicController) RegisterToExam(userId, examId int) (*models.User, err
 	if ex.CreatorID == userId {
 		return nil, errs.ErrSelfRegisterExam
 	}
+	if err := dbi.CheckCourseWritable(p.Database, ex.CourseID); err != nil {
+		return nil, err
+	}
 
 	u, err := models.FindUser(context.Background(), p.Database, userId)
 	if err != nil {
@@ -485,6 +511,14 @@ func (p *PublicController) GetFileFromExam(examId int) ([]*models.File, error) {
 
 // SubmitAnswer takes a filename, uri, local-indicator, file, examId, and userId and uploads the file as an answer
 func (p *PublicController) SubmitAnswer(fileName, uri string, examId, userId int, local bool, file io.Reader, fileSize int) error {
+	ex, err := p.GetExamByID(examId)
+	if err != nil {
+		return err
+	}
+	if err := dbi.CheckCourseWritable(p.Database, ex.CourseID); err != nil {
+		return err
+	}
+
 	fileId, err := dbi.SaveFile(p.Database, fileName, uri, userId, local, &file, fileSize)
 	if err != nil {
 		return err
@@ -494,6 +528,7 @@ func (p *PublicController) SubmitAnswer(fileName, uri string, examId, userId int
 	if err != nil {
 		return err
 	}
+	previous := uhex.FileID
 	fid := null.IntFrom(fileId)
 	uhex.FileID = fid
 
@@ -501,6 +536,16 @@ func (p *PublicController) SubmitAnswer(fileName, uri string, examId, userId int
 	if err != nil {
 		return err
 	}
+
+	err = dbi.UpdateFileReferences(p.Database, fileId)
+	if err != nil {
+		return err
+	}
+
+	// the previous answer is replaced, so its uploader isn't charged for it anymore
+	if previous.Valid && previous.Int != fileId {
+		return dbi.DeleteFile(p.Database, previous.Int)
+	}
 	return nil
 }
 
@@ -592,6 +637,20 @@ func (p *PublicController) GradeAnswer(examId, creatorId, userId int, grade null
 		return err
 	}
 
+	// re-grading an answer as not passed takes away the certificate again
+	if passed.Valid && passed.Int8 == 1 {
+		_, err = certificate.Issue(tx, userId, ex.CourseID, examId)
+	} else {
+		err = certificate.Revoke(tx, userId, examId)
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
 	attendees, err := p.GetRegisteredUsersFromExam(examId, creatorId)
 	if err != nil {
 		if e := tx.Rollback(); e != nil {
@@ -606,6 +665,7 @@ func (p *PublicController) GradeAnswer(examId, creatorId, userId int, grade null
 			if e := tx.Commit(); e != nil {
 				return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
 			}
+			p.notifyGraded(examId, userId)
 			return nil
 		}
 	}
@@ -626,10 +686,18 @@ func (p *PublicController) GradeAnswer(examId, creatorId, userId int, grade null
 
 		return err
 	}
+	p.notifyGraded(examId, userId)
 	return nil
 
 }
 
+// notifyGraded notifies the user about their graded answer; failing to do so doesn't undo the grading
+func (p *PublicController) notifyGraded(examId, userId int) {
+	if err := notification.NotifyExamGraded(p.Database, examId, userId); err != nil {
+		log.Errorf("Unable to notify user about graded exam: %s", err.Error())
+	}
+}
+
 // SetAttended takes an examId and userId and sets the corresponding registered exam of the user to attended
 func (p *PublicController) SetAttended(examId, userId int) error {
 	uhex, err := models.FindUserHasExam(context.Background(), p.Database, userId, examId)
@@ -0,0 +1,232 @@
+// Package fieldofstudy implements fields of study, the courses that make up their curriculum and the users that study them
+package fieldofstudy
+
+import (
+	"context"
+	"database/sql"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// CurriculumCourse is a course that is part of a field of study
+type CurriculumCourse struct {
+	CourseID int    `boil:"course_id" json:"course_id"`
+	Name     string `boil:"name" json:"name"`
+	Semester int    `boil:"semester" json:"semester"`
+	Enrolled bool   `boil:"enrolled" json:"enrolled"`
+	Passed   bool   `boil:"passed" json:"passed"`
+}
+
+// Curriculum lists the courses a user is supposed to take in their current semester of a field of study.
+// Courses of earlier semesters that the user hasn't passed yet are included as well.
+type Curriculum struct {
+	FieldOfStudy *models.FieldOfStudy `json:"field_of_study"`
+	Semester     int                  `json:"semester"`
+	Courses      []*CurriculumCourse  `json:"courses"`
+}
+
+// GetFieldsOfStudy returns all fields of study ordered by their name
+func GetFieldsOfStudy(db *sql.DB) (models.FieldOfStudySlice, error) {
+	fos, err := models.FieldOfStudies(qm.OrderBy(models.FieldOfStudyColumns.Name)).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	return fos, nil
+}
+
+// CreateFieldOfStudy takes a name and the amount of semesters and creates a field of study
+func CreateFieldOfStudy(db *sql.DB, name string, semesters int) (int, error) {
+	if name == "" {
+		return 0, errs.ErrEmptyName
+	}
+	if semesters < 1 {
+		return 0, errs.ErrInvalidSemester
+	}
+
+	fos := models.FieldOfStudy{Name: null.StringFrom(name), Semesters: null.IntFrom(semesters)}
+	err := fos.Insert(context.Background(), db, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	return fos.ID, nil
+}
+
+// GetCoursesFromFieldOfStudy takes the ID of a field of study and returns its courses ordered by semester
+func GetCoursesFromFieldOfStudy(db *sql.DB, fieldOfStudyId int) ([]*CurriculumCourse, error) {
+	if _, err := models.FindFieldOfStudy(context.Background(), db, fieldOfStudyId); err != nil {
+		return nil, err
+	}
+
+	courses := []*CurriculumCourse{}
+	err := queries.Raw(`SELECT c.id AS course_id, c.name, fhc.semester FROM field_of_study_has_course fhc
+		JOIN course c ON c.id = fhc.course_id
+		WHERE fhc.field_of_study_id = ? AND c.deleted_at IS NULL
+		ORDER BY fhc.semester, c.name`, fieldOfStudyId).Bind(context.Background(), db, &courses)
+	if err != nil {
+		return nil, err
+	}
+
+	return courses, nil
+}
+
+// SetCourseSemester takes the ID of a field of study, of a course and a semester and adds the course to the field of study in that semester.
+// If the course is already part of the field of study, it is moved to the given semester.
+func SetCourseSemester(db *sql.DB, fieldOfStudyId int, courseId int, semester int) error {
+	fos, err := models.FindFieldOfStudy(context.Background(), db, fieldOfStudyId)
+	if err != nil {
+		return err
+	}
+
+	if semester < 1 || (fos.Semesters.Valid && semester > fos.Semesters.Int) {
+		return errs.ErrInvalidSemester
+	}
+
+	if _, err := models.FindCourse(context.Background(), db, courseId); err != nil {
+		return err
+	}
+
+	fhc := models.FieldOfStudyHasCourse{FieldOfStudyID: fieldOfStudyId, CourseID: courseId, Semester: semester}
+	err = fhc.Upsert(context.Background(), db, boil.Whitelist(models.FieldOfStudyHasCourseColumns.Semester), boil.Infer())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemoveCourseFromFieldOfStudy takes the ID of a field of study and of one of its courses and removes the course from it
+func RemoveCourseFromFieldOfStudy(db *sql.DB, fieldOfStudyId int, courseId int) error {
+	fhc, err := models.FindFieldOfStudyHasCourse(context.Background(), db, fieldOfStudyId, courseId)
+	if err != nil {
+		return err
+	}
+
+	_, err = fhc.Delete(context.Background(), db)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetFieldsOfStudyFromUser takes the ID of a user and returns the fields of study they are studying
+func GetFieldsOfStudyFromUser(db *sql.DB, userId int) (models.FieldOfStudySlice, error) {
+	u, err := models.FindUser(context.Background(), db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	fos, err := u.FieldOfStudies(qm.OrderBy(models.FieldOfStudyTableColumns.Name)).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	return fos, nil
+}
+
+// JoinFieldOfStudy takes the ID of a user and of a field of study and makes the user study it.
+// Joining a field of study the user already studies is not an error.
+func JoinFieldOfStudy(db *sql.DB, userId int, fieldOfStudyId int) error {
+	u, err := models.FindUser(context.Background(), db, userId)
+	if err != nil {
+		return err
+	}
+
+	fos, err := models.FindFieldOfStudy(context.Background(), db, fieldOfStudyId)
+	if err != nil {
+		return err
+	}
+
+	joined, err := u.FieldOfStudies(models.FieldOfStudyWhere.ID.EQ(fieldOfStudyId)).Exists(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	if joined {
+		return nil
+	}
+
+	err = u.AddFieldOfStudies(context.Background(), db, false, fos)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LeaveFieldOfStudy takes the ID of a user and of a field of study they are studying and removes them from it
+func LeaveFieldOfStudy(db *sql.DB, userId int, fieldOfStudyId int) error {
+	u, err := models.FindUser(context.Background(), db, userId)
+	if err != nil {
+		return err
+	}
+
+	fos, err := u.FieldOfStudies(models.FieldOfStudyWhere.ID.EQ(fieldOfStudyId)).One(context.Background(), db)
+	if err != nil {
+		return err
+	}
+
+	err = u.RemoveFieldOfStudies(context.Background(), db, fos)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetCurriculum takes the ID of a user and returns their curriculum in every field of study they are studying, based on the semester they are in
+func GetCurriculum(db *sql.DB, userId int) ([]*Curriculum, error) {
+	u, err := models.FindUser(context.Background(), db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.Semester.Valid || u.Semester.Int < 1 {
+		return nil, errs.ErrSemesterNotSet
+	}
+
+	fos, err := GetFieldsOfStudyFromUser(db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	curricula := make([]*Curriculum, 0, len(fos))
+	for _, f := range fos {
+		courses := []*CurriculumCourse{}
+		err := queries.Raw(`SELECT c.id AS course_id, c.name, fhc.semester,
+			EXISTS (
+				SELECT 1 FROM user_has_course uhc
+				WHERE uhc.user_id = ? AND uhc.course_id = c.id AND uhc.deleted_at IS NULL
+			) AS enrolled,
+			EXISTS (
+				SELECT 1 FROM user_has_exam uhe JOIN exam e ON e.id = uhe.exam_id
+				WHERE uhe.user_id = ? AND e.course_id = c.id AND uhe.passed = 1 AND uhe.deleted_at IS NULL AND e.deleted_at IS NULL
+			) AS passed
+			FROM field_of_study_has_course fhc
+			JOIN course c ON c.id = fhc.course_id
+			WHERE fhc.field_of_study_id = ? AND fhc.semester <= ? AND c.deleted_at IS NULL
+			ORDER BY fhc.semester, c.name`, userId, userId, f.ID, u.Semester.Int).Bind(context.Background(), db, &courses)
+		if err != nil {
+			return nil, err
+		}
+
+		// courses of earlier semesters only remain until they're passed
+		due := []*CurriculumCourse{}
+		for _, c := range courses {
+			if c.Semester == u.Semester.Int || !c.Passed {
+				due = append(due, c)
+			}
+		}
+
+		curricula = append(curricula, &Curriculum{FieldOfStudy: f, Semester: u.Semester.Int, Courses: due})
+	}
+
+	return curricula, nil
+}
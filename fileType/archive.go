@@ -0,0 +1,203 @@
+package filetype
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"learningbay24.de/backend/errs"
+)
+
+// Maximum amount of entries in an archive
+const maxArchiveEntries = 10000
+
+// Maximum size of the contents of an archive after extracting it
+const maxExtractedSize = 1 << 30
+
+// Maximum ratio between the extracted and the compressed size of an archive. Smaller archives may always extract to minExtractedLimit.
+const (
+	maxCompressionRatio = 100
+	minExtractedLimit   = 16 << 20
+)
+
+// errTooLarge stops reading an archive once it extracts to more than it may
+var errTooLarge = errors.New("extracts to too much data")
+
+// CheckArchive takes a file and the MIME type of its content and, if it is an archive, checks that none of its entries would be extracted
+// outside of the directory it is extracted to and that it doesn't extract to far more than its own size, like a zip bomb.
+// The contents are actually decompressed, as the sizes an archive claims can't be trusted. RAR and 7z archives can't be decompressed
+// and are rejected with errs.ErrUncheckableArchive. Other files are accepted as they are.
+func CheckArchive(mimeType string, r io.ReaderAt, size int64) error {
+	limit := extractedLimit(size)
+
+	var err error
+	switch mediaType := strings.SplitN(mimeType, ";", 2)[0]; mediaType {
+	case "application/zip", docx, xlsx, pptx, odt, ods, odp:
+		err = checkZip(r, size, limit)
+	case "application/x-tar":
+		err = checkTar(io.NewSectionReader(r, 0, size), -1)
+	case "application/x-gzip":
+		var zr *gzip.Reader
+		zr, err = gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err == nil {
+			err = checkCompressed(zr, limit)
+		}
+	case "application/x-bzip2":
+		err = checkCompressed(bzip2.NewReader(io.NewSectionReader(r, 0, size)), limit)
+	case "application/x-rar-compressed", "application/x-7z-compressed":
+		return errs.ErrUncheckableArchive
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s", errs.ErrUnsafeArchive, err.Error())
+	}
+	return nil
+}
+
+func extractedLimit(size int64) int64 {
+	limit := size * maxCompressionRatio
+	if limit < minExtractedLimit {
+		limit = minExtractedLimit
+	}
+	if limit > maxExtractedSize {
+		limit = maxExtractedSize
+	}
+	return limit
+}
+
+// checkPath returns an error for paths of entries that are absolute or leave the directory they are extracted to
+func checkPath(name string) error {
+	p := strings.ReplaceAll(name, "\\", "/")
+	if p == "" || strings.HasPrefix(p, "/") || len(p) >= 2 && p[1] == ':' {
+		return fmt.Errorf("unsafe path %q", name)
+	}
+	if p = path.Clean(p); p == ".." || strings.HasPrefix(p, "../") {
+		return fmt.Errorf("unsafe path %q", name)
+	}
+	return nil
+}
+
+func checkZip(r io.ReaderAt, size int64, limit int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	if len(zr.File) > maxArchiveEntries {
+		return fmt.Errorf("more than %d entries", maxArchiveEntries)
+	}
+
+	var extracted int64
+	for _, f := range zr.File {
+		if err := checkPath(f.Name); err != nil {
+			return err
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("symbolic link %q", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(io.Discard, io.LimitReader(rc, limit-extracted+1))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		extracted += n
+		if extracted > limit {
+			return errTooLarge
+		}
+	}
+
+	return nil
+}
+
+// checkTar checks the entries of a tar archive, read up to limit bytes, or without a limit if it is negative
+func checkTar(r io.Reader, limit int64) error {
+	tr := tar.NewReader(r)
+	var extracted int64
+	for i := 0; ; i++ {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if i >= maxArchiveEntries {
+			return fmt.Errorf("more than %d entries", maxArchiveEntries)
+		}
+
+		if err := checkPath(h.Name); err != nil {
+			return err
+		}
+		switch h.Typeflag {
+		case tar.TypeSymlink:
+			// symbolic links are resolved relative to the directory they are in
+			if path.IsAbs(h.Linkname) {
+				return fmt.Errorf("unsafe link %q", h.Linkname)
+			}
+			if err := checkPath(path.Join(path.Dir(h.Name), h.Linkname)); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if err := checkPath(h.Linkname); err != nil {
+				return err
+			}
+		}
+
+		extracted += h.Size
+		if limit >= 0 && extracted > limit {
+			return errTooLarge
+		}
+	}
+}
+
+// checkCompressed decompresses a compressed file up to limit bytes. Compressed tar archives are checked as such.
+func checkCompressed(r io.Reader, limit int64) error {
+	counter := &countingReader{r: r}
+	br := bufio.NewReaderSize(io.LimitReader(counter, limit+1), 1024)
+
+	head, err := br.Peek(262)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+	if len(head) >= 262 && string(head[257:262]) == "ustar" {
+		if err := checkTar(br, limit); err != nil {
+			return err
+		}
+	}
+
+	// the rest, or all of it if it isn't a tar archive, is only counted
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return err
+	}
+	if counter.n > limit {
+		return errTooLarge
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
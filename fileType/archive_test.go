@@ -0,0 +1,69 @@
+package filetype
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"learningbay24.de/backend/errs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tarOf returns a tar archive containing the given headers, with regular files filled with zeros
+func tarOf(t *testing.T, headers ...*tar.Header) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range headers {
+		if h.Typeflag == 0 {
+			h.Typeflag = tar.TypeReg
+		}
+		assert.NoError(t, tw.WriteHeader(h))
+		if h.Typeflag == tar.TypeReg {
+			_, err := tw.Write(make([]byte, h.Size))
+			assert.NoError(t, err)
+		}
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func gzipOf(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(b)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func checkArchive(b []byte) error {
+	return CheckArchive(detect(b), bytes.NewReader(b), int64(len(b)))
+}
+
+func TestCheckArchive(t *testing.T) {
+	assert.NoError(t, checkArchive([]byte("no archive")))
+	assert.ErrorIs(t, checkArchive([]byte("Rar!\x1a\x07\x01\x00")), errs.ErrUncheckableArchive)
+	assert.ErrorIs(t, checkArchive([]byte("7z\xbc\xaf\x27\x1c\x00\x04")), errs.ErrUncheckableArchive)
+	assert.NoError(t, checkArchive(zipOf(t, map[string][]byte{"lecture/notes.txt": []byte("notes"), "lecture/": nil})))
+	assert.NoError(t, checkArchive(tarOf(t, &tar.Header{Name: "notes.txt", Size: 5}, &tar.Header{Name: "lecture/link", Typeflag: tar.TypeSymlink, Linkname: "../notes.txt"})))
+	assert.NoError(t, checkArchive(gzipOf(t, tarOf(t, &tar.Header{Name: "notes.txt", Size: 5}))))
+
+	for name, archive := range map[string][]byte{
+		"zip traversal":          zipOf(t, map[string][]byte{"../../etc/passwd": nil}),
+		"zip absolute path":      zipOf(t, map[string][]byte{"/etc/passwd": nil}),
+		"zip windows traversal":  zipOf(t, map[string][]byte{"..\\..\\boot.ini": nil}),
+		"zip drive letter":       zipOf(t, map[string][]byte{"C:/boot.ini": nil}),
+		"zip bomb":               zipOf(t, map[string][]byte{"zeros": make([]byte, minExtractedLimit+1)}),
+		"tar traversal":          tarOf(t, &tar.Header{Name: "../notes.txt", Size: 5}),
+		"tar symlink traversal":  tarOf(t, &tar.Header{Name: "lecture/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"}),
+		"tar absolute symlink":   tarOf(t, &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}),
+		"tar hardlink traversal": tarOf(t, &tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "../etc/passwd"}),
+		"tgz traversal":          gzipOf(t, tarOf(t, &tar.Header{Name: "../notes.txt", Size: 5})),
+		"gzip bomb":              gzipOf(t, make([]byte, minExtractedLimit+1)),
+		"corrupt zip":            append([]byte("PK\x03\x04"), make([]byte, 100)...),
+	} {
+		assert.ErrorIs(t, checkArchive(archive), errs.ErrUnsafeArchive, name)
+	}
+}
@@ -0,0 +1,157 @@
+// Package filetype detects the type of uploaded files by their content, so that files can't pretend to be of another type by their extension
+package filetype
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"learningbay24.de/backend/errs"
+)
+
+// MIME types of files without a more specific type
+const (
+	Binary = "application/octet-stream"
+	Text   = "text/plain; charset=utf-8"
+)
+
+// MIME types of office documents, which are zip archives containing a specific structure
+const (
+	docx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	xlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	pptx = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	odt  = "application/vnd.oasis.opendocument.text"
+	ods  = "application/vnd.oasis.opendocument.spreadsheet"
+	odp  = "application/vnd.oasis.opendocument.presentation"
+)
+
+// MIME types the content of a file with the given extension may have. Only the media type is compared, without its parameters.
+var extensions = map[string][]string{
+	"pdf":  {"application/pdf"},
+	"png":  {"image/png"},
+	"jpg":  {"image/jpeg"},
+	"jpeg": {"image/jpeg"},
+	"gif":  {"image/gif"},
+	"webp": {"image/webp"},
+	"bmp":  {"image/bmp"},
+	"zip":  {"application/zip"},
+	"tar":  {"application/x-tar"},
+	"gz":   {"application/x-gzip"},
+	"tgz":  {"application/x-gzip"},
+	"bz2":  {"application/x-bzip2"},
+	"bzip": {"application/x-bzip2"},
+	"tbz2": {"application/x-bzip2"},
+	"rar":  {"application/x-rar-compressed"},
+	"7z":   {"application/x-7z-compressed"},
+	"txt":  {"text/plain"},
+	"md":   {"text/plain"},
+	"csv":  {"text/plain"},
+	"docx": {docx},
+	"xlsx": {xlsx},
+	"pptx": {pptx},
+	"odt":  {odt},
+	"ods":  {ods},
+	"odp":  {odp},
+	"mp3":  {"audio/mpeg"},
+	"wav":  {"audio/wave"},
+	"ogg":  {"application/ogg"},
+	"mp4":  {"video/mp4"},
+	"webm": {"video/webm"},
+}
+
+// Signatures of types the standard library doesn't detect, executables are detected so that they are never accepted
+var signatures = []struct {
+	offset   int
+	magic    []byte
+	mimeType string
+}{
+	{0, []byte("\x7fELF"), "application/x-executable"},
+	{0, []byte("MZ"), "application/vnd.microsoft.portable-executable"},
+	{0, []byte("\xfe\xed\xfa\xce"), "application/x-mach-binary"},
+	{0, []byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary"},
+	{0, []byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{0, []byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{0, []byte("#!"), "text/x-shellscript"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed"},
+	{257, []byte("ustar"), "application/x-tar"},
+}
+
+// Detect returns the MIME type of a file by its content
+func Detect(r io.ReaderAt, size int64) string {
+	head := make([]byte, 512)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return Binary
+	}
+	head = head[:n]
+
+	for _, s := range signatures {
+		if len(head) >= s.offset+len(s.magic) && bytes.Equal(head[s.offset:s.offset+len(s.magic)], s.magic) {
+			return s.mimeType
+		}
+	}
+
+	mimeType := http.DetectContentType(head)
+	if mimeType == "application/zip" {
+		return detectZip(r, size)
+	}
+	return mimeType
+}
+
+// detectZip tells office documents apart from other zip archives by the files they contain
+func detectZip(r io.ReaderAt, size int64) string {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return "application/zip"
+	}
+
+	for _, f := range zr.File {
+		switch {
+		case strings.HasPrefix(f.Name, "word/"):
+			return docx
+		case strings.HasPrefix(f.Name, "xl/"):
+			return xlsx
+		case strings.HasPrefix(f.Name, "ppt/"):
+			return pptx
+		case f.Name == "mimetype":
+			// OpenDocument files start with their type stored uncompressed
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			b, _ := io.ReadAll(io.LimitReader(rc, 128))
+			rc.Close()
+			if t := string(b); t == odt || t == ods || t == odp {
+				return t
+			}
+		}
+	}
+
+	return "application/zip"
+}
+
+// Check takes the extension of a file without its leading dot and the MIME type of its content and returns an error if they don't agree.
+// Extensions without known types only accept contents that don't have a recognized type either, like plain text.
+func Check(ext string, mimeType string) error {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return fmt.Errorf("%w: unknown type %q", errs.ErrFileContentMismatch, mimeType)
+	}
+
+	allowed, ok := extensions[strings.ToLower(ext)]
+	if !ok {
+		allowed = []string{"text/plain", Binary}
+	}
+	for _, a := range allowed {
+		if mediaType == a {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: .%s file has type %s", errs.ErrFileContentMismatch, ext, mediaType)
+}
@@ -0,0 +1,62 @@
+package filetype
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"learningbay24.de/backend/errs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// zipOf returns a zip archive containing files with the given names and contents
+func zipOf(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write(content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func detect(b []byte) string {
+	return Detect(bytes.NewReader(b), int64(len(b)))
+}
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, "application/pdf", detect([]byte("%PDF-1.4\n%âãÏÓ\n")))
+	assert.Equal(t, "image/png", detect([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")))
+	assert.Equal(t, Text, detect([]byte("Binary search trees\n")))
+	assert.Equal(t, Text, detect(nil))
+	assert.Equal(t, "application/x-executable", detect([]byte("\x7fELF\x02\x01\x01\x00")))
+	assert.Equal(t, "application/x-bzip2", detect([]byte("BZh91AY&SY")))
+
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar\x0000")
+	assert.Equal(t, "application/x-tar", detect(tar))
+
+	assert.Equal(t, "application/zip", detect(zipOf(t, map[string][]byte{"notes.txt": []byte("notes")})))
+	assert.Equal(t, docx, detect(zipOf(t, map[string][]byte{"[Content_Types].xml": nil, "word/document.xml": nil})))
+	assert.Equal(t, odt, detect(zipOf(t, map[string][]byte{"mimetype": []byte(odt)})))
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check("pdf", "application/pdf"))
+	assert.NoError(t, Check("PDF", "application/pdf"))
+	assert.NoError(t, Check("txt", Text))
+	assert.NoError(t, Check("docx", docx))
+	// unknown extensions only accept contents without a recognized type
+	assert.NoError(t, Check("py", Text))
+	assert.NoError(t, Check("dat", Binary))
+
+	assert.ErrorIs(t, Check("pdf", "application/x-executable"), errs.ErrFileContentMismatch)
+	assert.ErrorIs(t, Check("txt", "text/html; charset=utf-8"), errs.ErrFileContentMismatch)
+	assert.ErrorIs(t, Check("docx", "application/zip"), errs.ErrFileContentMismatch)
+	assert.ErrorIs(t, Check("py", "application/x-executable"), errs.ErrFileContentMismatch)
+	assert.ErrorIs(t, Check("png", "not a type"), errs.ErrFileContentMismatch)
+}
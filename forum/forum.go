@@ -0,0 +1,294 @@
+// Package forum implements the forum every course has, consisting of threads and their (nested) replies
+package forum
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// Maximum length of the subject of an entry, as defined in the database
+const maxSubjectLength = 64
+
+// Entry is a forum entry together with all replies made to it
+type Entry struct {
+	*models.ForumEntry
+	Replies []*Entry `json:"replies"`
+}
+
+// GetForumIdFromCourse takes the ID of a course and returns the ID of the forum associated with it
+func GetForumIdFromCourse(db *sql.DB, courseId int) (int, error) {
+	c, err := models.FindCourse(context.Background(), db, courseId)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.ForumID, nil
+}
+
+// GetThreads takes the ID of a forum and returns all top-level entries of it, newest first
+func GetThreads(db *sql.DB, forumId int) ([]*models.ForumEntry, error) {
+	threads, err := models.ForumEntries(
+		models.ForumEntryWhere.ForumID.EQ(forumId),
+		models.ForumEntryWhere.InReplyTo.IsNull(),
+		qm.OrderBy(models.ForumEntryColumns.CreatedAt+" DESC"),
+	).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	return threads, nil
+}
+
+// GetThread takes the ID of a forum and of an entry in it and returns the entry with all of its nested replies
+func GetThread(db *sql.DB, forumId int, entryId int) (*Entry, error) {
+	entries, err := models.ForumEntries(
+		models.ForumEntryWhere.ForumID.EQ(forumId),
+		qm.OrderBy(models.ForumEntryColumns.CreatedAt+" ASC"),
+	).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildThread(entries, entryId)
+}
+
+// buildThread arranges the given entries as a tree below the entry with the given ID.
+// Replies keep the order they are given in.
+func buildThread(entries models.ForumEntrySlice, entryId int) (*Entry, error) {
+	nodes := make(map[int]*Entry, len(entries))
+	for _, e := range entries {
+		nodes[e.ID] = &Entry{ForumEntry: e, Replies: []*Entry{}}
+	}
+
+	root, ok := nodes[entryId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	for _, e := range entries {
+		if !e.InReplyTo.Valid {
+			continue
+		}
+
+		// replies to deleted entries are dropped as well
+		if parent, ok := nodes[e.InReplyTo.Int]; ok {
+			parent.Replies = append(parent.Replies, nodes[e.ID])
+		}
+	}
+
+	return root, nil
+}
+
+// collectThread returns the entry of a thread together with all of its nested replies
+func collectThread(thread *Entry) models.ForumEntrySlice {
+	entries := models.ForumEntrySlice{thread.ForumEntry}
+	for _, r := range thread.Replies {
+		entries = append(entries, collectThread(r)...)
+	}
+
+	return entries
+}
+
+// GetEntry takes the ID of a forum and of an entry and returns the entry if it is part of the forum
+func GetEntry(db *sql.DB, forumId int, entryId int) (*models.ForumEntry, error) {
+	entry, err := models.ForumEntries(
+		models.ForumEntryWhere.ID.EQ(entryId),
+		models.ForumEntryWhere.ForumID.EQ(forumId),
+	).One(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// CreateThread takes the ID of a forum, the ID of the author, a subject and content and creates a new top-level entry in the forum
+func CreateThread(db *sql.DB, forumId int, authorId int, subject string, content string) (int, error) {
+	if err := checkSubject(subject); err != nil {
+		return 0, err
+	}
+	if content == "" {
+		return 0, errs.ErrEmptyContent
+	}
+	if err := checkForumWritable(db, forumId); err != nil {
+		return 0, err
+	}
+
+	entry := models.ForumEntry{Subject: subject, Content: content, AuthorID: authorId, ForumID: forumId}
+	err := entry.Insert(context.Background(), db, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	return entry.ID, nil
+}
+
+// ReplyToEntry takes the ID of a forum, the ID of the entry that is replied to, the ID of the author, a subject and content and creates a reply.
+// If the subject is empty, it is derived from the subject of the entry that is replied to.
+func ReplyToEntry(db *sql.DB, forumId int, entryId int, authorId int, subject string, content string) (int, error) {
+	if content == "" {
+		return 0, errs.ErrEmptyContent
+	}
+
+	parent, err := GetEntry(db, forumId, entryId)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkForumWritable(db, forumId); err != nil {
+		return 0, err
+	}
+
+	if subject == "" {
+		subject = replySubject(parent.Subject)
+	} else if err := checkSubject(subject); err != nil {
+		return 0, err
+	}
+
+	entry := models.ForumEntry{Subject: subject, Content: content, InReplyTo: null.IntFrom(parent.ID), AuthorID: authorId, ForumID: forumId}
+	err = entry.Insert(context.Background(), db, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	return entry.ID, nil
+}
+
+// checkForumWritable returns `errs.ErrCourseArchived` if the forum belongs to an archived course, where entries can't be posted, edited or deleted
+func checkForumWritable(db *sql.DB, forumId int) error {
+	c, err := models.Courses(models.CourseWhere.ForumID.EQ(forumId)).One(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	if c.ArchivedAt.Valid {
+		return errs.ErrCourseArchived
+	}
+
+	return nil
+}
+
+// checkSubject returns an error for subjects that are empty or don't fit into the database
+func checkSubject(subject string) error {
+	if subject == "" {
+		return errs.ErrEmptySubject
+	}
+	if len([]rune(subject)) > maxSubjectLength {
+		return errs.ErrSubjectTooLong
+	}
+
+	return nil
+}
+
+// replySubject prefixes the subject with "Re: " unless it already is, cutting it off at the maximum length
+func replySubject(subject string) string {
+	if len(subject) < 4 || subject[:4] != "Re: " {
+		subject = "Re: " + subject
+	}
+
+	runes := []rune(subject)
+	if len(runes) > maxSubjectLength {
+		subject = string(runes[:maxSubjectLength])
+	}
+
+	return subject
+}
+
+// EditEntry takes the ID of a forum, the ID of an entry, the ID of the user editing it and the new subject and content and overwrites the entry.
+// Only the author of an entry is allowed to edit it.
+func EditEntry(db *sql.DB, forumId int, entryId int, userId int, subject string, content string) error {
+	if err := checkSubject(subject); err != nil {
+		return err
+	}
+	if content == "" {
+		return errs.ErrEmptyContent
+	}
+
+	entry, err := GetEntry(db, forumId, entryId)
+	if err != nil {
+		return err
+	}
+	if err := checkForumWritable(db, forumId); err != nil {
+		return err
+	}
+
+	if entry.AuthorID != userId {
+		return errs.ErrNotEntryAuthor
+	}
+
+	entry.Subject = subject
+	entry.Content = content
+	_, err = entry.Update(context.Background(), db, boil.Infer())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteEntry takes the ID of a forum and of an entry and soft-deletes the entry together with all replies to it
+func DeleteEntry(db *sql.DB, forumId int, entryId int) error {
+	if err := checkForumWritable(db, forumId); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	entries, err := models.ForumEntries(models.ForumEntryWhere.ForumID.EQ(forumId)).All(context.Background(), tx)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	thread, err := buildThread(entries, entryId)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	_, err = collectThread(thread).DeleteAll(context.Background(), tx, false)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAllEntries takes the ID of a forum and deletes all of its entries for good, so that the forum itself can be deleted
+func DeleteAllEntries(exec boil.ContextExecutor, forumId int) error {
+	// replies refer to the entries they reply to, which couldn't be deleted before them otherwise
+	_, err := exec.Exec("UPDATE forum_entry SET in_reply_to = NULL WHERE forum_id = ?", forumId)
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.Exec("DELETE FROM forum_entry WHERE forum_id = ?", forumId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package forum
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"learningbay24.de/backend/errs"
+	"learningbay24.de/backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/volatiletech/null/v8"
+)
+
+// entries returns a thread with the ID 1 and its replies, together with an unrelated thread and a reply to a deleted entry
+func entries() models.ForumEntrySlice {
+	return models.ForumEntrySlice{
+		{ID: 1},
+		{ID: 2, InReplyTo: null.IntFrom(1)},
+		{ID: 3, InReplyTo: null.IntFrom(2)},
+		{ID: 4},
+		{ID: 5, InReplyTo: null.IntFrom(1)},
+		{ID: 6, InReplyTo: null.IntFrom(4)},
+		{ID: 7, InReplyTo: null.IntFrom(42)},
+	}
+}
+
+func ids(entries models.ForumEntrySlice) []int {
+	result := []int{}
+	for _, e := range entries {
+		result = append(result, e.ID)
+	}
+	return result
+}
+
+func TestBuildThread(t *testing.T) {
+	thread, err := buildThread(entries(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, thread.ID)
+	// replies keep their order
+	assert.Len(t, thread.Replies, 2)
+	assert.Equal(t, 2, thread.Replies[0].ID)
+	assert.Equal(t, 5, thread.Replies[1].ID)
+	assert.Len(t, thread.Replies[0].Replies, 1)
+	assert.Equal(t, 3, thread.Replies[0].Replies[0].ID)
+	assert.Empty(t, thread.Replies[1].Replies)
+
+	reply, err := buildThread(entries(), 2)
+	assert.NoError(t, err)
+	assert.Len(t, reply.Replies, 1)
+
+	_, err = buildThread(entries(), 42)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCollectThread(t *testing.T) {
+	thread, err := buildThread(entries(), 1)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 2, 3, 5}, ids(collectThread(thread)))
+
+	// deleting a reply leaves its parent and siblings alone
+	reply, err := buildThread(entries(), 2)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{2, 3}, ids(collectThread(reply)))
+
+	other, err := buildThread(entries(), 4)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{4, 6}, ids(collectThread(other)))
+}
+
+func TestCheckSubject(t *testing.T) {
+	assert.NoError(t, checkSubject("Klausurtermin"))
+	assert.NoError(t, checkSubject(strings.Repeat("ä", maxSubjectLength)))
+	assert.ErrorIs(t, checkSubject(""), errs.ErrEmptySubject)
+	assert.ErrorIs(t, checkSubject(strings.Repeat("a", maxSubjectLength+1)), errs.ErrSubjectTooLong)
+}
+
+func TestReplySubject(t *testing.T) {
+	assert.Equal(t, "Re: Klausurtermin", replySubject("Klausurtermin"))
+	assert.Equal(t, "Re: Klausurtermin", replySubject("Re: Klausurtermin"))
+	assert.Len(t, []rune(replySubject(strings.Repeat("a", maxSubjectLength))), maxSubjectLength)
+}
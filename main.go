@@ -6,10 +6,14 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"learningbay24.de/backend/api"
 	"learningbay24.de/backend/config"
 	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/notification"
+	"learningbay24.de/backend/scanner"
+	"learningbay24.de/backend/storage"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
@@ -47,9 +51,10 @@ func setupEnvironment(db *sql.DB) {
 
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, DELETE, PATCH, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, HEAD, DELETE, PATCH, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "https://learningbay24.de")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Cache-Control")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Cache-Control, Upload-Offset, Range, If-Range, If-None-Match, If-Modified-Since")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Location, Upload-Offset, Upload-Length, ETag, Content-Disposition, Content-Range, Accept-Ranges")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -132,8 +137,40 @@ func main() {
 	config.InitLogger()
 	db := config.SetupDbHandle()
 	applyMigrations(db)
+	if err := dbi.AdoptLegacyFiles(db); err != nil {
+		log.Fatalf("Unable to adopt files into the local storage backend: %s. Aborting.", err.Error())
+	}
+	if err := dbi.DeduplicateBlobs(db); err != nil {
+		log.Fatalf("Unable to deduplicate file contents: %s. Aborting.", err.Error())
+	}
+
+	if config.MigrateStorage != "" {
+		if err := dbi.MigrateStorage(db, config.MigrateStorage); err != nil {
+			log.Fatalf("Unable to migrate files to storage backend %s: %s", config.MigrateStorage, err.Error())
+		}
+		return
+	}
+
 	setupEnvironment(db)
 
+	go notification.RunScheduler(db, 10*time.Minute)
+	go dbi.RunBlobCollector(db, time.Hour)
+	go dbi.RunUploadCollector(db, time.Hour)
+	if scanner.Enabled() {
+		if _, err := scanner.Default(); err != nil {
+			log.Fatalf("Unable to set up malware scanner: %s. Aborting.", err.Error())
+		}
+		if _, err := storage.Quarantine(); err != nil {
+			log.Fatalf("Unable to set up quarantine: %s. Aborting.", err.Error())
+		}
+
+		interval := time.Duration(config.Conf.Files.Scanner.RescanInterval) * time.Minute
+		if interval == 0 {
+			interval = time.Hour
+		}
+		go dbi.RunRescanner(db, interval)
+	}
+
 	pCtrl := api.PublicController{Database: db}
 	router := gin.Default()
 	router.Use(CORSMiddleware())
@@ -145,20 +182,40 @@ func main() {
 		auth.GET("/courses/:id/users", pCtrl.GetUsersInCourse)
 		auth.GET("/users/courses", pCtrl.GetEnrolledCoursesFromUser)
 		auth.GET("/users/createdcourses", pCtrl.GetCreatedCoursesFromUser)
+		auth.GET("/users/courses/archived", pCtrl.GetArchivedCoursesFromUser)
+		auth.GET("/users/materials/search", pCtrl.SearchMaterials)
 		auth.DELETE("/courses/:id", pCtrl.DeleteCourse)
 		auth.POST("/courses", pCtrl.CreateCourse)
 		auth.POST("/courses/:id", pCtrl.EnrollUser)
+		auth.POST("/courses/:id/copy", pCtrl.CopyCourse)
+		auth.GET("/courses/:id/export", pCtrl.ExportCourse)
+		auth.POST("/courses/import", pCtrl.ImportCourse)
+		auth.POST("/courses/:id/archive", pCtrl.ArchiveCourse)
+		auth.DELETE("/courses/:id/archive", pCtrl.UnarchiveCourse)
 		auth.PATCH("/courses/:id", pCtrl.EditCourseById)
 		auth.POST("/logout", pCtrl.Logout)
 		auth.POST("/register", pCtrl.Register)
+		auth.POST("/uploads", pCtrl.CreateUpload)
+		auth.GET("/uploads/:id", pCtrl.GetUpload)
+		auth.HEAD("/uploads/:id", pCtrl.GetUpload)
+		auth.PATCH("/uploads/:id", pCtrl.PatchUpload)
+		auth.DELETE("/uploads/:id", pCtrl.DeleteUpload)
 		auth.POST("/courses/:id/files", pCtrl.UploadMaterial)
 		auth.GET("/courses/:id/files", pCtrl.GetMaterialsFromCourse)
 		auth.GET("/courses/:id/files/:file_id", pCtrl.GetMaterialFromCourse)
+		auth.GET("/courses/:id/files/:file_id/link", pCtrl.GetMaterialLink)
 		auth.DELETE("/courses/:id/files/:file_id", pCtrl.DeleteMaterialFromCourse)
+		auth.PATCH("/courses/:id/files/:file_id/move", pCtrl.MoveMaterial)
+		auth.POST("/courses/:id/directories", pCtrl.CreateDirectory)
+		auth.PATCH("/courses/:id/directories/:directory_id", pCtrl.EditDirectory)
+		auth.PATCH("/courses/:id/directories/:directory_id/move", pCtrl.MoveDirectory)
+		auth.DELETE("/courses/:id/directories/:directory_id", pCtrl.DeleteDirectory)
+		auth.POST("/courses/:id/directories/:directory_id/files", pCtrl.UploadMaterial)
 		auth.DELETE("/users/:id", pCtrl.DeleteUser)
 		auth.GET("/users/cookie", pCtrl.GetUserByCookie)
 		auth.GET("/users/:id", pCtrl.GetUserById)
 		auth.GET("/courses/appointments", pCtrl.GetAllAppointments)
+		auth.GET("/users/timeline", pCtrl.GetTimeline)
 		auth.POST("/exams", pCtrl.CreateExam)
 		auth.PATCH("/exams/:id/edit", pCtrl.EditExam)
 		auth.POST("/exams/:id/files", pCtrl.UploadExamFile)
@@ -188,21 +245,73 @@ func main() {
 		auth.GET("/users/submissions", pCtrl.GetSubmissionFromUser)
 		auth.GET("/courses/submissions/:submission_id/files", pCtrl.GetFileFromSubmission)
 		auth.POST("/courses/submissions/:submission_id/files", pCtrl.CreateSubmissionHasFiles)
+		auth.GET("/courses/submissions/:submission_id/files/:file_id", pCtrl.DownloadFileFromSubmission)
+		auth.GET("/courses/submissions/:submission_id/files/:file_id/link", pCtrl.GetSubmissionFileLink)
 		auth.DELETE("/courses/submissions/:submission_id/files/:file_id", pCtrl.DeleteSubmissionHasFiles)
 		auth.POST("/courses/submissions/:submission_id/usersubmissions", pCtrl.CreateUserSubmission)
 		auth.GET("/courses/submissions/:submission_id/usersubmissions", pCtrl.GetAllUserSubmissionsFromSubmission)
 		auth.DELETE("/courses/submissions/usersubmissions/:usersubmission_id", pCtrl.DeleteUserSubmission)
 		auth.GET("/courses/submissions/usersubmissions/:usersubmission_id/files", pCtrl.GetFileFromUserSubmission)
 		auth.POST("/courses/submissions/usersubmissions/:usersubmission_id/files", pCtrl.CreateUserSubmissionHasFiles)
+		auth.GET("/courses/submissions/usersubmissions/:usersubmission_id/files/:file_id", pCtrl.DownloadFileFromUserSubmission)
+		auth.GET("/courses/submissions/usersubmissions/:usersubmission_id/files/:file_id/link", pCtrl.GetUserSubmissionFileLink)
 		auth.DELETE("/courses/submissions/usersubmissions/:usersubmission_id/files/:file_id", pCtrl.DeleteUserSubmissionHasFiles)
 		auth.GET("/courses/:id/submissions", pCtrl.GetSubmissionsFromCourse)
 		auth.PATCH("/courses/submissions/usersubmissions/:usersubmission_id/grade", pCtrl.GradeUserSubmission)
 		auth.GET("/courses/:id/role", pCtrl.GetUserCourseRole)
 		auth.DELETE("/appointments", pCtrl.DeactivateCourseInCalender)
 		auth.POST("/appointments/add", pCtrl.AddCourseToCalender)
+		auth.PATCH("/appointments/:id", pCtrl.EditAppointment)
+		auth.POST("/courses/:id/appointments/import", pCtrl.ImportAppointments)
+		auth.GET("/courses/:id/forum", pCtrl.GetForumThreads)
+		auth.POST("/courses/:id/forum", pCtrl.CreateForumThread)
+		auth.GET("/courses/:id/forum/:entry_id", pCtrl.GetForumThread)
+		auth.POST("/courses/:id/forum/:entry_id", pCtrl.ReplyToForumEntry)
+		auth.PATCH("/courses/:id/forum/:entry_id", pCtrl.EditForumEntry)
+		auth.DELETE("/courses/:id/forum/:entry_id", pCtrl.DeleteForumEntry)
+		auth.PATCH("/courses/:id/enrollment", pCtrl.SetEnrollmentOptions)
+		auth.GET("/courses/:id/requests", pCtrl.GetEnrollmentRequests)
+		auth.POST("/courses/:id/requests/:user_id", pCtrl.ApproveEnrollmentRequest)
+		auth.DELETE("/courses/:id/requests/:user_id", pCtrl.RejectEnrollmentRequest)
+		auth.GET("/courses/:id/invites", pCtrl.GetInvitesFromCourse)
+		auth.POST("/courses/:id/invites", pCtrl.CreateInvite)
+		auth.DELETE("/courses/:id/invites/:invite_id", pCtrl.RevokeInvite)
+		auth.POST("/invites/:token", pCtrl.RedeemInvite)
+		auth.POST("/courses/:id/users/import", pCtrl.EnrollUsersFromCSV)
+		auth.GET("/courses/:id/staff", pCtrl.GetCourseStaff)
+		auth.GET("/courses/:id/staff/history", pCtrl.GetRoleChangesFromCourse)
+		auth.POST("/courses/:id/staff/:user_id", pCtrl.PromoteUserInCourse)
+		auth.DELETE("/courses/:id/staff/:user_id", pCtrl.DemoteUserInCourse)
+		auth.POST("/courses/:id/owner/:user_id", pCtrl.TransferCourseOwnership)
+		auth.GET("/courses/:id/prerequisites", pCtrl.GetPrerequisitesFromCourse)
+		auth.GET("/courses/:id/prerequisites/missing", pCtrl.GetMissingPrerequisitesFromCourse)
+		auth.POST("/courses/:id/prerequisites/:required_id", pCtrl.AddPrerequisiteToCourse)
+		auth.DELETE("/courses/:id/prerequisites/:required_id", pCtrl.RemovePrerequisiteFromCourse)
+		auth.GET("/users/notifications", pCtrl.GetNotificationsFromUser)
+		auth.GET("/users/notifications/unread", pCtrl.GetUnreadNotificationsFromUser)
+		auth.PATCH("/users/notifications/read", pCtrl.MarkAllNotificationsAsRead)
+		auth.PATCH("/users/notifications/:id/read", pCtrl.MarkNotificationAsRead)
+		auth.DELETE("/users/notifications", pCtrl.DeleteAllNotifications)
+		auth.DELETE("/users/notifications/:id", pCtrl.DeleteNotification)
+		auth.GET("/users/certificates", pCtrl.GetCertificatesFromUser)
+		auth.GET("/fieldsofstudy", pCtrl.GetFieldsOfStudy)
+		auth.POST("/fieldsofstudy", pCtrl.CreateFieldOfStudy)
+		auth.GET("/fieldsofstudy/:id/courses", pCtrl.GetCoursesFromFieldOfStudy)
+		auth.POST("/fieldsofstudy/:id/courses/:course_id", pCtrl.SetCourseSemesterInFieldOfStudy)
+		auth.DELETE("/fieldsofstudy/:id/courses/:course_id", pCtrl.RemoveCourseFromFieldOfStudy)
+		auth.GET("/users/fieldsofstudy", pCtrl.GetFieldsOfStudyFromUser)
+		auth.POST("/users/fieldsofstudy/:id", pCtrl.JoinFieldOfStudy)
+		auth.DELETE("/users/fieldsofstudy/:id", pCtrl.LeaveFieldOfStudy)
+		auth.GET("/users/curriculum", pCtrl.GetCurriculum)
+		auth.GET("/users/calendar/token", pCtrl.GetCalendarFeedToken)
+		auth.POST("/users/calendar/token", pCtrl.RegenerateCalendarFeedToken)
 	}
 
 	router.POST("/login", pCtrl.Login)
+	router.GET("/certificates/:id", pCtrl.VerifyCertificate)
+	router.GET("/certificates/:id/pdf", pCtrl.GetCertificatePDF)
+	router.GET("/calendar/:token", pCtrl.GetCalendarFeed)
+	router.GET("/files/:id/download", pCtrl.DownloadSignedFile)
 
 	router.Run("0.0.0.0:8080")
 }
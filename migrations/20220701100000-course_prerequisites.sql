@@ -0,0 +1,18 @@
+-- +migrate Up
+-- `course_requires_certificate` references single certificates, which are issued per user and thus can't be required by a course.
+-- Instead a course requires the certificate of another course, which every user gains by passing one of its exams.
+CREATE TABLE `course_requires_course` (
+  `course_id` int(11) NOT NULL COMMENT 'The course that can only be enrolled in with a certificate of the required course.',
+  `required_course_id` int(11) NOT NULL COMMENT 'The course a certificate is required from.',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`course_id`,`required_course_id`),
+  KEY `fk_course_requires_course_course1_idx` (`course_id`),
+  KEY `fk_course_requires_course_course2_idx` (`required_course_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+ALTER TABLE `course_requires_course`
+	ADD CONSTRAINT `fk_course_requires_course_course1` FOREIGN KEY (`course_id`) REFERENCES `course` (`id`),
+	ADD CONSTRAINT `fk_course_requires_course_course2` FOREIGN KEY (`required_course_id`) REFERENCES `course` (`id`);
+
+-- +migrate Down
+DROP TABLE `course_requires_course`;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE `directory` ADD `parent_id` int(11) NULL COMMENT 'The directory this directory is contained in, if it isn''t at the top level of the course.' AFTER `course_id`;
+ALTER TABLE `directory` ADD CONSTRAINT `fk_directory_directory1` FOREIGN KEY (`parent_id`) REFERENCES `directory` (`id`);
+
+-- +migrate Down
+ALTER TABLE `directory` DROP CONSTRAINT `fk_directory_directory1`;
+ALTER TABLE `directory` DROP COLUMN `parent_id`;
@@ -0,0 +1,25 @@
+-- +migrate Up
+CREATE TABLE `appointment_series` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `course_id` int(11) NOT NULL,
+  `repeat_distance` int(11) NOT NULL COMMENT 'How far apart the appointments of this series are. 1: weekly, 2: monthly, 3: yearly.',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NULL DEFAULT NULL,
+  `deleted_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `fk_appointment_series_course1_idx` (`course_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci COMMENT='Links repeating appointments of a course.';
+
+ALTER TABLE `appointment_series`
+	ADD CONSTRAINT `fk_appointment_series_course1` FOREIGN KEY (`course_id`) REFERENCES `course` (`id`);
+
+ALTER TABLE `appointment` ADD `series_id` int(11) NULL COMMENT 'The series this appointment is part of, if it repeats.';
+ALTER TABLE `appointment` ADD CONSTRAINT `fk_appointment_appointment_series1` FOREIGN KEY (`series_id`) REFERENCES `appointment_series` (`id`);
+-- editing or deleting an appointment must not move it to the current time
+ALTER TABLE `appointment` MODIFY `date` timestamp NOT NULL DEFAULT current_timestamp() COMMENT 'The date the appointment should be.';
+
+-- +migrate Down
+ALTER TABLE `appointment` MODIFY `date` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp() COMMENT 'The date the appointment should be.';
+ALTER TABLE `appointment` DROP CONSTRAINT `fk_appointment_appointment_series1`;
+ALTER TABLE `appointment` DROP COLUMN `series_id`;
+DROP TABLE `appointment_series`;
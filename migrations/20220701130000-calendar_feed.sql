@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE `calendar_feed` (
+  `user_id` int(11) NOT NULL,
+  `token` char(64) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Secret that grants access to the calendar feed of the user without logging in.',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`user_id`),
+  UNIQUE KEY `UC_calendar_feed_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+ALTER TABLE `calendar_feed`
+	ADD CONSTRAINT `fk_calendar_feed_user1` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`);
+
+-- +migrate Down
+DROP TABLE `calendar_feed`;
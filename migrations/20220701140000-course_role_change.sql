@@ -0,0 +1,24 @@
+-- +migrate Up
+CREATE TABLE `course_role_change` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `course_id` int(11) NOT NULL,
+  `user_id` int(11) NOT NULL COMMENT 'The user whose role in the course changed.',
+  `changed_by_id` int(11) NOT NULL COMMENT 'The user that changed the role.',
+  `old_role_id` int(11) NULL COMMENT 'The role the user had before, NULL if they weren''t part of the course.',
+  `new_role_id` int(11) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `fk_course_role_change_course1_idx` (`course_id`),
+  KEY `fk_course_role_change_user1_idx` (`user_id`),
+  KEY `fk_course_role_change_user2_idx` (`changed_by_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci COMMENT='Log of the changes of course roles.';
+
+ALTER TABLE `course_role_change`
+	ADD CONSTRAINT `fk_course_role_change_course1` FOREIGN KEY (`course_id`) REFERENCES `course` (`id`),
+	ADD CONSTRAINT `fk_course_role_change_user1` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`),
+	ADD CONSTRAINT `fk_course_role_change_user2` FOREIGN KEY (`changed_by_id`) REFERENCES `user` (`id`),
+	ADD CONSTRAINT `fk_course_role_change_role1` FOREIGN KEY (`old_role_id`) REFERENCES `role` (`id`),
+	ADD CONSTRAINT `fk_course_role_change_role2` FOREIGN KEY (`new_role_id`) REFERENCES `role` (`id`);
+
+-- +migrate Down
+DROP TABLE `course_role_change`;
@@ -0,0 +1,22 @@
+-- +migrate Up
+ALTER TABLE `course` ADD `enroll_mode` tinyint(4) NOT NULL DEFAULT 0 COMMENT 'How users enroll in this course. 0: with the enroll key, 1: open, 2: approval required, 3: closed.';
+ALTER TABLE `course` ADD `capacity` int(11) NULL COMMENT 'Maximum amount of users enrolled in this course, NULL if unlimited.';
+
+CREATE TABLE `enrollment_request` (
+  `user_id` int(11) NOT NULL,
+  `course_id` int(11) NOT NULL,
+  `status` tinyint(4) NOT NULL COMMENT '0: waiting for approval, 1: approved, waiting for a free seat.',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp() COMMENT 'The time the user asked to enroll, which decides their position on the waitlist.',
+  PRIMARY KEY (`user_id`,`course_id`),
+  KEY `fk_enrollment_request_user1_idx` (`user_id`),
+  KEY `fk_enrollment_request_course1_idx` (`course_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci COMMENT='Users that asked to enroll in a course but aren''t admitted yet.';
+
+ALTER TABLE `enrollment_request`
+	ADD CONSTRAINT `fk_enrollment_request_user1` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`),
+	ADD CONSTRAINT `fk_enrollment_request_course1` FOREIGN KEY (`course_id`) REFERENCES `course` (`id`);
+
+-- +migrate Down
+DROP TABLE `enrollment_request`;
+ALTER TABLE `course` DROP COLUMN `capacity`;
+ALTER TABLE `course` DROP COLUMN `enroll_mode`;
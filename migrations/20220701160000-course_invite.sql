@@ -0,0 +1,25 @@
+-- +migrate Up
+CREATE TABLE `course_invite` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `course_id` int(11) NOT NULL,
+  `token` char(64) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Secret that enrolls whoever knows it in the course.',
+  `role_id` int(11) NOT NULL COMMENT 'The course role users redeeming the invite get.',
+  `max_uses` int(11) NULL COMMENT 'How often the invite can be redeemed, NULL if unlimited.',
+  `uses` int(11) NOT NULL DEFAULT 0 COMMENT 'How often the invite has been redeemed.',
+  `expires_at` timestamp NULL DEFAULT NULL COMMENT 'The time the invite stops working, NULL if it never expires.',
+  `created_by_id` int(11) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `deleted_at` timestamp NULL DEFAULT NULL COMMENT 'The time the invite was revoked.',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `UC_course_invite_token` (`token`),
+  KEY `fk_course_invite_course1_idx` (`course_id`),
+  KEY `fk_course_invite_user1_idx` (`created_by_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+ALTER TABLE `course_invite`
+	ADD CONSTRAINT `fk_course_invite_course1` FOREIGN KEY (`course_id`) REFERENCES `course` (`id`),
+	ADD CONSTRAINT `fk_course_invite_role1` FOREIGN KEY (`role_id`) REFERENCES `role` (`id`),
+	ADD CONSTRAINT `fk_course_invite_user1` FOREIGN KEY (`created_by_id`) REFERENCES `user` (`id`);
+
+-- +migrate Down
+DROP TABLE `course_invite`;
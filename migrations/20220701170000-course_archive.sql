@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE `course` ADD `archived_at` timestamp NULL DEFAULT NULL COMMENT 'The time this course was archived, which makes it read-only; NULL if it is active.';
+
+-- +migrate Down
+ALTER TABLE `course` DROP COLUMN `archived_at`;
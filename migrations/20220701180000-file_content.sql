@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE `file_content` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `file_id` int(11) NOT NULL,
+  `page` int(11) NULL COMMENT 'The page of the document the content is on, NULL for files without pages.',
+  `name` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT 'The displayed name of the file, so that files are found by their name too.',
+  `content` mediumtext COLLATE utf8mb4_unicode_ci NOT NULL COMMENT 'The text extracted from the file, or the names of the files in an archive.',
+  PRIMARY KEY (`id`),
+  KEY `fk_file_content_file1_idx` (`file_id`),
+  FULLTEXT KEY `FT_file_content` (`name`, `content`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `file_content`
+	ADD CONSTRAINT `fk_file_content_file1` FOREIGN KEY (`file_id`) REFERENCES `file` (`id`);
+
+-- +migrate Down
+DROP TABLE `file_content`;
@@ -0,0 +1,11 @@
+-- +migrate Up
+ALTER TABLE `file`
+	ADD `storage` varchar(16) COLLATE utf8_unicode_ci NULL DEFAULT NULL COMMENT 'Storage backend the content of a local file is stored in, e.g. local or s3.',
+	ADD `storage_key` varchar(256) COLLATE utf8_unicode_ci NULL DEFAULT NULL COMMENT 'Key the content of a local file is stored under in its storage backend. Files from before storage backends keep their absolute path in the uri until they are adopted on startup.',
+	ADD KEY `IDX_file_storage` (`storage`, `storage_key`);
+
+-- +migrate Down
+ALTER TABLE `file`
+	DROP KEY `IDX_file_storage`,
+	DROP COLUMN `storage`,
+	DROP COLUMN `storage_key`;
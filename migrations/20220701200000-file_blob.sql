@@ -0,0 +1,50 @@
+-- +migrate Up
+CREATE TABLE `file_blob` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `sha256` char(64) COLLATE utf8_unicode_ci NULL DEFAULT NULL COMMENT 'SHA-256 hash of the content, hex encoded. Contents stored before deduplication are hashed on startup.',
+  `size` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Size of the content in bytes.',
+  `storage` varchar(16) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Storage backend the content is stored in, e.g. local or s3.',
+  `storage_key` varchar(256) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Key the content is stored under in its storage backend.',
+  `ref_count` int(11) NOT NULL DEFAULT 0 COMMENT 'How often the content is referred to by materials, submissions, answers and exams through a file.',
+  `released_at` timestamp NULL DEFAULT NULL COMMENT 'When the last reference to the content was removed. The content is deleted some time afterwards, unless it is referred to again.',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp() COMMENT 'When the content was stored.',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `UQ_file_blob_sha256` (`sha256`),
+  KEY `IDX_file_blob_storage` (`storage`, `storage_key`),
+  KEY `IDX_file_blob_released_at` (`released_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci COMMENT='Content of local files, stored once for all files with the same content.';
+
+INSERT INTO `file_blob` (`storage`, `storage_key`)
+	SELECT DISTINCT `storage`, `storage_key` FROM `file`
+	WHERE `local` = 1 AND `storage` IS NOT NULL AND `storage_key` IS NOT NULL;
+
+ALTER TABLE `file`
+	ADD `blob_id` int(11) NULL DEFAULT NULL COMMENT 'Content of a local file.',
+	ADD `charged_bytes` int(11) NOT NULL DEFAULT 0 COMMENT 'Bytes counted against the upload limit of the uploader for this file, refunded when it is deleted.',
+	ADD KEY `fk_file_file_blob1_idx` (`blob_id`),
+	ADD CONSTRAINT `fk_file_file_blob1` FOREIGN KEY (`blob_id`) REFERENCES `file_blob` (`id`);
+
+UPDATE `file` f JOIN `file_blob` b ON b.`storage` = f.`storage` AND b.`storage_key` = f.`storage_key`
+	SET f.`blob_id` = b.`id`;
+
+ALTER TABLE `file`
+	DROP KEY `IDX_file_storage`,
+	DROP COLUMN `storage`,
+	DROP COLUMN `storage_key`;
+
+-- +migrate Down
+ALTER TABLE `file`
+	ADD `storage` varchar(16) COLLATE utf8_unicode_ci NULL DEFAULT NULL COMMENT 'Storage backend the content of a local file is stored in, e.g. local or s3.',
+	ADD `storage_key` varchar(256) COLLATE utf8_unicode_ci NULL DEFAULT NULL COMMENT 'Key the content of a local file is stored under in its storage backend. Files from before storage backends keep their absolute path in the uri until they are adopted on startup.',
+	ADD KEY `IDX_file_storage` (`storage`, `storage_key`);
+
+UPDATE `file` f JOIN `file_blob` b ON b.`id` = f.`blob_id`
+	SET f.`storage` = b.`storage`, f.`storage_key` = b.`storage_key`;
+
+ALTER TABLE `file`
+	DROP FOREIGN KEY `fk_file_file_blob1`,
+	DROP KEY `fk_file_file_blob1_idx`,
+	DROP COLUMN `blob_id`,
+	DROP COLUMN `charged_bytes`;
+
+DROP TABLE `file_blob`;
@@ -0,0 +1,13 @@
+-- +migrate Up
+ALTER TABLE `file`
+	ADD `mime_type` varchar(128) COLLATE utf8_unicode_ci NULL DEFAULT NULL COMMENT 'MIME type detected from the content of a local file, sent when it is downloaded.';
+
+ALTER TABLE `submission`
+	ADD `allowed_file_types` varchar(256) COLLATE utf8_unicode_ci NULL DEFAULT NULL COMMENT 'Comma separated extensions of the files that may be submitted, e.g. pdf,zip. All file types allowed by the server if NULL.';
+
+-- +migrate Down
+ALTER TABLE `submission`
+	DROP COLUMN `allowed_file_types`;
+
+ALTER TABLE `file`
+	DROP COLUMN `mime_type`;
@@ -0,0 +1,32 @@
+-- +migrate Up
+CREATE TABLE `quarantine` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(64) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Name of the infected file.',
+  `uploader_id` int(11) NULL DEFAULT NULL COMMENT 'User that uploaded the infected file, NULL for stored files that were found to be infected later on.',
+  `sha256` char(64) COLLATE utf8_unicode_ci NOT NULL COMMENT 'SHA-256 hash of the content, hex encoded.',
+  `signature` varchar(256) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Name of the malware signature that matched.',
+  `storage_key` varchar(256) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Key the content is kept under in the quarantine directory.',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp() COMMENT 'When the content was quarantined.',
+  PRIMARY KEY (`id`),
+  KEY `fk_quarantine_user1_idx` (`uploader_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci COMMENT='Infected contents, which are kept out of the storage backends.';
+
+ALTER TABLE `quarantine`
+	ADD CONSTRAINT `fk_quarantine_user1` FOREIGN KEY (`uploader_id`) REFERENCES `user` (`id`);
+
+ALTER TABLE `file_blob`
+	ADD `scanned_version` varchar(128) COLLATE utf8_unicode_ci NULL DEFAULT NULL COMMENT 'Version of the malware signatures the content was last scanned with, NULL if it wasn''t scanned yet.',
+	ADD `quarantine_id` int(11) NULL DEFAULT NULL COMMENT 'Set if the content was found to be infected after it was stored, so it can''t be downloaded anymore.',
+	ADD KEY `IDX_file_blob_scanned_version` (`scanned_version`),
+	ADD KEY `fk_file_blob_quarantine1_idx` (`quarantine_id`),
+	ADD CONSTRAINT `fk_file_blob_quarantine1` FOREIGN KEY (`quarantine_id`) REFERENCES `quarantine` (`id`);
+
+-- +migrate Down
+ALTER TABLE `file_blob`
+	DROP FOREIGN KEY `fk_file_blob_quarantine1`,
+	DROP KEY `fk_file_blob_quarantine1_idx`,
+	DROP KEY `IDX_file_blob_scanned_version`,
+	DROP COLUMN `scanned_version`,
+	DROP COLUMN `quarantine_id`;
+
+DROP TABLE `quarantine`;
@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE `upload` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `uploader_id` int(11) NOT NULL COMMENT 'User uploading the file.',
+  `name` varchar(64) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Name of the file, used once the upload is finished.',
+  `size` bigint(20) NOT NULL COMMENT 'Size of the whole file in bytes.',
+  `received` bigint(20) NOT NULL DEFAULT 0 COMMENT 'Bytes received so far. The upload is finished once all bytes were received.',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp() COMMENT 'When the upload was started.',
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() COMMENT 'When the last chunk was received. Uploads are deleted if they weren''t continued or used for some time.',
+  PRIMARY KEY (`id`),
+  KEY `fk_upload_user1_idx` (`uploader_id`),
+  KEY `IDX_upload_updated_at` (`updated_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci COMMENT='Files uploaded in chunks, which are kept in Files.UploadPath until they are saved as a file.';
+
+ALTER TABLE `upload`
+	ADD CONSTRAINT `fk_upload_user1` FOREIGN KEY (`uploader_id`) REFERENCES `user` (`id`);
+
+-- +migrate Down
+DROP TABLE `upload`;
@@ -0,0 +1,22 @@
+-- +migrate Up
+-- blobs adopted for the same content by servers starting at the same time are combined first
+UPDATE `file` f
+	JOIN `file_blob` b ON b.`id` = f.`blob_id`
+	JOIN (SELECT `storage`, `storage_key`, MIN(`id`) AS `id` FROM `file_blob` GROUP BY `storage`, `storage_key`) k
+		ON k.`storage` = b.`storage` AND k.`storage_key` = b.`storage_key`
+	SET f.`blob_id` = k.`id`
+	WHERE f.`blob_id` <> k.`id`;
+
+DELETE b FROM `file_blob` b
+	JOIN (SELECT `storage`, `storage_key`, MIN(`id`) AS `id` FROM `file_blob` GROUP BY `storage`, `storage_key`) k
+		ON k.`storage` = b.`storage` AND k.`storage_key` = b.`storage_key`
+	WHERE b.`id` <> k.`id`;
+
+ALTER TABLE `file_blob`
+	DROP KEY `IDX_file_blob_storage`,
+	ADD UNIQUE KEY `UQ_file_blob_storage` (`storage`, `storage_key`);
+
+-- +migrate Down
+ALTER TABLE `file_blob`
+	DROP KEY `UQ_file_blob_storage`,
+	ADD KEY `IDX_file_blob_storage` (`storage`, `storage_key`);
@@ -0,0 +1,20 @@
+-- +migrate Up
+CREATE TABLE `upload_chunk` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `upload_id` int(11) NOT NULL COMMENT 'Upload the chunk belongs to.',
+  `offset` bigint(20) NOT NULL COMMENT 'Position of the first byte of the chunk in the uploaded file.',
+  `size` bigint(20) NOT NULL COMMENT 'Size of the chunk in bytes.',
+  `storage` varchar(16) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Storage backend the chunk is stored in, e.g. local or s3.',
+  `storage_key` varchar(256) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Key the chunk is stored under in its storage backend.',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp() COMMENT 'When the chunk was received.',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `UQ_upload_chunk_offset` (`upload_id`, `offset`),
+  CONSTRAINT `fk_upload_chunk_upload1` FOREIGN KEY (`upload_id`) REFERENCES `upload` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci COMMENT='Chunks of uploads, which are kept in the storage backends, so that every server can receive the next one.';
+
+ALTER TABLE `upload` COMMENT='Files uploaded in chunks, which are kept until they are saved as a file.';
+
+-- +migrate Down
+ALTER TABLE `upload` COMMENT='Files uploaded in chunks, which are kept in Files.UploadPath until they are saved as a file.';
+
+DROP TABLE `upload_chunk`;
@@ -0,0 +1,10 @@
+-- +migrate Up
+ALTER TABLE `submission` ADD `notified_at` timestamp NULL DEFAULT NULL COMMENT 'When the users of the course were notified that the submission is visible; NULL if they weren''t yet.';
+ALTER TABLE `submission` ADD KEY `IDX_submission_notified` (`notified_at`, `visible_from`);
+
+-- submissions that are visible already were notified about before the column existed
+UPDATE `submission` SET `notified_at` = `visible_from` WHERE `visible_from` <= NOW();
+
+-- +migrate Down
+ALTER TABLE `submission` DROP KEY `IDX_submission_notified`;
+ALTER TABLE `submission` DROP COLUMN `notified_at`;
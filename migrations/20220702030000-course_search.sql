@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE `course` ADD FULLTEXT KEY `FT_course_search` (`name`, `description`);
+
+-- +migrate Down
+ALTER TABLE `course` DROP KEY `FT_course_search`;
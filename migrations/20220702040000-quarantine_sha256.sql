@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE `quarantine`
+	MODIFY `storage_key` varchar(256) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Key the content is kept under in the quarantine directory, shared by all records of the same content.',
+	ADD KEY `IDX_quarantine_sha256` (`sha256`);
+
+-- +migrate Down
+ALTER TABLE `quarantine`
+	DROP KEY `IDX_quarantine_sha256`,
+	MODIFY `storage_key` varchar(256) COLLATE utf8_unicode_ci NOT NULL COMMENT 'Key the content is kept under in the quarantine directory.';
@@ -36,6 +36,8 @@ type Appointment struct {
 	UpdatedAt null.Time `boil:"updated_at" json:"updated_at,omitempty" toml:"updated_at" yaml:"updated_at,omitempty"`
 	DeletedAt null.Time `boil:"deleted_at" json:"deleted_at,omitempty" toml:"deleted_at" yaml:"deleted_at,omitempty"`
 	Duration  int       `boil:"duration" json:"duration" toml:"duration" yaml:"duration"`
+	// The series this appointment is part of, if it repeats.
+	SeriesID null.Int `boil:"series_id" json:"series_id,omitempty" toml:"series_id" yaml:"series_id,omitempty"`
 
 	R *appointmentR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L appointmentL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -51,6 +53,7 @@ var AppointmentColumns = struct {
 	UpdatedAt string
 	DeletedAt string
 	Duration  string
+	SeriesID  string
 }{
 	ID:        "id",
 	Date:      "date",
@@ -61,6 +64,7 @@ var AppointmentColumns = struct {
 	UpdatedAt: "updated_at",
 	DeletedAt: "deleted_at",
 	Duration:  "duration",
+	SeriesID:  "series_id",
 }
 
 var AppointmentTableColumns = struct {
@@ -73,6 +77,7 @@ var AppointmentTableColumns = struct {
 	UpdatedAt string
 	DeletedAt string
 	Duration  string
+	SeriesID  string
 }{
 	ID:        "appointment.id",
 	Date:      "appointment.date",
@@ -83,6 +88,7 @@ var AppointmentTableColumns = struct {
 	UpdatedAt: "appointment.updated_at",
 	DeletedAt: "appointment.deleted_at",
 	Duration:  "appointment.duration",
+	SeriesID:  "appointment.series_id",
 }
 
 // Generated where
@@ -212,6 +218,7 @@ var AppointmentWhere = struct {
 	UpdatedAt whereHelpernull_Time
 	DeletedAt whereHelpernull_Time
 	Duration  whereHelperint
+	SeriesID  whereHelpernull_Int
 }{
 	ID:        whereHelperint{field: "`appointment`.`id`"},
 	Date:      whereHelpertime_Time{field: "`appointment`.`date`"},
@@ -222,6 +229,7 @@ var AppointmentWhere = struct {
 	UpdatedAt: whereHelpernull_Time{field: "`appointment`.`updated_at`"},
 	DeletedAt: whereHelpernull_Time{field: "`appointment`.`deleted_at`"},
 	Duration:  whereHelperint{field: "`appointment`.`duration`"},
+	SeriesID:  whereHelpernull_Int{field: "`appointment`.`series_id`"},
 }
 
 // AppointmentRels is where relationship names are stored.
@@ -252,8 +260,8 @@ func (r *appointmentR) GetCourse() *Course {
 type appointmentL struct{}
 
 var (
-	appointmentAllColumns            = []string{"id", "date", "location", "online", "course_id", "created_at", "updated_at", "deleted_at", "duration"}
-	appointmentColumnsWithoutDefault = []string{"location", "online", "course_id", "updated_at", "deleted_at", "duration"}
+	appointmentAllColumns            = []string{"id", "date", "location", "online", "course_id", "created_at", "updated_at", "deleted_at", "duration", "series_id"}
+	appointmentColumnsWithoutDefault = []string{"location", "online", "course_id", "updated_at", "deleted_at", "duration", "series_id"}
 	appointmentColumnsWithDefault    = []string{"id", "date", "created_at"}
 	appointmentPrimaryKeyColumns     = []string{"id"}
 	appointmentGeneratedColumns      = []string{}
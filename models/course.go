@@ -33,6 +33,12 @@ type Course struct {
 	CreatedAt   null.Time   `boil:"created_at" json:"created_at,omitempty" toml:"created_at" yaml:"created_at,omitempty"`
 	UpdatedAt   null.Time   `boil:"updated_at" json:"updated_at,omitempty" toml:"updated_at" yaml:"updated_at,omitempty"`
 	DeletedAt   null.Time   `boil:"deleted_at" json:"deleted_at,omitempty" toml:"deleted_at" yaml:"deleted_at,omitempty"`
+	// How users enroll in this course. 0: with the enroll key, 1: open, 2: approval required, 3: closed.
+	EnrollMode int8 `boil:"enroll_mode" json:"enroll_mode" toml:"enroll_mode" yaml:"enroll_mode"`
+	// Maximum amount of users enrolled in this course, NULL if unlimited.
+	Capacity null.Int `boil:"capacity" json:"capacity,omitempty" toml:"capacity" yaml:"capacity,omitempty"`
+	// The time this course was archived, which makes it read-only; NULL if it is active.
+	ArchivedAt null.Time `boil:"archived_at" json:"archived_at,omitempty" toml:"archived_at" yaml:"archived_at,omitempty"`
 
 	R *courseR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L courseL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -47,6 +53,9 @@ var CourseColumns = struct {
 	CreatedAt   string
 	UpdatedAt   string
 	DeletedAt   string
+	EnrollMode  string
+	Capacity    string
+	ArchivedAt  string
 }{
 	ID:          "id",
 	Name:        "name",
@@ -56,6 +65,9 @@ var CourseColumns = struct {
 	CreatedAt:   "created_at",
 	UpdatedAt:   "updated_at",
 	DeletedAt:   "deleted_at",
+	EnrollMode:  "enroll_mode",
+	Capacity:    "capacity",
+	ArchivedAt:  "archived_at",
 }
 
 var CourseTableColumns = struct {
@@ -67,6 +79,9 @@ var CourseTableColumns = struct {
 	CreatedAt   string
 	UpdatedAt   string
 	DeletedAt   string
+	EnrollMode  string
+	Capacity    string
+	ArchivedAt  string
 }{
 	ID:          "course.id",
 	Name:        "course.name",
@@ -76,6 +91,9 @@ var CourseTableColumns = struct {
 	CreatedAt:   "course.created_at",
 	UpdatedAt:   "course.updated_at",
 	DeletedAt:   "course.deleted_at",
+	EnrollMode:  "course.enroll_mode",
+	Capacity:    "course.capacity",
+	ArchivedAt:  "course.archived_at",
 }
 
 // Generated where
@@ -89,6 +107,9 @@ var CourseWhere = struct {
 	CreatedAt   whereHelpernull_Time
 	UpdatedAt   whereHelpernull_Time
 	DeletedAt   whereHelpernull_Time
+	EnrollMode  whereHelperint8
+	Capacity    whereHelpernull_Int
+	ArchivedAt  whereHelpernull_Time
 }{
 	ID:          whereHelperint{field: "`course`.`id`"},
 	Name:        whereHelperstring{field: "`course`.`name`"},
@@ -98,6 +119,9 @@ var CourseWhere = struct {
 	CreatedAt:   whereHelpernull_Time{field: "`course`.`created_at`"},
 	UpdatedAt:   whereHelpernull_Time{field: "`course`.`updated_at`"},
 	DeletedAt:   whereHelpernull_Time{field: "`course`.`deleted_at`"},
+	EnrollMode:  whereHelperint8{field: "`course`.`enroll_mode`"},
+	Capacity:    whereHelpernull_Int{field: "`course`.`capacity`"},
+	ArchivedAt:  whereHelpernull_Time{field: "`course`.`archived_at`"},
 }
 
 // CourseRels is where relationship names are stored.
@@ -218,9 +242,9 @@ func (r *courseR) GetUserHasCourses() UserHasCourseSlice {
 type courseL struct{}
 
 var (
-	courseAllColumns            = []string{"id", "name", "description", "enroll_key", "forum_id", "created_at", "updated_at", "deleted_at"}
-	courseColumnsWithoutDefault = []string{"name", "description", "enroll_key", "forum_id", "created_at", "updated_at", "deleted_at"}
-	courseColumnsWithDefault    = []string{"id"}
+	courseAllColumns            = []string{"id", "name", "description", "enroll_key", "forum_id", "created_at", "updated_at", "deleted_at", "enroll_mode", "capacity", "archived_at"}
+	courseColumnsWithoutDefault = []string{"name", "description", "enroll_key", "forum_id", "created_at", "updated_at", "deleted_at", "capacity", "archived_at"}
+	courseColumnsWithDefault    = []string{"id", "enroll_mode"}
 	coursePrimaryKeyColumns     = []string{"id"}
 	courseGeneratedColumns      = []string{}
 )
@@ -29,6 +29,8 @@ type Directory struct {
 	Name string `boil:"name" json:"name" toml:"name" yaml:"name"`
 	// The course this directory is displayed in.
 	CourseID int `boil:"course_id" json:"course_id" toml:"course_id" yaml:"course_id"`
+	// The directory this directory is contained in, if it isn't at the top level of the course.
+	ParentID null.Int `boil:"parent_id" json:"parent_id,omitempty" toml:"parent_id" yaml:"parent_id,omitempty"`
 	// At which date the folder will be visible to enrolled users.
 	VisibleFrom time.Time `boil:"visible_from" json:"visible_from" toml:"visible_from" yaml:"visible_from"`
 	// The date this directory has been created.
@@ -44,6 +46,7 @@ var DirectoryColumns = struct {
 	ID          string
 	Name        string
 	CourseID    string
+	ParentID    string
 	VisibleFrom string
 	CreatedAt   string
 	UpdatedAt   string
@@ -52,6 +55,7 @@ var DirectoryColumns = struct {
 	ID:          "id",
 	Name:        "name",
 	CourseID:    "course_id",
+	ParentID:    "parent_id",
 	VisibleFrom: "visible_from",
 	CreatedAt:   "created_at",
 	UpdatedAt:   "updated_at",
@@ -62,6 +66,7 @@ var DirectoryTableColumns = struct {
 	ID          string
 	Name        string
 	CourseID    string
+	ParentID    string
 	VisibleFrom string
 	CreatedAt   string
 	UpdatedAt   string
@@ -70,6 +75,7 @@ var DirectoryTableColumns = struct {
 	ID:          "directory.id",
 	Name:        "directory.name",
 	CourseID:    "directory.course_id",
+	ParentID:    "directory.parent_id",
 	VisibleFrom: "directory.visible_from",
 	CreatedAt:   "directory.created_at",
 	UpdatedAt:   "directory.updated_at",
@@ -82,6 +88,7 @@ var DirectoryWhere = struct {
 	ID          whereHelperint
 	Name        whereHelperstring
 	CourseID    whereHelperint
+	ParentID    whereHelpernull_Int
 	VisibleFrom whereHelpertime_Time
 	CreatedAt   whereHelpertime_Time
 	UpdatedAt   whereHelpernull_Time
@@ -90,6 +97,7 @@ var DirectoryWhere = struct {
 	ID:          whereHelperint{field: "`directory`.`id`"},
 	Name:        whereHelperstring{field: "`directory`.`name`"},
 	CourseID:    whereHelperint{field: "`directory`.`course_id`"},
+	ParentID:    whereHelpernull_Int{field: "`directory`.`parent_id`"},
 	VisibleFrom: whereHelpertime_Time{field: "`directory`.`visible_from`"},
 	CreatedAt:   whereHelpertime_Time{field: "`directory`.`created_at`"},
 	UpdatedAt:   whereHelpernull_Time{field: "`directory`.`updated_at`"},
@@ -134,8 +142,8 @@ func (r *directoryR) GetFiles() FileSlice {
 type directoryL struct{}
 
 var (
-	directoryAllColumns            = []string{"id", "name", "course_id", "visible_from", "created_at", "updated_at", "deleted_at"}
-	directoryColumnsWithoutDefault = []string{"name", "course_id", "updated_at", "deleted_at"}
+	directoryAllColumns            = []string{"id", "name", "course_id", "parent_id", "visible_from", "created_at", "updated_at", "deleted_at"}
+	directoryColumnsWithoutDefault = []string{"name", "course_id", "parent_id", "updated_at", "deleted_at"}
 	directoryColumnsWithDefault    = []string{"id", "visible_from", "created_at"}
 	directoryPrimaryKeyColumns     = []string{"id"}
 	directoryGeneratedColumns      = []string{}
@@ -590,7 +598,7 @@ func (directoryL) LoadFiles(ctx context.Context, e boil.ContextExecutor, singula
 	}
 
 	query := NewQuery(
-		qm.Select("`file`.`id`, `file`.`name`, `file`.`uri`, `file`.`local`, `file`.`uploader_id`, `file`.`created_at`, `file`.`deleted_at`, `a`.`directory_id`"),
+		qm.Select("`file`.`id`, `file`.`name`, `file`.`uri`, `file`.`local`, `file`.`uploader_id`, `file`.`created_at`, `file`.`deleted_at`, `file`.`blob_id`, `file`.`charged_bytes`, `file`.`mime_type`, `a`.`directory_id`"),
 		qm.From("`file`"),
 		qm.InnerJoin("`directory_has_files` as `a` on `file`.`id` = `a`.`file_id`"),
 		qm.WhereIn("`a`.`directory_id` in ?", args...),
@@ -612,7 +620,7 @@ func (directoryL) LoadFiles(ctx context.Context, e boil.ContextExecutor, singula
 		one := new(File)
 		var localJoinCol int
 
-		err = results.Scan(&one.ID, &one.Name, &one.URI, &one.Local, &one.UploaderID, &one.CreatedAt, &one.DeletedAt, &localJoinCol)
+		err = results.Scan(&one.ID, &one.Name, &one.URI, &one.Local, &one.UploaderID, &one.CreatedAt, &one.DeletedAt, &one.BlobID, &one.ChargedBytes, &one.MimeType, &localJoinCol)
 		if err != nil {
 			return errors.Wrap(err, "failed to scan eager loaded results for file")
 		}
@@ -36,65 +36,89 @@ type File struct {
 	// When the file was created.
 	CreatedAt time.Time `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
 	DeletedAt null.Time `boil:"deleted_at" json:"deleted_at,omitempty" toml:"deleted_at" yaml:"deleted_at,omitempty"`
+	// Content of a local file.
+	BlobID null.Int `boil:"blob_id" json:"blob_id,omitempty" toml:"blob_id" yaml:"blob_id,omitempty"`
+	// Bytes counted against the upload limit of the uploader for this file, refunded when it is deleted.
+	ChargedBytes int `boil:"charged_bytes" json:"charged_bytes" toml:"charged_bytes" yaml:"charged_bytes"`
+	// MIME type detected from the content of a local file, sent when it is downloaded.
+	MimeType null.String `boil:"mime_type" json:"mime_type,omitempty" toml:"mime_type" yaml:"mime_type,omitempty"`
 
 	R *fileR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L fileL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var FileColumns = struct {
-	ID         string
-	Name       string
-	URI        string
-	Local      string
-	UploaderID string
-	CreatedAt  string
-	DeletedAt  string
+	ID           string
+	Name         string
+	URI          string
+	Local        string
+	UploaderID   string
+	CreatedAt    string
+	DeletedAt    string
+	BlobID       string
+	ChargedBytes string
+	MimeType     string
 }{
-	ID:         "id",
-	Name:       "name",
-	URI:        "uri",
-	Local:      "local",
-	UploaderID: "uploader_id",
-	CreatedAt:  "created_at",
-	DeletedAt:  "deleted_at",
+	ID:           "id",
+	Name:         "name",
+	URI:          "uri",
+	Local:        "local",
+	UploaderID:   "uploader_id",
+	CreatedAt:    "created_at",
+	DeletedAt:    "deleted_at",
+	BlobID:       "blob_id",
+	ChargedBytes: "charged_bytes",
+	MimeType:     "mime_type",
 }
 
 var FileTableColumns = struct {
-	ID         string
-	Name       string
-	URI        string
-	Local      string
-	UploaderID string
-	CreatedAt  string
-	DeletedAt  string
+	ID           string
+	Name         string
+	URI          string
+	Local        string
+	UploaderID   string
+	CreatedAt    string
+	DeletedAt    string
+	BlobID       string
+	ChargedBytes string
+	MimeType     string
 }{
-	ID:         "file.id",
-	Name:       "file.name",
-	URI:        "file.uri",
-	Local:      "file.local",
-	UploaderID: "file.uploader_id",
-	CreatedAt:  "file.created_at",
-	DeletedAt:  "file.deleted_at",
+	ID:           "file.id",
+	Name:         "file.name",
+	URI:          "file.uri",
+	Local:        "file.local",
+	UploaderID:   "file.uploader_id",
+	CreatedAt:    "file.created_at",
+	DeletedAt:    "file.deleted_at",
+	BlobID:       "file.blob_id",
+	ChargedBytes: "file.charged_bytes",
+	MimeType:     "file.mime_type",
 }
 
 // Generated where
 
 var FileWhere = struct {
-	ID         whereHelperint
-	Name       whereHelperstring
-	URI        whereHelperstring
-	Local      whereHelperint8
-	UploaderID whereHelperint
-	CreatedAt  whereHelpertime_Time
-	DeletedAt  whereHelpernull_Time
+	ID           whereHelperint
+	Name         whereHelperstring
+	URI          whereHelperstring
+	Local        whereHelperint8
+	UploaderID   whereHelperint
+	CreatedAt    whereHelpertime_Time
+	DeletedAt    whereHelpernull_Time
+	BlobID       whereHelpernull_Int
+	ChargedBytes whereHelperint
+	MimeType     whereHelpernull_String
 }{
-	ID:         whereHelperint{field: "`file`.`id`"},
-	Name:       whereHelperstring{field: "`file`.`name`"},
-	URI:        whereHelperstring{field: "`file`.`uri`"},
-	Local:      whereHelperint8{field: "`file`.`local`"},
-	UploaderID: whereHelperint{field: "`file`.`uploader_id`"},
-	CreatedAt:  whereHelpertime_Time{field: "`file`.`created_at`"},
-	DeletedAt:  whereHelpernull_Time{field: "`file`.`deleted_at`"},
+	ID:           whereHelperint{field: "`file`.`id`"},
+	Name:         whereHelperstring{field: "`file`.`name`"},
+	URI:          whereHelperstring{field: "`file`.`uri`"},
+	Local:        whereHelperint8{field: "`file`.`local`"},
+	UploaderID:   whereHelperint{field: "`file`.`uploader_id`"},
+	CreatedAt:    whereHelpertime_Time{field: "`file`.`created_at`"},
+	DeletedAt:    whereHelpernull_Time{field: "`file`.`deleted_at`"},
+	BlobID:       whereHelpernull_Int{field: "`file`.`blob_id`"},
+	ChargedBytes: whereHelperint{field: "`file`.`charged_bytes`"},
+	MimeType:     whereHelpernull_String{field: "`file`.`mime_type`"},
 }
 
 // FileRels is where relationship names are stored.
@@ -195,9 +219,9 @@ func (r *fileR) GetUserSubmissions() UserSubmissionSlice {
 type fileL struct{}
 
 var (
-	fileAllColumns            = []string{"id", "name", "uri", "local", "uploader_id", "created_at", "deleted_at"}
-	fileColumnsWithoutDefault = []string{"name", "uri", "local", "uploader_id", "deleted_at"}
-	fileColumnsWithDefault    = []string{"id", "created_at"}
+	fileAllColumns            = []string{"id", "name", "uri", "local", "uploader_id", "created_at", "deleted_at", "blob_id", "charged_bytes", "mime_type"}
+	fileColumnsWithoutDefault = []string{"name", "uri", "local", "uploader_id", "deleted_at", "blob_id", "mime_type"}
+	fileColumnsWithDefault    = []string{"id", "created_at", "charged_bytes"}
 	filePrimaryKeyColumns     = []string{"id"}
 	fileGeneratedColumns      = []string{}
 )
@@ -40,83 +40,99 @@ type Submission struct {
 	UpdatedAt null.Time `boil:"updated_at" json:"updated_at,omitempty" toml:"updated_at" yaml:"updated_at,omitempty"`
 	GradedAt  null.Time `boil:"graded_at" json:"graded_at,omitempty" toml:"graded_at" yaml:"graded_at,omitempty"`
 	DeletedAt null.Time `boil:"deleted_at" json:"deleted_at,omitempty" toml:"deleted_at" yaml:"deleted_at,omitempty"`
+	// Comma separated extensions of the files that may be submitted, e.g. pdf,zip. All file types allowed by the server if NULL.
+	AllowedFileTypes null.String `boil:"allowed_file_types" json:"allowed_file_types,omitempty" toml:"allowed_file_types" yaml:"allowed_file_types,omitempty"`
+	// When the users of the course were notified that the submission is visible; NULL if they weren't yet.
+	NotifiedAt null.Time `boil:"notified_at" json:"notified_at,omitempty" toml:"notified_at" yaml:"notified_at,omitempty"`
 
 	R *submissionR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L submissionL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var SubmissionColumns = struct {
-	ID          string
-	Name        string
-	Deadline    string
-	CourseID    string
-	MaxFilesize string
-	VisibleFrom string
-	CreatedAt   string
-	UpdatedAt   string
-	GradedAt    string
-	DeletedAt   string
+	ID               string
+	Name             string
+	Deadline         string
+	CourseID         string
+	MaxFilesize      string
+	VisibleFrom      string
+	CreatedAt        string
+	UpdatedAt        string
+	GradedAt         string
+	DeletedAt        string
+	AllowedFileTypes string
+	NotifiedAt       string
 }{
-	ID:          "id",
-	Name:        "name",
-	Deadline:    "deadline",
-	CourseID:    "course_id",
-	MaxFilesize: "max_filesize",
-	VisibleFrom: "visible_from",
-	CreatedAt:   "created_at",
-	UpdatedAt:   "updated_at",
-	GradedAt:    "graded_at",
-	DeletedAt:   "deleted_at",
+	ID:               "id",
+	Name:             "name",
+	Deadline:         "deadline",
+	CourseID:         "course_id",
+	MaxFilesize:      "max_filesize",
+	VisibleFrom:      "visible_from",
+	CreatedAt:        "created_at",
+	UpdatedAt:        "updated_at",
+	GradedAt:         "graded_at",
+	DeletedAt:        "deleted_at",
+	AllowedFileTypes: "allowed_file_types",
+	NotifiedAt:       "notified_at",
 }
 
 var SubmissionTableColumns = struct {
-	ID          string
-	Name        string
-	Deadline    string
-	CourseID    string
-	MaxFilesize string
-	VisibleFrom string
-	CreatedAt   string
-	UpdatedAt   string
-	GradedAt    string
-	DeletedAt   string
+	ID               string
+	Name             string
+	Deadline         string
+	CourseID         string
+	MaxFilesize      string
+	VisibleFrom      string
+	CreatedAt        string
+	UpdatedAt        string
+	GradedAt         string
+	DeletedAt        string
+	AllowedFileTypes string
+	NotifiedAt       string
 }{
-	ID:          "submission.id",
-	Name:        "submission.name",
-	Deadline:    "submission.deadline",
-	CourseID:    "submission.course_id",
-	MaxFilesize: "submission.max_filesize",
-	VisibleFrom: "submission.visible_from",
-	CreatedAt:   "submission.created_at",
-	UpdatedAt:   "submission.updated_at",
-	GradedAt:    "submission.graded_at",
-	DeletedAt:   "submission.deleted_at",
+	ID:               "submission.id",
+	Name:             "submission.name",
+	Deadline:         "submission.deadline",
+	CourseID:         "submission.course_id",
+	MaxFilesize:      "submission.max_filesize",
+	VisibleFrom:      "submission.visible_from",
+	CreatedAt:        "submission.created_at",
+	UpdatedAt:        "submission.updated_at",
+	GradedAt:         "submission.graded_at",
+	DeletedAt:        "submission.deleted_at",
+	AllowedFileTypes: "submission.allowed_file_types",
+	NotifiedAt:       "submission.notified_at",
 }
 
 // Generated where
 
 var SubmissionWhere = struct {
-	ID          whereHelperint
-	Name        whereHelperstring
-	Deadline    whereHelpernull_Time
-	CourseID    whereHelperint
-	MaxFilesize whereHelperint
-	VisibleFrom whereHelpertime_Time
-	CreatedAt   whereHelpertime_Time
-	UpdatedAt   whereHelpernull_Time
-	GradedAt    whereHelpernull_Time
-	DeletedAt   whereHelpernull_Time
+	ID               whereHelperint
+	Name             whereHelperstring
+	Deadline         whereHelpernull_Time
+	CourseID         whereHelperint
+	MaxFilesize      whereHelperint
+	VisibleFrom      whereHelpertime_Time
+	CreatedAt        whereHelpertime_Time
+	UpdatedAt        whereHelpernull_Time
+	GradedAt         whereHelpernull_Time
+	DeletedAt        whereHelpernull_Time
+	AllowedFileTypes whereHelpernull_String
+	NotifiedAt       whereHelpernull_Time
 }{
-	ID:          whereHelperint{field: "`submission`.`id`"},
-	Name:        whereHelperstring{field: "`submission`.`name`"},
-	Deadline:    whereHelpernull_Time{field: "`submission`.`deadline`"},
-	CourseID:    whereHelperint{field: "`submission`.`course_id`"},
-	MaxFilesize: whereHelperint{field: "`submission`.`max_filesize`"},
-	VisibleFrom: whereHelpertime_Time{field: "`submission`.`visible_from`"},
-	CreatedAt:   whereHelpertime_Time{field: "`submission`.`created_at`"},
-	UpdatedAt:   whereHelpernull_Time{field: "`submission`.`updated_at`"},
-	GradedAt:    whereHelpernull_Time{field: "`submission`.`graded_at`"},
-	DeletedAt:   whereHelpernull_Time{field: "`submission`.`deleted_at`"},
+	ID:               whereHelperint{field: "`submission`.`id`"},
+	Name:             whereHelperstring{field: "`submission`.`name`"},
+	Deadline:         whereHelpernull_Time{field: "`submission`.`deadline`"},
+	CourseID:         whereHelperint{field: "`submission`.`course_id`"},
+	MaxFilesize:      whereHelperint{field: "`submission`.`max_filesize`"},
+	VisibleFrom:      whereHelpertime_Time{field: "`submission`.`visible_from`"},
+	CreatedAt:        whereHelpertime_Time{field: "`submission`.`created_at`"},
+	UpdatedAt:        whereHelpernull_Time{field: "`submission`.`updated_at`"},
+	GradedAt:         whereHelpernull_Time{field: "`submission`.`graded_at`"},
+	DeletedAt:        whereHelpernull_Time{field: "`submission`.`deleted_at`"},
+	AllowedFileTypes: whereHelpernull_String{field: "`submission`.`allowed_file_types`"},
+	NotifiedAt:       whereHelpernull_Time{field: "`submission`.`notified_at`"},
 }
 
 // SubmissionRels is where relationship names are stored.
@@ -167,8 +183,8 @@ func (r *submissionR) GetUserSubmissions() UserSubmissionSlice {
 type submissionL struct{}
 
 var (
-	submissionAllColumns            = []string{"id", "name", "deadline", "course_id", "max_filesize", "visible_from", "created_at", "updated_at", "graded_at", "deleted_at"}
-	submissionColumnsWithoutDefault = []string{"name", "deadline", "course_id", "updated_at", "graded_at", "deleted_at"}
+	submissionAllColumns            = []string{"id", "name", "deadline", "course_id", "max_filesize", "visible_from", "created_at", "updated_at", "graded_at", "deleted_at", "allowed_file_types", "notified_at"}
+	submissionColumnsWithoutDefault = []string{"name", "deadline", "course_id", "updated_at", "graded_at", "deleted_at", "allowed_file_types", "notified_at"}
 	submissionColumnsWithDefault    = []string{"id", "max_filesize", "visible_from", "created_at"}
 	submissionPrimaryKeyColumns     = []string{"id"}
 	submissionGeneratedColumns      = []string{}
@@ -637,7 +653,7 @@ func (submissionL) LoadFiles(ctx context.Context, e boil.ContextExecutor, singul
 	}
 
 	query := NewQuery(
-		qm.Select("`file`.`id`, `file`.`name`, `file`.`uri`, `file`.`local`, `file`.`uploader_id`, `file`.`created_at`, `file`.`deleted_at`, `a`.`submission_id`"),
+		qm.Select("`file`.`id`, `file`.`name`, `file`.`uri`, `file`.`local`, `file`.`uploader_id`, `file`.`created_at`, `file`.`deleted_at`, `file`.`blob_id`, `file`.`charged_bytes`, `file`.`mime_type`, `a`.`submission_id`"),
 		qm.From("`file`"),
 		qm.InnerJoin("`submission_has_files` as `a` on `file`.`id` = `a`.`file_id`"),
 		qm.WhereIn("`a`.`submission_id` in ?", args...),
@@ -659,7 +675,7 @@ func (submissionL) LoadFiles(ctx context.Context, e boil.ContextExecutor, singul
 		one := new(File)
 		var localJoinCol int
 
-		err = results.Scan(&one.ID, &one.Name, &one.URI, &one.Local, &one.UploaderID, &one.CreatedAt, &one.DeletedAt, &localJoinCol)
+		err = results.Scan(&one.ID, &one.Name, &one.URI, &one.Local, &one.UploaderID, &one.CreatedAt, &one.DeletedAt, &one.BlobID, &one.ChargedBytes, &one.MimeType, &localJoinCol)
 		if err != nil {
 			return errors.Wrap(err, "failed to scan eager loaded results for file")
 		}
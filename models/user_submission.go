@@ -740,7 +740,7 @@ func (userSubmissionL) LoadFiles(ctx context.Context, e boil.ContextExecutor, si
 	}
 
 	query := NewQuery(
-		qm.Select("`file`.`id`, `file`.`name`, `file`.`uri`, `file`.`local`, `file`.`uploader_id`, `file`.`created_at`, `file`.`deleted_at`, `a`.`user_submission_id`"),
+		qm.Select("`file`.`id`, `file`.`name`, `file`.`uri`, `file`.`local`, `file`.`uploader_id`, `file`.`created_at`, `file`.`deleted_at`, `file`.`blob_id`, `file`.`charged_bytes`, `file`.`mime_type`, `a`.`user_submission_id`"),
 		qm.From("`file`"),
 		qm.InnerJoin("`user_submission_has_files` as `a` on `file`.`id` = `a`.`file_id`"),
 		qm.WhereIn("`a`.`user_submission_id` in ?", args...),
@@ -762,7 +762,7 @@ func (userSubmissionL) LoadFiles(ctx context.Context, e boil.ContextExecutor, si
 		one := new(File)
 		var localJoinCol int
 
-		err = results.Scan(&one.ID, &one.Name, &one.URI, &one.Local, &one.UploaderID, &one.CreatedAt, &one.DeletedAt, &localJoinCol)
+		err = results.Scan(&one.ID, &one.Name, &one.URI, &one.Local, &one.UploaderID, &one.CreatedAt, &one.DeletedAt, &one.BlobID, &one.ChargedBytes, &one.MimeType, &localJoinCol)
 		if err != nil {
 			return errors.Wrap(err, "failed to scan eager loaded results for file")
 		}
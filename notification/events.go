@@ -0,0 +1,250 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"learningbay24.de/backend/dbi"
+	"learningbay24.de/backend/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// How far ahead of an exam's register deadline users are reminded of it
+const registerDeadlineReminder = 24 * time.Hour
+
+// getCourseUsers takes the ID of a course and returns the IDs of all users enrolled in it as regular users
+func getCourseUsers(exec boil.ContextExecutor, courseId int) ([]int, error) {
+	uhc, err := models.UserHasCourses(
+		models.UserHasCourseWhere.CourseID.EQ(courseId),
+		models.UserHasCourseWhere.RoleID.EQ(dbi.CourseUserRoleId),
+	).All(context.Background(), exec)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(uhc))
+	for _, u := range uhc {
+		ids = append(ids, u.UserID)
+	}
+
+	return ids, nil
+}
+
+// createOnce creates a notification for every given user who didn't already get the same one
+func createOnce(db *sql.DB, userIds []int, title string, body string, url string) error {
+	var missing []int
+	for _, id := range userIds {
+		ok, err := exists(db, id, title, url)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	return CreateForUsers(db, missing, title, body, url)
+}
+
+// NotifyUserSubmissionGraded notifies the submitter of a user submission that it has been graded
+func NotifyUserSubmissionGraded(db *sql.DB, userSubmissionId int) error {
+	us, err := models.FindUserSubmission(context.Background(), db, userSubmissionId)
+	if err != nil {
+		return err
+	}
+
+	s, err := models.FindSubmission(context.Background(), db, us.SubmissionID)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("Submission \"%s\" graded", s.Name)
+	url := fmt.Sprintf("/courses/%d/submissions/%d", s.CourseID, s.ID)
+	_, err = Create(db, us.SubmitterID, title, fmt.Sprintf("Your grade: %d", us.Grade.Int), url)
+
+	return err
+}
+
+// NotifyExamGraded notifies a user that their answer to an exam has been graded
+func NotifyExamGraded(db *sql.DB, examId int, userId int) error {
+	ex, err := models.FindExam(context.Background(), db, examId)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("Exam \"%s\" graded", ex.Name)
+	url := fmt.Sprintf("/courses/%d/exams/%d", ex.CourseID, ex.ID)
+	_, err = Create(db, userId, title, "Your answer has been graded", url)
+
+	return err
+}
+
+// NotifyAppointmentCreated notifies all users of a course about a new appointment of it
+func NotifyAppointmentCreated(db *sql.DB, appointmentId int) error {
+	a, err := models.FindAppointment(context.Background(), db, appointmentId)
+	if err != nil {
+		return err
+	}
+
+	c, err := models.FindCourse(context.Background(), db, a.CourseID)
+	if err != nil {
+		return err
+	}
+
+	users, err := getCourseUsers(db, c.ID)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("New appointment in \"%s\"", c.Name)
+	body := fmt.Sprintf("On %s", a.Date.Format("02.01.2006 15:04"))
+	if a.Location.Valid {
+		body += fmt.Sprintf(" at %s", a.Location.String)
+	}
+
+	return CreateForUsers(db, users, title, body, fmt.Sprintf("/courses/%d", c.ID))
+}
+
+// notifyVisibleSubmissions notifies the users of a course about every submission that became visible up to now and wasn't notified about yet.
+// As submissions may only be created to become visible in the future, this can't be done on creation.
+func notifyVisibleSubmissions(db *sql.DB, now time.Time) error {
+	submissions, err := models.Submissions(
+		qm.Select(models.SubmissionColumns.ID),
+		models.SubmissionWhere.NotifiedAt.IsNull(),
+		models.SubmissionWhere.VisibleFrom.LTE(now),
+		qm.Expr(
+			models.SubmissionWhere.Deadline.IsNull(),
+			qm.Or2(models.SubmissionWhere.Deadline.GT(null.TimeFrom(now))),
+		),
+	).All(context.Background(), db)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range submissions {
+		if err := notifyVisibleSubmission(db, s.ID, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notifyVisibleSubmission notifies the users of a course about a visible submission and marks it as notified in the same transaction,
+// so that every user is notified exactly once, even if several servers run the scheduler
+func notifyVisibleSubmission(db *sql.DB, submissionId int, now time.Time) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	s, err := models.Submissions(
+		models.SubmissionWhere.ID.EQ(submissionId),
+		models.SubmissionWhere.NotifiedAt.IsNull(),
+		qm.For("update"),
+	).One(context.Background(), tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		// notified by another server in the meantime
+		return tx.Rollback()
+	}
+
+	var users []int
+	if err == nil {
+		users, err = getCourseUsers(tx, s.CourseID)
+	}
+	if err == nil {
+		title := fmt.Sprintf("New submission \"%s\"", s.Name)
+		body := ""
+		if s.Deadline.Valid {
+			body = fmt.Sprintf("Due %s", s.Deadline.Time.Format("02.01.2006 15:04"))
+		}
+		url := fmt.Sprintf("/courses/%d/submissions/%d", s.CourseID, s.ID)
+
+		for _, id := range users {
+			if _, err = Create(tx, id, title, body, url); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		s.NotifiedAt = null.TimeFrom(now)
+		_, err = s.Update(context.Background(), tx, boil.Whitelist(models.SubmissionColumns.NotifiedAt))
+	}
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// notifyRegisterDeadlines reminds users of a course of exams whose register deadline is approaching, if they aren't registered to them yet
+func notifyRegisterDeadlines(db *sql.DB, now time.Time) error {
+	exams, err := models.Exams(
+		models.ExamWhere.RegisterDeadline.GT(null.TimeFrom(now)),
+		models.ExamWhere.RegisterDeadline.LTE(null.TimeFrom(now.Add(registerDeadlineReminder))),
+	).All(context.Background(), db)
+	if err != nil {
+		return err
+	}
+
+	for _, ex := range exams {
+		users, err := getCourseUsers(db, ex.CourseID)
+		if err != nil {
+			return err
+		}
+
+		var unregistered []int
+		for _, id := range users {
+			registered, err := models.UserHasExamExists(context.Background(), db, id, ex.ID)
+			if err != nil {
+				return err
+			}
+			if !registered {
+				unregistered = append(unregistered, id)
+			}
+		}
+
+		title := fmt.Sprintf("Register for exam \"%s\"", ex.Name)
+		body := fmt.Sprintf("Registration closes %s", ex.RegisterDeadline.Time.Format("02.01.2006 15:04"))
+		err = createOnce(db, unregistered, title, body, fmt.Sprintf("/courses/%d/exams/%d", ex.CourseID, ex.ID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunScheduler periodically creates the notifications that aren't caused by a request, but by time passing.
+// It blocks forever and is meant to be run in its own goroutine.
+func RunScheduler(db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		if err := notifyVisibleSubmissions(db, now); err != nil {
+			log.Errorf("Unable to notify about visible submissions: %s", err.Error())
+		}
+		if err := notifyRegisterDeadlines(db, now); err != nil {
+			log.Errorf("Unable to notify about register deadlines: %s", err.Error())
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,173 @@
+// Package notification implements in-app notifications that are sent to users on events in their courses
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"learningbay24.de/backend/models"
+
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// Maximum lengths of the fields of a notification, as defined in the database
+const (
+	maxTitleLength = 64
+	maxBodyLength  = 128
+	maxURLLength   = 256
+)
+
+// Create a notification for the user with the given ID.
+// Title, body and url are cut off if they exceed the length the database allows for them.
+func Create(exec boil.ContextExecutor, userId int, title string, body string, url string) (int, error) {
+	n := models.Notification{
+		Title:    truncate(title, maxTitleLength),
+		Body:     null.NewString(truncate(body, maxBodyLength), body != ""),
+		URL:      null.NewString(truncate(url, maxURLLength), url != ""),
+		UserToID: userId,
+	}
+
+	err := n.Insert(context.Background(), exec, boil.Infer())
+	if err != nil {
+		return 0, err
+	}
+
+	return n.ID, nil
+}
+
+// CreateForUsers creates the same notification for every user in userIds
+func CreateForUsers(db *sql.DB, userIds []int, title string, body string, url string) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range userIds {
+		if _, err := Create(tx, id, title, body, url); err != nil {
+			if e := tx.Rollback(); e != nil {
+				return fmt.Errorf("unable to rollback transaction on error: %s; %w", err, e)
+			}
+
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// exists checks whether the user already got a notification with the given title and url, including ones that have been deleted since
+func exists(db *sql.DB, userId int, title string, url string) (bool, error) {
+	return models.Notifications(
+		qm.WithDeleted(),
+		models.NotificationWhere.UserToID.EQ(userId),
+		models.NotificationWhere.Title.EQ(truncate(title, maxTitleLength)),
+		models.NotificationWhere.URL.EQ(null.StringFrom(truncate(url, maxURLLength))),
+	).Exists(context.Background(), db)
+}
+
+// truncate cuts off s after max characters
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+
+	return string(runes[:max])
+}
+
+// GetNotificationsFromUser takes the ID of a user and returns their notifications, newest first.
+// If unreadOnly is set, notifications that have been read already are left out.
+func GetNotificationsFromUser(db *sql.DB, userId int, unreadOnly bool) ([]*models.Notification, error) {
+	mods := []qm.QueryMod{
+		models.NotificationWhere.UserToID.EQ(userId),
+		qm.OrderBy(models.NotificationColumns.CreatedAt + " DESC"),
+	}
+	if unreadOnly {
+		mods = append(mods, models.NotificationWhere.TimeRead.IsNull())
+	}
+
+	notifications, err := models.Notifications(mods...).All(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// getNotificationFromUser returns the notification with the given ID if it was sent to the given user
+func getNotificationFromUser(db *sql.DB, userId int, notificationId int) (*models.Notification, error) {
+	return models.Notifications(
+		models.NotificationWhere.ID.EQ(notificationId),
+		models.NotificationWhere.UserToID.EQ(userId),
+	).One(context.Background(), db)
+}
+
+// MarkAsRead takes the ID of a user and of one of their notifications and marks it as read
+func MarkAsRead(db *sql.DB, userId int, notificationId int) error {
+	n, err := getNotificationFromUser(db, userId, notificationId)
+	if err != nil {
+		return err
+	}
+
+	if n.TimeRead.Valid {
+		return nil
+	}
+
+	n.TimeRead = null.TimeFrom(time.Now())
+	_, err = n.Update(context.Background(), db, boil.Infer())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MarkAllAsRead takes the ID of a user and marks all of their unread notifications as read
+func MarkAllAsRead(db *sql.DB, userId int) error {
+	_, err := models.Notifications(
+		models.NotificationWhere.UserToID.EQ(userId),
+		models.NotificationWhere.TimeRead.IsNull(),
+	).UpdateAll(context.Background(), db, models.M{models.NotificationColumns.TimeRead: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNotification takes the ID of a user and of one of their notifications and soft-deletes it
+func DeleteNotification(db *sql.DB, userId int, notificationId int) error {
+	n, err := getNotificationFromUser(db, userId, notificationId)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.Delete(context.Background(), db, false)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAllNotifications takes the ID of a user and soft-deletes all of their notifications
+func DeleteAllNotifications(db *sql.DB, userId int) error {
+	_, err := models.Notifications(models.NotificationWhere.UserToID.EQ(userId)).DeleteAll(context.Background(), db, false)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Size of the chunks contents are streamed to clamd in
+const chunkSize = 64 << 10
+
+// Clamd scans contents with a running ClamAV daemon, streaming them with the INSTREAM command
+type Clamd struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamd takes the address of clamd, like "tcp://127.0.0.1:3310" or "unix:///run/clamav/clamd.ctl",
+// and the time a command may take at most and returns a scanner using it
+func NewClamd(address string, timeout time.Duration) (*Clamd, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clamd address: %w", err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid clamd address %q", address)
+		}
+		return &Clamd{network: "tcp", address: u.Host, timeout: timeout}, nil
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid clamd address %q", address)
+		}
+		return &Clamd{network: "unix", address: u.Path, timeout: timeout}, nil
+	}
+
+	return nil, fmt.Errorf("invalid clamd address %q, has to start with tcp:// or unix://", address)
+}
+
+// command connects to clamd and sends a command, which is terminated by a null byte, as is the reply
+func (c *Clamd) command(name string) (net.Conn, error) {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if _, err := conn.Write([]byte("z" + name + "\x00")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
+
+func (c *Clamd) Scan(r io.Reader) (Result, error) {
+	conn, err := c.command("INSTREAM")
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+
+	// every chunk is prefixed by its length, the stream ends with an empty chunk
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, e := conn.Write(buf[:4+n]); e != nil {
+				// clamd closes the connection early if the content is larger than its StreamMaxLength
+				if reply, re := readReply(conn); re == nil && reply != "" {
+					return parseReply(reply)
+				}
+				return Result{}, e
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return Result{}, err
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return Result{}, err
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return Result{}, err
+	}
+	return parseReply(reply)
+}
+
+// parseReply reads the reply to a scan, like "stream: OK" or "stream: Eicar-Signature FOUND"
+func parseReply(reply string) (Result, error) {
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(reply, " FOUND")
+		if i := strings.Index(signature, ": "); i >= 0 {
+			signature = signature[i+2:]
+		}
+		return Result{Infected: true, Signature: signature}, nil
+	case strings.HasSuffix(reply, ": OK"):
+		return Result{}, nil
+	case reply == "":
+		return Result{}, errors.New("clamd: empty reply")
+	case strings.Contains(reply, "size limit exceeded"):
+		return Result{}, fmt.Errorf("clamd: %s: %w", reply, ErrTooLarge)
+	}
+
+	return Result{}, fmt.Errorf("clamd: %s", reply)
+}
+
+// Version returns the version of clamd together with the version of its signatures, like "ClamAV 0.103.6/26590/Mon Jun 27 09:00:00 2022"
+func (c *Clamd) Version() (string, error) {
+	conn, err := c.command("VERSION")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(reply, "ClamAV ") {
+		return "", fmt.Errorf("clamd: unexpected version %q", reply)
+	}
+	return reply, nil
+}
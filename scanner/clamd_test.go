@@ -0,0 +1,156 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The EICAR test file, which every scanner detects without it being harmful
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// StreamMaxLength of the fake clamd
+const fakeMaxLength = 1 << 20
+
+// fakeClamd answers INSTREAM and VERSION like clamd, finding the EICAR test file
+func fakeClamd(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn)
+		}
+	}()
+
+	return "tcp://" + l.Addr().String()
+}
+
+func serveClamd(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	cmd, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch cmd {
+	case "zVERSION\x00":
+		conn.Write([]byte("ClamAV 0.103.6/26590/Mon Jun 27 09:00:00 2022\x00"))
+	case "zINSTREAM\x00":
+		var content bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+				return
+			}
+			if content.Len() > fakeMaxLength {
+				conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				return
+			}
+		}
+
+		if strings.Contains(content.String(), eicar) {
+			conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+		} else {
+			conn.Write([]byte("stream: OK\x00"))
+		}
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
+
+func testScanner(t *testing.T, sc Scanner) {
+	version, err := sc.Version()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(version, "ClamAV "))
+
+	res, err := sc.Scan(strings.NewReader("Binary search trees\n"))
+	assert.NoError(t, err)
+	assert.False(t, res.Infected)
+
+	res, err = sc.Scan(strings.NewReader(eicar))
+	assert.NoError(t, err)
+	assert.True(t, res.Infected)
+	assert.Contains(t, res.Signature, "Eicar")
+
+	// larger than a single chunk
+	res, err = sc.Scan(io.MultiReader(bytes.NewReader(make([]byte, 3*chunkSize/2)), strings.NewReader("clean")))
+	assert.NoError(t, err)
+	assert.False(t, res.Infected)
+}
+
+func TestClamd(t *testing.T) {
+	sc, err := NewClamd(fakeClamd(t), 5*time.Second)
+	assert.NoError(t, err)
+	testScanner(t, sc)
+
+	_, err = sc.Scan(bytes.NewReader(make([]byte, fakeMaxLength+1)))
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestNewClamd(t *testing.T) {
+	sc, err := NewClamd("tcp://127.0.0.1:3310", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp", sc.network)
+	assert.Equal(t, "127.0.0.1:3310", sc.address)
+
+	sc, err = NewClamd("unix:///run/clamav/clamd.ctl", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "unix", sc.network)
+	assert.Equal(t, "/run/clamav/clamd.ctl", sc.address)
+
+	for _, address := range []string{"127.0.0.1:3310", "tcp://", "unix://", "http://127.0.0.1:3310"} {
+		_, err := NewClamd(address, time.Second)
+		assert.Error(t, err, address)
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	res, err := parseReply("stream: OK")
+	assert.NoError(t, err)
+	assert.False(t, res.Infected)
+
+	res, err = parseReply("stream: Win.Test.EICAR_HDB-1 FOUND")
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Infected: true, Signature: "Win.Test.EICAR_HDB-1"}, res)
+
+	_, err = parseReply("INSTREAM size limit exceeded. ERROR")
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = parseReply("Can't allocate memory ERROR")
+	assert.Error(t, err)
+	_, err = parseReply("")
+	assert.Error(t, err)
+}
+
+// TestClamdReal runs against a real clamd if one is configured, e.g. started with contrib/docker-test-clamav
+func TestClamdReal(t *testing.T) {
+	address := os.Getenv("LEARNINGBAY24_TEST_CLAMD")
+	if address == "" {
+		t.Skip("LEARNINGBAY24_TEST_CLAMD isn't set")
+	}
+
+	sc, err := NewClamd(address, time.Minute)
+	assert.NoError(t, err)
+	testScanner(t, sc)
+}
@@ -0,0 +1,78 @@
+// Package scanner checks the contents of uploaded files for malware
+package scanner
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"learningbay24.de/backend/config"
+)
+
+// ErrTooLarge is returned if a content is larger than the scanner accepts
+var ErrTooLarge = errors.New("content is too large to be scanned")
+
+// Result is the outcome of scanning a content
+type Result struct {
+	Infected bool
+	// Name of the signature that matched, if the content is infected
+	Signature string
+}
+
+// Scanner checks contents for malware
+type Scanner interface {
+	// Scan reads the content from r and checks it
+	Scan(r io.Reader) (Result, error)
+	// Version returns the version of the signatures, which changes whenever they are updated
+	Version() (string, error)
+}
+
+// nopScanner is used if no scanner is configured and considers every content clean
+type nopScanner struct{}
+
+func (nopScanner) Scan(r io.Reader) (Result, error) {
+	return Result{}, nil
+}
+
+func (nopScanner) Version() (string, error) {
+	return "", nil
+}
+
+var (
+	once    sync.Once
+	current Scanner
+	initErr error
+)
+
+// Enabled returns whether a scanner is configured
+func Enabled() bool {
+	return config.Conf.Files.Scanner.Clamd != ""
+}
+
+// MaxSize returns the size in bytes of the largest content the configured scanner accepts
+func MaxSize() int64 {
+	if config.Conf.Files.Scanner.MaxSize > 0 {
+		return config.Conf.Files.Scanner.MaxSize
+	}
+	// the default StreamMaxLength of clamd
+	return 25 << 20
+}
+
+// Default returns the scanner configured in the `Files.Scanner` section of the config, which doesn't find anything if none is configured
+func Default() (Scanner, error) {
+	once.Do(func() {
+		if !Enabled() {
+			current = nopScanner{}
+			return
+		}
+
+		timeout := time.Duration(config.Conf.Files.Scanner.Timeout) * time.Second
+		if timeout == 0 {
+			timeout = time.Minute
+		}
+		current, initErr = NewClamd(config.Conf.Files.Scanner.Clamd, timeout)
+	})
+
+	return current, initErr
+}
@@ -0,0 +1,64 @@
+// Package signedurl creates and checks URLs that allow downloading a single file until they expire, without being logged in
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"learningbay24.de/backend/config"
+	"learningbay24.de/backend/errs"
+)
+
+// How long a signed URL can be used
+const Lifetime = 15 * time.Minute
+
+// key returns the key URLs are signed with. Without a configured download secret it is derived from the JWT secret,
+// so that a signature can't be used as a session token or the other way around.
+func key() []byte {
+	if config.Conf.Secrets.DownloadSecret != "" {
+		return []byte(config.Conf.Secrets.DownloadSecret)
+	}
+
+	mac := hmac.New(sha256.New, []byte(config.Conf.Secrets.JWTSecret))
+	mac.Write([]byte("learningbay24 file download"))
+	return mac.Sum(nil)
+}
+
+// Sign returns the signature allowing to download the file until the given unix time
+func Sign(fileId int, expires int64) string {
+	mac := hmac.New(sha256.New, key())
+	fmt.Fprintf(mac, "file:%d:%d", fileId, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// URL returns the path and query of a signed URL downloading the file, which is relative to the API, and when it expires
+func URL(fileId int, now time.Time) (string, time.Time) {
+	expires := now.Add(Lifetime).Truncate(time.Second)
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("signature", Sign(fileId, expires.Unix()))
+
+	return fmt.Sprintf("/files/%d/download?%s", fileId, q.Encode()), expires
+}
+
+// Verify checks that the signature allows downloading the file at the given time.
+// expires is the unix time from the URL, which is covered by the signature.
+func Verify(fileId int, expires string, signature string, now time.Time) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errs.ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(fileId, exp)), []byte(signature)) {
+		return errs.ErrInvalidSignature
+	}
+	if now.Unix() >= exp {
+		return errs.ErrDownloadLinkExpired
+	}
+
+	return nil
+}
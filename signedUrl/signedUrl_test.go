@@ -0,0 +1,41 @@
+package signedurl
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"learningbay24.de/backend/config"
+	"learningbay24.de/backend/errs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	config.Conf.Secrets.JWTSecret = "secret"
+	now := time.Now()
+
+	u, expires := URL(42, now)
+	assert.Equal(t, now.Add(Lifetime).Truncate(time.Second), expires)
+
+	parsed, err := url.Parse(u)
+	assert.NoError(t, err)
+	assert.Equal(t, "/files/42/download", parsed.Path)
+	q := parsed.Query()
+	exp, sig := q.Get("expires"), q.Get("signature")
+
+	assert.NoError(t, Verify(42, exp, sig, now))
+	// scoped to the file and the expiry
+	assert.ErrorIs(t, Verify(43, exp, sig, now), errs.ErrInvalidSignature)
+	later := strconv.FormatInt(expires.Add(time.Hour).Unix(), 10)
+	assert.ErrorIs(t, Verify(42, later, sig, now), errs.ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(42, "soon", sig, now), errs.ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(42, exp, "", now), errs.ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(42, exp, sig, expires), errs.ErrDownloadLinkExpired)
+
+	// changing the secret invalidates all URLs
+	config.Conf.Secrets.DownloadSecret = "other"
+	defer func() { config.Conf.Secrets.DownloadSecret = "" }()
+	assert.ErrorIs(t, Verify(42, exp, sig, now), errs.ErrInvalidSignature)
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Suffix of files that are still being written
+const partialSuffix = ".partial"
+
+// LocalBackend stores contents as files below a directory
+type LocalBackend struct {
+	root string
+}
+
+type localObject struct {
+	*os.File
+	info fs.FileInfo
+}
+
+func (o *localObject) Size() int64 {
+	return o.info.Size()
+}
+
+func (o *localObject) ModTime() time.Time {
+	return o.info.ModTime()
+}
+
+// NewLocalBackend returns a backend storing contents below the given directory
+func NewLocalBackend(root string) *LocalBackend {
+	return &LocalBackend{root: root}
+}
+
+func (b *LocalBackend) Name() string {
+	return LocalName
+}
+
+func (b *LocalBackend) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(b.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the content to a temporary file first, so that nobody reads half of it
+func (b *LocalBackend) Put(key string, r io.Reader, size int64) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+		return err
+	}
+
+	fp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*"+partialSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fp.Name())
+
+	n, err := io.Copy(fp, r)
+	if e := fp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("expected %d bytes for %q, got %d", size, key, n)
+	}
+
+	return os.Rename(fp.Name(), p)
+}
+
+func (b *LocalBackend) Open(key string) (Object, error) {
+	p, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fp, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+	} else if err != nil {
+		return nil, err
+	}
+
+	info, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return nil, err
+	}
+
+	return &localObject{File: fp, info: info}, nil
+}
+
+func (b *LocalBackend) Delete(key string) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotExist, key)
+	}
+	return err
+}
+
+func (b *LocalBackend) List(fn func(key string, size int64) error) error {
+	return filepath.WalkDir(b.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, partialSuffix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(b.root, p)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), info.Size())
+	})
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testBackend stores, reads, lists and deletes a file, which every backend has to support in the same way
+func testBackend(t *testing.T, b Backend) {
+	key := "2022/07/test.txt"
+	content := []byte("Binary search trees")
+
+	_, err := b.Open(key)
+	assert.ErrorIs(t, err, ErrNotExist)
+
+	assert.NoError(t, b.Put(key, bytes.NewReader(content), int64(len(content))))
+	assert.NoError(t, b.Put("empty", bytes.NewReader(nil), 0))
+
+	obj, err := b.Open(key)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), obj.Size())
+
+	p := make([]byte, 6)
+	n, err := obj.ReadAt(p, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "search", string(p[:n]))
+
+	_, err = obj.Seek(14, io.SeekStart)
+	assert.NoError(t, err)
+	rest, err := io.ReadAll(obj)
+	assert.NoError(t, err)
+	assert.Equal(t, "trees", string(rest))
+	assert.NoError(t, obj.Close())
+
+	keys := make(map[string]int64)
+	assert.NoError(t, b.List(func(key string, size int64) error {
+		keys[key] = size
+		return nil
+	}))
+	assert.Equal(t, map[string]int64{key: int64(len(content)), "empty": 0}, keys)
+
+	assert.NoError(t, b.Delete(key))
+	assert.NoError(t, b.Delete("empty"))
+	_, err = b.Open(key)
+	assert.ErrorIs(t, err, ErrNotExist)
+}
+
+func TestLocalBackend(t *testing.T) {
+	b := NewLocalBackend(t.TempDir())
+	testBackend(t, b)
+
+	assert.Error(t, b.Put("../escape", bytes.NewReader(nil), 0))
+	assert.Error(t, b.Put("/etc/passwd", bytes.NewReader(nil), 0))
+	assert.Error(t, b.Put("short", bytes.NewReader([]byte("abc")), 4))
+	_, err := b.Open("short")
+	assert.ErrorIs(t, err, ErrNotExist)
+}
@@ -0,0 +1,121 @@
+// Package storage implements the backends the contents of uploaded files are stored in
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"learningbay24.de/backend/config"
+)
+
+// Names of the backends, as they are stored with each file and chosen in the config
+const (
+	LocalName = "local"
+	S3Name    = "s3"
+)
+
+// ErrNotExist is returned if there is no content stored under a key
+var ErrNotExist = errors.New("File content doesn't exist in storage")
+
+// Backend stores the contents of files under keys, which are relative slash-separated paths
+type Backend interface {
+	// Name returns the name of the backend, by which it can be retrieved with `Get`
+	Name() string
+	// Put stores the content read from r under the key, replacing what was stored there before.
+	// size is the amount of bytes r returns.
+	Put(key string, r io.Reader, size int64) error
+	// Open returns the content stored under the key, or `ErrNotExist`
+	Open(key string) (Object, error)
+	// Delete removes the content stored under the key
+	Delete(key string) error
+	// List calls fn with the key and size of every content stored, until it returns an error
+	List(fn func(key string, size int64) error) error
+}
+
+// Object is content that is being read from a backend
+type Object interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+	Size() int64
+	ModTime() time.Time
+}
+
+var (
+	mu       sync.Mutex
+	backends = make(map[string]Backend)
+)
+
+// Get returns the backend with the given name, configured by the `Files` section of the config
+func Get(name string) (Backend, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if b, ok := backends[name]; ok {
+		return b, nil
+	}
+
+	var b Backend
+	var err error
+	switch name {
+	case LocalName:
+		b = NewLocalBackend(config.Conf.Files.Path)
+	case S3Name:
+		b, err = NewS3Backend(config.Conf.Files.S3)
+	default:
+		err = fmt.Errorf("unknown storage backend %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backends[name] = b
+	return b, nil
+}
+
+// Quarantine returns the backend infected contents are kept in, a local directory that has to be outside of the one files are stored in
+func Quarantine() (Backend, error) {
+	root := config.Conf.Files.QuarantinePath
+	if root == "" {
+		return nil, errors.New("no quarantine path configured")
+	}
+	if rel, err := filepath.Rel(config.Conf.Files.Path, root); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("quarantine path %s is inside of the files path", root)
+	}
+
+	return NewLocalBackend(root), nil
+}
+
+// Default returns the backend new files are stored in
+func Default() (Backend, error) {
+	name := config.Conf.Files.Storage
+	if name == "" {
+		name = LocalName
+	}
+	return Get(name)
+}
+
+// NewKey returns a new, unique key for a file with the given extension, grouped by the month it was created in
+func NewKey(ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s%s", time.Now().Format("2006/01"), hex.EncodeToString(b), strings.ToLower(ext)), nil
+}
+
+// validKey checks that a key can't leave the root of a backend
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
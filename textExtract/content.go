@@ -0,0 +1,223 @@
+package textextract
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+)
+
+// Maximum depth of form XObjects drawn inside each other
+const maxFormDepth = 8
+
+// Displacement in thousandths of a unit within a TJ array above which it is read as a space between words
+const wordGap = 250
+
+var ligatures = strings.NewReplacer("ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "­", "")
+
+// pageText returns the text shown on a page
+func (doc *pdfDocument) pageText(page *pdfPage) string {
+	t := &textWriter{doc: doc, fonts: make(map[interface{}]*font)}
+	t.run(doc.contents(page), page.resources, 0)
+	return normalize(t.b.String())
+}
+
+// textWriter collects the text of content streams. Text is written in the order it is drawn,
+// which is the reading order for almost all documents.
+type textWriter struct {
+	doc   *pdfDocument
+	fonts map[interface{}]*font
+	font  *font
+	b     strings.Builder
+	// vertical position of the last text matrix, to tell whether a new one starts a line
+	y float64
+}
+
+func (t *textWriter) newline() {
+	t.b.WriteByte('\n')
+}
+
+func (t *textWriter) space() {
+	t.b.WriteByte(' ')
+}
+
+func (t *textWriter) show(s []byte) {
+	if t.font == nil {
+		return
+	}
+	t.b.WriteString(t.font.decode(s))
+}
+
+// setFont looks up a font of the current resources, reading it only once
+func (t *textWriter) setFont(resources pdfDict, name pdfName) {
+	fonts := t.doc.dict(resources["Font"])
+	if fonts == nil {
+		t.font = nil
+		return
+	}
+
+	ref := fonts[name]
+	// direct font dictionaries can't be used as key, so they're read every time
+	key, ok := ref.(pdfRef)
+	if ok {
+		if f, ok := t.fonts[key]; ok {
+			t.font = f
+			return
+		}
+	}
+
+	d := t.doc.dict(ref)
+	if d == nil {
+		t.font = nil
+		return
+	}
+	t.font = t.doc.loadFont(d)
+	if ok {
+		t.fonts[key] = t.font
+	}
+}
+
+// run interprets a content stream, only looking at operators that show or position text
+func (t *textWriter) run(data []byte, resources pdfDict, depth int) {
+	l := &lexer{data: data}
+	var operands []interface{}
+	for {
+		obj, err := l.readObject()
+		if err != nil {
+			// also skips stray delimiters
+			if l.pos < len(l.data) {
+				l.pos++
+				operands = operands[:0]
+				continue
+			}
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "BI":
+			// the data of inline images is binary and ends at `EI`
+			i := bytes.Index(l.data[l.pos:], []byte("ID"))
+			if i < 0 {
+				return
+			}
+			l.pos += i + 2
+			for {
+				i = bytes.Index(l.data[l.pos:], []byte("EI"))
+				if i < 0 {
+					return
+				}
+				l.pos += i + 2
+				if isWhite(l.data[l.pos-3]) && (l.pos == len(l.data) || isWhite(l.data[l.pos]) || isDelimiter(l.data[l.pos])) {
+					break
+				}
+			}
+		case "BT":
+			t.y = 0
+			t.newline()
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					t.setFont(resources, name)
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := toFloat(operands[0])
+				ty, _ := toFloat(operands[1])
+				if ty != 0 {
+					t.newline()
+				} else if tx > 0 {
+					t.space()
+				}
+				t.y += ty
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := toFloat(operands[5])
+				if y != t.y {
+					t.newline()
+				} else {
+					t.space()
+				}
+				t.y = y
+			}
+		case "T*":
+			t.newline()
+		case "Tj":
+			if len(operands) >= 1 {
+				s, _ := operands[0].([]byte)
+				t.show(s)
+			}
+		case "'", "\"":
+			t.newline()
+			if len(operands) >= 1 {
+				s, _ := operands[len(operands)-1].([]byte)
+				t.show(s)
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				a, _ := operands[0].(pdfArray)
+				for _, v := range a {
+					if s, ok := v.([]byte); ok {
+						t.show(s)
+					} else if n, ok := toFloat(v); ok && n < -wordGap {
+						t.space()
+					}
+				}
+			}
+		case "Do":
+			if len(operands) >= 1 && depth < maxFormDepth {
+				if name, ok := operands[0].(pdfName); ok {
+					t.runForm(resources, name, depth)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// runForm interprets a form XObject, which may contain text too
+func (t *textWriter) runForm(resources pdfDict, name pdfName, depth int) {
+	xobjects := t.doc.dict(resources["XObject"])
+	if xobjects == nil {
+		return
+	}
+	s, ok := t.doc.resolve(xobjects[name]).(*pdfStream)
+	if !ok || s.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := t.doc.decode(s)
+	if err != nil {
+		return
+	}
+
+	if r := t.doc.dict(s.dict["Resources"]); r != nil {
+		resources = r
+	}
+	font := t.font
+	t.newline()
+	t.run(data, resources, depth+1)
+	t.newline()
+	t.font = font
+}
+
+// normalize collapses whitespace, drops empty lines and control characters and splits up ligatures
+func normalize(text string) string {
+	text = ligatures.Replace(text)
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.FieldsFunc(line, func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.IsControl(r)
+		}), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
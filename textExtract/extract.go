@@ -0,0 +1,142 @@
+// Package textextract reads the text of uploaded files, so that it can be searched
+package textextract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// Maximum size of a PDF, which is read into memory as a whole
+const maxPDFSize = 64 << 20
+
+// Maximum amount of text extracted from a file
+const maxTextSize = 4 << 20
+
+// Maximum amount of entries listed from an archive
+const maxArchiveEntries = 10000
+
+// ErrUnsupported is returned for files whose text can't be extracted
+var ErrUnsupported = errors.New("unsupported file type")
+
+// Page is the text of a page of a document. Files without pages have a single one with the number 0.
+type Page struct {
+	Number int
+	Text   string
+}
+
+// Extract returns the text of a file, chosen by the extension of its name. PDFs are read page by page,
+// text files as a whole and archives are listed by the names of the files they contain.
+func Extract(name string, r io.ReaderAt, size int64) ([]Page, error) {
+	lower := strings.ToLower(name)
+	switch ext := path.Ext(lower); {
+	case ext == ".pdf":
+		return extractPDF(r, size)
+	case ext == ".txt" || ext == ".md" || ext == ".csv":
+		return extractText(r, size)
+	case ext == ".zip":
+		return listZip(r, size)
+	case ext == ".tar":
+		return listTar(io.NewSectionReader(r, 0, size))
+	case ext == ".tgz" || strings.HasSuffix(lower, ".tar.gz"):
+		zr, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, err
+		}
+		return listTar(zr)
+	case ext == ".tbz2" || ext == ".bzip" || strings.HasSuffix(lower, ".tar.bz2"):
+		return listTar(bzip2.NewReader(io.NewSectionReader(r, 0, size)))
+	}
+
+	return nil, ErrUnsupported
+}
+
+func extractPDF(r io.ReaderAt, size int64) ([]Page, error) {
+	if size > maxPDFSize {
+		return nil, ErrUnsupported
+	}
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	doc, err := newPDFDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := []Page{}
+	total := 0
+	for i, p := range doc.pages() {
+		text := doc.pageText(p)
+		if text == "" {
+			continue
+		}
+		if total+len(text) > maxTextSize {
+			break
+		}
+		total += len(text)
+		pages = append(pages, Page{Number: i + 1, Text: text})
+	}
+
+	return pages, nil
+}
+
+func extractText(r io.ReaderAt, size int64) ([]Page, error) {
+	if size > maxTextSize {
+		size = maxTextSize
+	}
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return []Page{{Text: strings.ToValidUTF8(string(data), "")}}, nil
+}
+
+func listZip(r io.ReaderAt, size int64) ([]Page, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range zr.File {
+		if len(names) == maxArchiveEntries {
+			break
+		}
+		names = append(names, f.Name)
+	}
+
+	return listing(names), nil
+}
+
+func listTar(r io.Reader) ([]Page, error) {
+	tr := tar.NewReader(r)
+	var names []string
+	for len(names) < maxArchiveEntries {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, h.Name)
+	}
+
+	return listing(names), nil
+}
+
+func listing(names []string) []Page {
+	text := strings.ToValidUTF8(strings.Join(names, "\n"), "")
+	if len(text) > maxTextSize {
+		text = strings.ToValidUTF8(text[:maxTextSize], "")
+	}
+	return []Page{{Text: text}}
+}
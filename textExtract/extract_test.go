@@ -0,0 +1,94 @@
+package textextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// buildPDF writes a PDF with the given objects, numbered from 1, the first being the catalog
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func stream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar <0001> <0042> <0002> <00FC> endbfchar
+1 beginbfrange <0010> <0012> <0061> endbfrange
+endcmap`
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 7 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 9 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /Differences [1 /fi /adieresis] >> >>",
+		stream("", []byte("BT /F1 12 Tf 72 700 Td (Binary Search) Tj 0 -14 Td [(Tr) -20 (ees) -300 (\\001nd) 10 (en)] TJ ET")),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Sub /Encoding /Identity-H /ToUnicode 8 0 R >>",
+		stream("/Filter /FlateDecode", deflate(cmap)),
+		stream("/Filter /FlateDecode", deflate("BT /F2 12 Tf 72 700 Td <000100020010001100100012> Tj ET\nBT /F1 12 Tf 1 0 0 1 72 600 Tm (H\\002user) Tj ET")),
+	)
+
+	pages, err := Extract("Lecture 1.PDF", bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, []Page{
+		{Number: 1, Text: "Binary Search\nTrees finden"},
+		{Number: 2, Text: "Büabac\nHäuser"},
+	}, pages)
+
+	_, err = Extract("broken.pdf", bytes.NewReader([]byte("not a pdf")), 9)
+	assert.ErrorIs(t, err, errInvalidPDF)
+}
+
+func TestExtractArchive(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"src/main.go", "README.md"} {
+		_, err := zw.Create(name)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+
+	pages, err := Extract("project.zip", bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, []Page{{Text: "src/main.go\nREADME.md"}}, pages)
+
+	pages, err = Extract("notes.txt", bytes.NewReader([]byte("hello\xffworld")), 11)
+	assert.NoError(t, err)
+	assert.Equal(t, []Page{{Text: "helloworld"}}, pages)
+
+	_, err = Extract("image.png", bytes.NewReader(nil), 0)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
@@ -0,0 +1,296 @@
+package textextract
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// font turns the codes of shown strings into text
+type font struct {
+	// set if the font has a ToUnicode CMap, which takes precedence
+	cmap *cmap
+	// the encoding of simple fonts, nil for composite fonts without CMap, whose text can't be read
+	encoding *[256]rune
+}
+
+func (f *font) decode(s []byte) string {
+	if f.cmap != nil {
+		return f.cmap.decode(s)
+	}
+	if f.encoding == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, c := range s {
+		if r := f.encoding[c]; r != 0 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// loadFont reads the encoding of a font dictionary
+func (doc *pdfDocument) loadFont(d pdfDict) *font {
+	f := &font{}
+	if s, ok := doc.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := doc.decode(s); err == nil {
+			f.cmap = parseCMap(data)
+		}
+	}
+	if f.cmap != nil || d["Subtype"] == pdfName("Type0") {
+		return f
+	}
+
+	enc := winAnsiEncoding
+	f.encoding = &enc
+	if e := doc.dict(d["Encoding"]); e != nil {
+		diff, _ := doc.resolve(e["Differences"]).(pdfArray)
+		code := 0
+		for _, v := range diff {
+			switch v := doc.resolve(v).(type) {
+			case int:
+				code = v
+			case pdfName:
+				if code >= 0 && code < 256 {
+					f.encoding[code] = glyphRune(string(v))
+				}
+				code++
+			}
+		}
+	}
+
+	return f
+}
+
+// winAnsiEncoding is the encoding of simple fonts without a CMap, which equals Latin-1 apart from 0x80 to 0x9F.
+// Other base encodings are read like it too, as they hardly ever differ in letters.
+var winAnsiEncoding = func() [256]rune {
+	var enc [256]rune
+	for i := 0x20; i < 256; i++ {
+		enc[i] = rune(i)
+	}
+	enc[0x7F] = 0
+	for i, r := range []rune("€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ") {
+		enc[0x80+i] = r
+	}
+	// a non-breaking space is just a space in text that is searched
+	enc[0xA0] = ' '
+	return enc
+}()
+
+// glyphNames maps the names of common glyphs that aren't derived from their character to it
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%', "ampersand": '&',
+	"quotesingle": '\'', "quoteright": '’', "quoteleft": '‘', "parenleft": '(', "parenright": ')', "asterisk": '*',
+	"plus": '+', "comma": ',', "hyphen": '-', "minus": '-', "period": '.', "slash": '/', "colon": ':', "semicolon": ';',
+	"less": '<', "equal": '=', "greater": '>', "question": '?', "at": '@', "bracketleft": '[', "backslash": '\\',
+	"bracketright": ']', "underscore": '_', "braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"adieresis": 'ä', "odieresis": 'ö', "udieresis": 'ü', "Adieresis": 'Ä', "Odieresis": 'Ö', "Udieresis": 'Ü', "germandbls": 'ß',
+	"eacute": 'é', "egrave": 'è', "agrave": 'à', "ccedilla": 'ç', "Eacute": 'É',
+	"endash": '–', "emdash": '—', "bullet": '•', "ellipsis": '…', "quotedblleft": '“', "quotedblright": '”',
+	"quotedblbase": '„', "quotesinglbase": '‚', "section": '§', "degree": '°', "copyright": '©', "registered": '®',
+	"fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ', "ffi": 'ﬃ', "ffl": 'ﬄ', "Euro": '€',
+}
+
+// glyphRune returns the character of a glyph name, or 0 if it is unknown
+func glyphRune(name string) rune {
+	// variants like `a.sc` are the same character
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	if r, ok := glyphNames[name]; ok {
+		return r
+	}
+	if len(name) == 1 {
+		return rune(name[0])
+	}
+
+	var hex string
+	switch {
+	case strings.HasPrefix(name, "uni") && len(name) == 7:
+		hex = name[3:]
+	case strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7:
+		hex = name[1:]
+	default:
+		return 0
+	}
+	r, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0
+	}
+	return rune(r)
+}
+
+// cmap is a ToUnicode CMap, mapping codes of one to four bytes to text
+type cmap struct {
+	codespaces []codespace
+	chars      map[cmapCode]string
+	ranges     []cmapRange
+}
+
+type cmapCode struct {
+	len  int
+	code uint32
+}
+
+type codespace struct {
+	lo, hi []byte
+}
+
+type cmapRange struct {
+	len    int
+	lo, hi uint32
+	// either the text of the first code, incremented for the following ones, or the text of every code
+	start []uint16
+	texts []string
+}
+
+func codeOf(b []byte) uint32 {
+	var c uint32
+	for _, v := range b {
+		c = c<<8 | uint32(v)
+	}
+	return c
+}
+
+func utf16Text(b []byte) []uint16 {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return u
+}
+
+// parseCMap reads the mappings of a CMap, returning nil if there are none
+func parseCMap(data []byte) *cmap {
+	m := &cmap{chars: make(map[cmapCode]string)}
+	l := &lexer{data: data}
+	var operands []interface{}
+	for {
+		obj, err := l.readObject()
+		if err != nil {
+			break
+		}
+		kw, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch kw {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].([]byte)
+				hi, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 && len(lo) <= 4 {
+					m.codespaces = append(m.codespaces, codespace{lo: lo, hi: hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].([]byte)
+				dst, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 && len(src) > 0 && len(src) <= 4 {
+					m.chars[cmapCode{len(src), codeOf(src)}] = string(utf16.Decode(utf16Text(dst)))
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].([]byte)
+				hi, ok2 := operands[i+1].([]byte)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 || codeOf(lo) > codeOf(hi) {
+					continue
+				}
+
+				r := cmapRange{len: len(lo), lo: codeOf(lo), hi: codeOf(hi)}
+				switch dst := operands[i+2].(type) {
+				case []byte:
+					r.start = utf16Text(dst)
+				case pdfArray:
+					for _, d := range dst {
+						b, _ := d.([]byte)
+						r.texts = append(r.texts, string(utf16.Decode(utf16Text(b))))
+					}
+				default:
+					continue
+				}
+				m.ranges = append(m.ranges, r)
+			}
+		}
+		operands = operands[:0]
+	}
+
+	if len(m.chars) == 0 && len(m.ranges) == 0 {
+		return nil
+	}
+	return m
+}
+
+// codeLength returns how many bytes the code at the beginning of s takes up
+func (m *cmap) codeLength(s []byte) int {
+	for _, cs := range m.codespaces {
+		if len(cs.lo) > len(s) {
+			continue
+		}
+		in := true
+		for i := range cs.lo {
+			if s[i] < cs.lo[i] || s[i] > cs.hi[i] {
+				in = false
+				break
+			}
+		}
+		if in {
+			return len(cs.lo)
+		}
+	}
+
+	// without a matching code space, the length of the mapped codes is used
+	for k := range m.chars {
+		return k.len
+	}
+	return m.ranges[0].len
+}
+
+func (m *cmap) decode(s []byte) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		n := m.codeLength(s)
+		if n > len(s) {
+			n = len(s)
+		}
+		b.WriteString(m.lookup(n, codeOf(s[:n])))
+		s = s[n:]
+	}
+	return b.String()
+}
+
+func (m *cmap) lookup(n int, code uint32) string {
+	if t, ok := m.chars[cmapCode{n, code}]; ok {
+		return t
+	}
+	for _, r := range m.ranges {
+		if r.len != n || code < r.lo || code > r.hi {
+			continue
+		}
+		offset := code - r.lo
+		if r.texts != nil {
+			if int(offset) < len(r.texts) {
+				return r.texts[offset]
+			}
+			return ""
+		}
+		if len(r.start) == 0 {
+			return ""
+		}
+
+		// the last unit is incremented
+		u := append([]uint16{}, r.start...)
+		u[len(u)-1] += uint16(offset)
+		return string(utf16.Decode(u))
+	}
+
+	return ""
+}
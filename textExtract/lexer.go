@@ -0,0 +1,300 @@
+package textextract
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+var errInvalidPDF = errors.New("invalid PDF")
+
+type pdfName string
+
+// pdfKeyword is a bare word, like `obj`, `R` or an operator of a content stream, or the end of a dictionary or array
+type pdfKeyword string
+
+type pdfRef struct {
+	num int
+	gen int
+}
+
+type pdfDict map[pdfName]interface{}
+
+type pdfArray []interface{}
+
+// pdfStream is a stream as it is stored in the file, its data is still encoded
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+}
+
+// lexer reads the objects of a PDF file or of a content stream
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func isWhite(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isWhite(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// regular reads a token that isn't delimited by special characters, like a number or keyword
+func (l *lexer) regular() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isWhite(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// readObject reads the next object. Dictionaries and arrays are read as a whole, while the ends of them and other bare words are returned as `pdfKeyword`.
+func (l *lexer) readObject() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errInvalidPDF
+	}
+
+	switch c := l.data[l.pos]; c {
+	case '/':
+		l.pos++
+		return l.readName(), nil
+	case '(':
+		l.pos++
+		return l.readLiteralString(), nil
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return l.readDict()
+		}
+		l.pos++
+		return l.readHexString(), nil
+	case '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		return nil, errInvalidPDF
+	case '[':
+		l.pos++
+		return l.readArray()
+	case ']', '{', '}':
+		l.pos++
+		return pdfKeyword(c), nil
+	case ')':
+		return nil, errInvalidPDF
+	}
+
+	tok := l.regular()
+	if tok == "" {
+		return nil, errInvalidPDF
+	}
+	switch tok {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	if i, err := strconv.Atoi(tok); err == nil {
+		// two integers followed by `R` are a reference
+		save := l.pos
+		l.skipSpace()
+		if gen, err := strconv.Atoi(l.regular()); err == nil {
+			l.skipSpace()
+			if l.regular() == "R" {
+				return pdfRef{num: i, gen: gen}, nil
+			}
+		}
+		l.pos = save
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(tok, 64); err == nil {
+		return f, nil
+	}
+
+	return pdfKeyword(tok), nil
+}
+
+func (l *lexer) readName() pdfName {
+	tok := l.regular()
+	if !bytes.ContainsRune([]byte(tok), '#') {
+		return pdfName(tok)
+	}
+
+	var name []byte
+	for i := 0; i < len(tok); i++ {
+		if tok[i] == '#' && i+2 < len(tok) {
+			if b, err := strconv.ParseUint(tok[i+1:i+3], 16, 8); err == nil {
+				name = append(name, byte(b))
+				i += 2
+				continue
+			}
+		}
+		name = append(name, tok[i])
+	}
+	return pdfName(name)
+}
+
+func (l *lexer) readLiteralString() []byte {
+	var s []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return s
+			}
+			c = l.data[l.pos]
+			l.pos++
+
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// a backslash at the end of a line continues the string on the next one
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					n := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				}
+			}
+		}
+		s = append(s, c)
+	}
+
+	return s
+}
+
+func (l *lexer) readHexString() []byte {
+	var s []byte
+	var b byte
+	half := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		var v byte
+		switch {
+		case c == '>':
+			if half {
+				s = append(s, b<<4)
+			}
+			return s
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+
+		if half {
+			s = append(s, b<<4|v)
+		} else {
+			b = v
+		}
+		half = !half
+	}
+
+	return s
+}
+
+func (l *lexer) readDict() (pdfDict, error) {
+	d := pdfDict{}
+	for {
+		key, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		if key == pdfKeyword(">>") {
+			return d, nil
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, errInvalidPDF
+		}
+
+		value, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		d[name] = value
+	}
+}
+
+func (l *lexer) readArray() (pdfArray, error) {
+	a := pdfArray{}
+	for {
+		v, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		if v == pdfKeyword("]") {
+			return a, nil
+		}
+		a = append(a, v)
+	}
+}
+
+// toFloat returns the value of a number object
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
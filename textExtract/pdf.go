@@ -0,0 +1,370 @@
+package textextract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// Maximum size of a decoded stream, so that a small file can't take up all memory
+const maxStreamSize = 64 << 20
+
+// Maximum amount of pages read from a PDF
+const maxPages = 10000
+
+var objectHeader = regexp.MustCompile(`(\d+)[\x00\t\n\f\r ]+(\d+)[\x00\t\n\f\r ]+obj`)
+
+// pdfDocument gives access to the objects of a PDF file. The cross-reference table isn't used, as it is often broken;
+// instead the file is scanned for objects, later definitions replacing earlier ones like in incremental updates.
+type pdfDocument struct {
+	data    []byte
+	offsets map[int]int
+	// objects stored in object streams, with the decoded stream and their offset in it
+	packed map[int]packedObject
+	cache  map[int]interface{}
+}
+
+type packedObject struct {
+	data   []byte
+	offset int
+}
+
+func newPDFDocument(data []byte) (*pdfDocument, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, errInvalidPDF
+	}
+
+	doc := &pdfDocument{data: data, offsets: make(map[int]int), packed: make(map[int]packedObject), cache: make(map[int]interface{})}
+	for _, m := range objectHeader.FindAllSubmatchIndex(data, -1) {
+		// the number must not be the end of a longer one
+		if m[0] > 0 && data[m[0]-1] >= '0' && data[m[0]-1] <= '9' {
+			continue
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		doc.offsets[num] = m[1]
+	}
+
+	for num := range doc.offsets {
+		s, ok := doc.object(num).(*pdfStream)
+		if !ok || s.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		doc.unpackObjectStream(s)
+	}
+
+	return doc, nil
+}
+
+// unpackObjectStream makes the objects of an object stream available, unless they are defined outside of it
+func (doc *pdfDocument) unpackObjectStream(s *pdfStream) {
+	n, _ := doc.resolve(s.dict["N"]).(int)
+	first, _ := doc.resolve(s.dict["First"]).(int)
+	data, err := doc.decode(s)
+	if err != nil || n <= 0 || first < 0 || first > len(data) {
+		return
+	}
+
+	l := &lexer{data: data[:first]}
+	for i := 0; i < n; i++ {
+		num, err := l.readObject()
+		if err != nil {
+			return
+		}
+		offset, err := l.readObject()
+		if err != nil {
+			return
+		}
+
+		num_, ok1 := num.(int)
+		offset_, ok2 := offset.(int)
+		if !ok1 || !ok2 || first+offset_ > len(data) {
+			return
+		}
+		if _, ok := doc.offsets[num_]; !ok {
+			doc.packed[num_] = packedObject{data: data, offset: first + offset_}
+		}
+	}
+}
+
+// object returns the object with the given number, or nil if there is none
+func (doc *pdfDocument) object(num int) interface{} {
+	if obj, ok := doc.cache[num]; ok {
+		return obj
+	}
+	// guards against objects that refer to themselves while they are read
+	doc.cache[num] = nil
+
+	var obj interface{}
+	if offset, ok := doc.offsets[num]; ok {
+		obj = doc.readIndirect(offset)
+	} else if p, ok := doc.packed[num]; ok {
+		l := &lexer{data: p.data, pos: p.offset}
+		obj, _ = l.readObject()
+	}
+
+	doc.cache[num] = obj
+	return obj
+}
+
+// readIndirect reads the object after an `obj` keyword, including the data of a stream
+func (doc *pdfDocument) readIndirect(offset int) interface{} {
+	l := &lexer{data: doc.data, pos: offset}
+	obj, err := l.readObject()
+	if err != nil {
+		return nil
+	}
+
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return obj
+	}
+
+	l.skipSpace()
+	if !bytes.HasPrefix(doc.data[l.pos:], []byte("stream")) {
+		return dict
+	}
+	l.pos += len("stream")
+	if l.pos < len(doc.data) && doc.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(doc.data) && doc.data[l.pos] == '\n' {
+		l.pos++
+	}
+
+	start := l.pos
+	if length, ok := doc.resolve(dict["Length"]).(int); ok && length >= 0 && start+length <= len(doc.data) {
+		rest := bytes.TrimLeft(doc.data[start+length:], "\x00\t\n\f\r ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return &pdfStream{dict: dict, data: doc.data[start : start+length]}
+		}
+	}
+
+	// the length is wrong, so the stream ends right before `endstream`
+	end := bytes.Index(doc.data[start:], []byte("endstream"))
+	if end < 0 {
+		return nil
+	}
+	return &pdfStream{dict: dict, data: bytes.TrimRight(doc.data[start:start+end], "\r\n")}
+}
+
+// resolve follows references until it reaches a direct object
+func (doc *pdfDocument) resolve(v interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = doc.object(ref.num)
+	}
+	return nil
+}
+
+func (doc *pdfDocument) dict(v interface{}) pdfDict {
+	switch d := doc.resolve(v).(type) {
+	case pdfDict:
+		return d
+	case *pdfStream:
+		return d.dict
+	}
+	return nil
+}
+
+// decode returns the data of a stream with all of its filters undone
+func (doc *pdfDocument) decode(s *pdfStream) ([]byte, error) {
+	var filters pdfArray
+	switch f := doc.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = pdfArray{f}
+	case pdfArray:
+		filters = f
+	}
+
+	data := s.data
+	for _, f := range filters {
+		var err error
+		switch doc.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			if parms := doc.dict(s.dict["DecodeParms"]); parms != nil {
+				if p, ok := doc.resolve(parms["Predictor"]).(int); ok && p > 1 {
+					return nil, fmt.Errorf("%w: predictors aren't supported", errInvalidPDF)
+				}
+			}
+			data, err = inflate(data)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data, err = decodeASCIIHex(data)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("%w: filter %v isn't supported", errInvalidPDF, f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, maxStreamSize))
+	// truncated streams are common, so whatever could be read is used
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isWhite(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// catalog returns the root of the document
+func (doc *pdfDocument) catalog() pdfDict {
+	// the trailer of the last update knows the root
+	if i := bytes.LastIndex(doc.data, []byte("trailer")); i >= 0 {
+		l := &lexer{data: doc.data, pos: i + len("trailer")}
+		if trailer, err := l.readObject(); err == nil {
+			if d, ok := trailer.(pdfDict); ok {
+				if root := doc.dict(d["Root"]); root != nil {
+					return root
+				}
+			}
+		}
+	}
+
+	// otherwise there are cross-reference streams, or the catalog has to be found by its type
+	var catalog pdfDict
+	for num := range doc.offsets {
+		d := doc.dict(doc.object(num))
+		if d == nil {
+			continue
+		}
+		if d["Type"] == pdfName("XRef") {
+			if root := doc.dict(d["Root"]); root != nil {
+				return root
+			}
+		}
+		if d["Type"] == pdfName("Catalog") {
+			catalog = d
+		}
+	}
+	for num := range doc.packed {
+		if d := doc.dict(doc.object(num)); catalog == nil && d != nil && d["Type"] == pdfName("Catalog") {
+			catalog = d
+		}
+	}
+
+	return catalog
+}
+
+// pdfPage is a page together with the resources it inherited from the page tree
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages of the document in order
+func (doc *pdfDocument) pages() []*pdfPage {
+	catalog := doc.catalog()
+	if catalog == nil {
+		return nil
+	}
+
+	var pages []*pdfPage
+	seen := make(map[int]bool)
+	var walk func(node interface{}, resources pdfDict)
+	walk = func(node interface{}, resources pdfDict) {
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref.num] {
+				return
+			}
+			seen[ref.num] = true
+		}
+		d := doc.dict(node)
+		if d == nil || len(pages) >= maxPages {
+			return
+		}
+		if r := doc.dict(d["Resources"]); r != nil {
+			resources = r
+		}
+
+		kids, ok := doc.resolve(d["Kids"]).(pdfArray)
+		if !ok {
+			pages = append(pages, &pdfPage{dict: d, resources: resources})
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, resources)
+		}
+	}
+	walk(catalog["Pages"], nil)
+
+	return pages
+}
+
+// contents returns the decoded content streams of a page, one after another
+func (doc *pdfDocument) contents(page *pdfPage) []byte {
+	var streams pdfArray
+	switch c := doc.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		streams = pdfArray{c}
+	case pdfArray:
+		streams = c
+	}
+
+	var data []byte
+	for _, s := range streams {
+		s, ok := doc.resolve(s).(*pdfStream)
+		if !ok {
+			continue
+		}
+		decoded, err := doc.decode(s)
+		if err != nil {
+			continue
+		}
+		data = append(data, decoded...)
+		data = append(data, '\n')
+	}
+
+	return data
+}